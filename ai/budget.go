@@ -0,0 +1,214 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when the daily AI spend cap blocks a call
+var ErrBudgetExceeded = errors.New("daily AI budget exceeded")
+
+// Usage is the token usage reported by a chat completion response
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ModelPrice is the price per million prompt and completion tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// Cost prices usage at p
+func (p ModelPrice) Cost(usage Usage) float64 {
+	return float64(usage.PromptTokens)/1e6*p.Input + float64(usage.CompletionTokens)/1e6*p.Output
+}
+
+// CostTotals accumulates calls, tokens and spend
+type CostTotals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (t *CostTotals) add(usage Usage, cost float64) {
+	t.Calls++
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.Cost += cost
+}
+
+// CostReport is a snapshot of one day of AI spend
+type CostReport struct {
+	Date     string                 `json:"date"`
+	Total    CostTotals             `json:"total"`
+	BySymbol map[string]*CostTotals `json:"by_symbol"`
+	ByModel  map[string]*CostTotals `json:"by_model"`
+}
+
+// CostTracker prices AI usage and enforces a daily spend cap
+type CostTracker struct {
+	mu         sync.Mutex
+	pricing    map[string]ModelPrice
+	dailyLimit float64
+	hardLimit  float64
+	stateFile  string
+	saveErr    error
+	report     CostReport
+	now        func() time.Time
+}
+
+// NewCostTracker creates a tracker. dailyLimit triggers degradation, hardLimit
+// blocks every call; zero disables either. Totals are persisted to stateFile
+// when set so the cap survives restarts.
+func NewCostTracker(pricing map[string]ModelPrice, dailyLimit, hardLimit float64, stateFile string) *CostTracker {
+	ct := &CostTracker{
+		pricing:    pricing,
+		dailyLimit: dailyLimit,
+		hardLimit:  hardLimit,
+		stateFile:  stateFile,
+		now:        time.Now,
+	}
+	ct.report = newCostReport(ct.today())
+
+	if stateFile != "" {
+		if report, err := LoadCostReport(stateFile); err == nil && report.Date == ct.today() {
+			ct.report = *report
+		}
+	}
+	return ct
+}
+
+func newCostReport(date string) CostReport {
+	return CostReport{
+		Date:     date,
+		BySymbol: make(map[string]*CostTotals),
+		ByModel:  make(map[string]*CostTotals),
+	}
+}
+
+func (ct *CostTracker) today() string {
+	return ct.now().Format("2006-01-02")
+}
+
+// resetIfNeeded starts a new report when the day has changed
+func (ct *CostTracker) resetIfNeeded() {
+	if today := ct.today(); ct.report.Date != today {
+		ct.report = newCostReport(today)
+	}
+}
+
+// Record prices usage of model on behalf of symbol and returns its cost
+func (ct *CostTracker) Record(symbol, model string, usage Usage) float64 {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.resetIfNeeded()
+
+	cost := ct.pricing[model].Cost(usage)
+	ct.report.Total.add(usage, cost)
+
+	if ct.report.BySymbol[symbol] == nil {
+		ct.report.BySymbol[symbol] = &CostTotals{}
+	}
+	ct.report.BySymbol[symbol].add(usage, cost)
+
+	if ct.report.ByModel[model] == nil {
+		ct.report.ByModel[model] = &CostTotals{}
+	}
+	ct.report.ByModel[model].add(usage, cost)
+
+	if ct.stateFile != "" {
+		ct.saveErr = ct.save()
+	}
+	return cost
+}
+
+// SaveError returns the error of the last attempt to persist totals, if any
+func (ct *CostTracker) SaveError() error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	return ct.saveErr
+}
+
+// Exceeded reports whether the daily limit has been reached
+func (ct *CostTracker) Exceeded() bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.resetIfNeeded()
+	return ct.dailyLimit > 0 && ct.report.Total.Cost >= ct.dailyLimit
+}
+
+// HardExceeded reports whether the hard limit has been reached
+func (ct *CostTracker) HardExceeded() bool {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.resetIfNeeded()
+	return ct.hardLimit > 0 && ct.report.Total.Cost >= ct.hardLimit
+}
+
+// Report returns a copy of today's totals
+func (ct *CostTracker) Report() CostReport {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.resetIfNeeded()
+
+	report := newCostReport(ct.report.Date)
+	report.Total = ct.report.Total
+	for symbol, totals := range ct.report.BySymbol {
+		copied := *totals
+		report.BySymbol[symbol] = &copied
+	}
+	for model, totals := range ct.report.ByModel {
+		copied := *totals
+		report.ByModel[model] = &copied
+	}
+	return report
+}
+
+// save writes the current report to the state file
+func (ct *CostTracker) save() error {
+	data, err := json.MarshalIndent(ct.report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cost report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ct.stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create cost report directory: %w", err)
+	}
+	if err := os.WriteFile(ct.stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cost report: %w", err)
+	}
+	return nil
+}
+
+// LoadCostReport reads a cost report persisted by a CostTracker
+func LoadCostReport(filename string) (*CostReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var report CostReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse cost report: %w", err)
+	}
+	if report.BySymbol == nil {
+		report.BySymbol = make(map[string]*CostTotals)
+	}
+	if report.ByModel == nil {
+		report.ByModel = make(map[string]*CostTotals)
+	}
+	return &report, nil
+}
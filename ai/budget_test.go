@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestCostTrackerPricesAndPersists(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "cost.json")
+	pricing := map[string]ModelPrice{"big": {Input: 2, Output: 10}}
+
+	ct := NewCostTracker(pricing, 1.0, 0, stateFile)
+	cost := ct.Record("ETH", "big", Usage{PromptTokens: 100000, CompletionTokens: 10000})
+	if math.Abs(cost-0.3) > 1e-9 {
+		t.Errorf("expected cost 0.3, got %f", cost)
+	}
+	if ct.Exceeded() {
+		t.Error("budget should not be exceeded yet")
+	}
+
+	// A restarted tracker keeps today's totals
+	restarted := NewCostTracker(pricing, 1.0, 0, stateFile)
+	restarted.Record("BTC", "big", Usage{PromptTokens: 400000})
+	if !restarted.Exceeded() {
+		t.Error("budget should be exceeded after restart adds to persisted spend")
+	}
+
+	report := restarted.Report()
+	if report.Total.Calls != 2 || report.BySymbol["ETH"].Calls != 1 || report.BySymbol["BTC"].Calls != 1 {
+		t.Errorf("unexpected totals: %+v", report.Total)
+	}
+}
+
+func TestBudgetDowngradeAndHold(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		fmt.Fprint(w, `{"choices": [{"message": {"content": "ok"}}], "usage": {"prompt_tokens": 1000000, "completion_tokens": 0}}`)
+	}))
+	defer server.Close()
+
+	pricing := map[string]ModelPrice{"big": {Input: 1}, "small": {Input: 0.5}}
+	ct := NewCostTracker(pricing, 1.0, 1.5, "")
+
+	dm, _ := newTestDecisionMaker(server.URL)
+	dm.providers[0].model = "big"
+	dm.SetBudget(ct, "downgrade", "small")
+
+	messages := []chatMessage{{Role: "user", Content: "hi"}}
+	for i := 0; i < 2; i++ {
+		if _, err := dm.callAI("ETH", messages); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+	if _, err := dm.callAI("ETH", messages); err != ErrBudgetExceeded {
+		t.Fatalf("expected ErrBudgetExceeded past the hard limit, got %v", err)
+	}
+
+	if fmt.Sprint(models) != "[big small]" {
+		t.Errorf("expected primary model then downgrade, got %v", models)
+	}
+}
@@ -3,9 +3,11 @@ package ai
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"aitrading/hyperliquid"
@@ -14,32 +16,111 @@ import (
 
 // DecisionMaker handles AI-based trading decisions
 type DecisionMaker struct {
-	provider    string
-	apiKey      string
-	baseURL     string
-	model       string
+	providers   []*provider
 	temperature float64
 	maxTokens   int
 	timeout     time.Duration
 	httpClient  *http.Client
+	memory      *Memory
+	memoryTurns bool
+	retry       RetryPolicy
+	sleep       func(time.Duration)
+
+	mu           sync.Mutex // Guards lastProvider, written by concurrent calls
+	lastProvider string
+
+	budget         *CostTracker
+	budgetAction   string
+	downgradeModel string
+}
+
+// chatMessage is a single message in a chat completion request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
 }
 
 // NewDecisionMaker creates a new AI decision maker
-func NewDecisionMaker(provider, apiKey, baseURL, model string, temperature float64, maxTokens, timeout int) *DecisionMaker {
+func NewDecisionMaker(providerName, apiKey, baseURL, model string, temperature float64, maxTokens, timeout int) *DecisionMaker {
 	return &DecisionMaker{
-		provider:    provider,
-		apiKey:      apiKey,
-		baseURL:     baseURL,
-		model:       model,
+		providers: []*provider{{
+			name:    providerName,
+			apiKey:  apiKey,
+			baseURL: baseURL,
+			model:   model,
+			breaker: newCircuitBreaker(0, 0),
+		}},
 		temperature: temperature,
 		maxTokens:   maxTokens,
 		timeout:     time.Duration(timeout) * time.Second,
 		httpClient: &http.Client{
 			Timeout: time.Duration(timeout) * time.Second,
 		},
+		sleep: time.Sleep,
+	}
+}
+
+// AddFallback registers a provider tried when the previous ones fail or are tripped
+func (dm *DecisionMaker) AddFallback(name, apiKey, baseURL, model string) {
+	breaker := newCircuitBreaker(dm.providers[0].breaker.threshold, dm.providers[0].breaker.cooldown)
+	dm.providers = append(dm.providers, &provider{
+		name:    name,
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		model:   model,
+		breaker: breaker,
+	})
+}
+
+// SetRetryPolicy sets how often and how long to back off before giving up on a provider
+func (dm *DecisionMaker) SetRetryPolicy(maxRetries int, baseDelay, maxDelay time.Duration) {
+	dm.retry = RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  baseDelay,
+		MaxDelay:   maxDelay,
 	}
 }
 
+// SetCircuitBreaker trips a provider after threshold consecutive failed calls for cooldown
+func (dm *DecisionMaker) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	for _, p := range dm.providers {
+		p.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+// SetBudget attaches a cost tracker. Once its daily limit is reached calls
+// either fall back to HOLD (onExceed "hold") or switch the primary provider
+// to downgradeModel (onExceed "downgrade") until the hard limit is hit.
+func (dm *DecisionMaker) SetBudget(tracker *CostTracker, onExceed, downgradeModel string) {
+	dm.budget = tracker
+	dm.budgetAction = onExceed
+	dm.downgradeModel = downgradeModel
+}
+
+// Budget returns the attached cost tracker, or nil
+func (dm *DecisionMaker) Budget() *CostTracker {
+	return dm.budget
+}
+
+// LastProvider returns the name of the provider that answered the last call
+func (dm *DecisionMaker) LastProvider() string {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.lastProvider
+}
+
+// SetMemory attaches a decision memory; when asChatTurns is set the recent
+// decisions are also replayed as prior chat messages
+func (dm *DecisionMaker) SetMemory(memory *Memory, asChatTurns bool) {
+	dm.memory = memory
+	dm.memoryTurns = asChatTurns
+}
+
+// Memory returns the attached decision memory, or nil
+func (dm *DecisionMaker) Memory() *Memory {
+	return dm.memory
+}
+
 // Decision represents a trading decision from AI
 type Decision struct {
 	Action                string  `json:"action"`
@@ -51,6 +132,14 @@ type Decision struct {
 	TakeProfit            float64 `json:"take_profit"`
 	RiskLevel             string  `json:"risk_level"`
 	ExpectedHoldingPeriod string  `json:"expected_holding_period"`
+
+	// ExecutionAlgo optionally picks how the executor works the order,
+	// overriding the configured algo
+	ExecutionAlgo string `json:"execution_algo,omitempty"`
+
+	// Quantity is the exact coin amount set by risk sizing; zero lets the
+	// executor derive it from Size and the account balance
+	Quantity float64 `json:"-"`
 }
 
 // MarketAnalysis contains all data for AI analysis
@@ -60,6 +149,7 @@ type MarketAnalysis struct {
 	Market     *hyperliquid.MarketInfo
 	Indicators *indicators.TechnicalIndicators
 	Position   *hyperliquid.Position
+	Candles    []indicators.MarketData
 }
 
 // Analyze sends market data to AI and gets trading decision
@@ -67,8 +157,15 @@ func (dm *DecisionMaker) Analyze(analysis *MarketAnalysis) (*Decision, error) {
 	// Build the prompt with all market data
 	prompt := dm.buildPrompt(analysis)
 
+	// Replay recent decisions as prior turns if configured
+	var messages []chatMessage
+	if dm.memory != nil && dm.memoryTurns {
+		messages = dm.memory.chatTurns(analysis.Symbol)
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: prompt})
+
 	// Call AI API based on provider
-	response, err := dm.callAI(prompt)
+	response, err := dm.callAI(analysis.Symbol, messages)
 	if err != nil {
 		return nil, fmt.Errorf("AI API call failed: %w", err)
 	}
@@ -79,6 +176,10 @@ func (dm *DecisionMaker) Analyze(analysis *MarketAnalysis) (*Decision, error) {
 		return nil, fmt.Errorf("failed to parse AI decision: %w", err)
 	}
 
+	if dm.memory != nil {
+		dm.memory.RecordDecision(analysis.Symbol, decision, analysis.Market.CurrentPrice)
+	}
+
 	return decision, nil
 }
 
@@ -88,6 +189,16 @@ func (dm *DecisionMaker) buildPrompt(analysis *MarketAnalysis) string {
 	pos := analysis.Position
 	mkt := analysis.Market
 
+	// Recent decision history, summarized only when replayed as chat turns
+	memoryBlock := ""
+	if dm.memory != nil {
+		if dm.memoryTurns {
+			memoryBlock = dm.memory.RenderSummary(analysis.Symbol)
+		} else {
+			memoryBlock = dm.memory.Render(analysis.Symbol)
+		}
+	}
+
 	prompt := fmt.Sprintf(`你是一位专业的量化交易员,拥有20年加密货币交易经验。现在作为自动化交易系统的核心决策引擎,你需要基于实时市场数据做出冷静、理性的交易决策。
 
 **你的任务:**
@@ -126,6 +237,7 @@ func (dm *DecisionMaker) buildPrompt(analysis *MarketAnalysis) string {
 - 当前盈亏: %.2f%%
 - 持仓时间: %s
 
+%s
 ## 决策规则指导
 
 **开仓条件(需满足至少3个条件):**
@@ -211,58 +323,144 @@ func (dm *DecisionMaker) buildPrompt(analysis *MarketAnalysis) string {
 		pos.EntryPrice,
 		pos.PnLPercent,
 		pos.HoldingTime.String(),
+		memoryBlock,
 	)
 
 	return prompt
 }
 
-// callAI makes API call to the configured AI provider
-func (dm *DecisionMaker) callAI(prompt string) (string, error) {
-	url := fmt.Sprintf("%s/chat/completions", dm.baseURL)
+// callAI sends messages to the first available provider, retrying with
+// backoff and failing over to the next provider when one gives up.
+// symbol attributes the token cost when a budget is attached.
+func (dm *DecisionMaker) callAI(symbol string, messages []chatMessage) (string, error) {
+	providers, err := dm.availableProviders()
+	if err != nil {
+		return "", err
+	}
+
+	var errs []error
+	for _, p := range providers {
+		if !p.breaker.Allow() {
+			errs = append(errs, fmt.Errorf("%s: circuit breaker open", p.name))
+			continue
+		}
+
+		content, usage, err := dm.callWithRetry(p, messages)
+		if err == nil {
+			p.breaker.Success()
+			dm.mu.Lock()
+			dm.lastProvider = p.name
+			dm.mu.Unlock()
+			if dm.budget != nil {
+				dm.budget.Record(symbol, p.model, usage)
+			}
+			return content, nil
+		}
+
+		p.breaker.Failure()
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+	}
+
+	return "", errors.Join(errs...)
+}
+
+// availableProviders returns the providers allowed under the current budget
+func (dm *DecisionMaker) availableProviders() ([]*provider, error) {
+	if dm.budget == nil || !dm.budget.Exceeded() {
+		return dm.providers, nil
+	}
+
+	if dm.budgetAction != "downgrade" || dm.downgradeModel == "" || dm.budget.HardExceeded() {
+		return nil, ErrBudgetExceeded
+	}
+
+	// Same endpoint and breaker, cheaper model
+	cheap := *dm.providers[0]
+	cheap.model = dm.downgradeModel
+	return []*provider{&cheap}, nil
+}
+
+// callWithRetry calls one provider, backing off between retryable failures
+func (dm *DecisionMaker) callWithRetry(p *provider, messages []chatMessage) (string, Usage, error) {
+	var err error
+	for attempt := 0; attempt <= dm.retry.MaxRetries; attempt++ {
+		if attempt > 0 {
+			dm.sleep(dm.retry.backoff(attempt-1, err))
+		}
+
+		var content string
+		var usage Usage
+		content, usage, err = dm.callProvider(p, messages)
+		if err == nil {
+			return content, usage, nil
+		}
+		if !retryable(err) {
+			break
+		}
+	}
+	return "", Usage{}, err
+}
+
+// callProvider makes a single chat completion call to a provider
+func (dm *DecisionMaker) callProvider(p *provider, messages []chatMessage) (string, Usage, error) {
+	url := fmt.Sprintf("%s/chat/completions", p.baseURL)
 
 	reqBody := map[string]interface{}{
-		"model": dm.model,
-		"messages": []map[string]string{
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
+		"model":       p.model,
+		"messages":    messages,
 		"temperature": dm.temperature,
 		"max_tokens":  dm.maxTokens,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", dm.apiKey))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
 
 	resp, err := dm.httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return "", Usage{}, &APIError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Body:       string(body),
+		}
 	}
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+		return "", Usage{}, err
+	}
+
+	// Extract token usage for cost accounting
+	var usage Usage
+	if usageMap, ok := result["usage"].(map[string]interface{}); ok {
+		if v, ok := usageMap["prompt_tokens"].(float64); ok {
+			usage.PromptTokens = int(v)
+		}
+		if v, ok := usageMap["completion_tokens"].(float64); ok {
+			usage.CompletionTokens = int(v)
+		}
+		if v, ok := usageMap["total_tokens"].(float64); ok {
+			usage.TotalTokens = int(v)
+		}
 	}
 
 	// Extract the response text
@@ -270,13 +468,25 @@ func (dm *DecisionMaker) callAI(prompt string) (string, error) {
 		if choice, ok := choices[0].(map[string]interface{}); ok {
 			if message, ok := choice["message"].(map[string]interface{}); ok {
 				if content, ok := message["content"].(string); ok {
-					return content, nil
+					return content, usage, nil
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("unexpected response format")
+	return "", Usage{}, fmt.Errorf("unexpected response format")
+}
+
+// BudgetHold is the decision made when the AI budget blocks a call and no
+// strategy fallback is configured
+func BudgetHold() *Decision {
+	return &Decision{
+		Action:     "HOLD",
+		Leverage:   1,
+		RiskLevel:  "LOW",
+		Reason:     "AI daily budget exceeded, holding without analysis",
+		Confidence: 0,
+	}
 }
 
 // parseDecision extracts Decision struct from AI response
@@ -0,0 +1,269 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryEntry records one decision cycle for a symbol and what came of it
+type MemoryEntry struct {
+	Timestamp   time.Time
+	Price       float64
+	Decision    Decision
+	Executed    bool
+	Result      string
+	Closed      bool
+	RealizedPnL float64
+	PnLPercent  float64
+}
+
+// memorySummary aggregates entries that have rolled out of the verbatim window
+type memorySummary struct {
+	Since       time.Time
+	Decisions   int
+	Actions     map[string]int
+	Trades      int
+	Wins        int
+	Losses      int
+	RealizedPnL float64
+}
+
+// Memory keeps a rolling per-symbol history of decisions, executions and outcomes
+type Memory struct {
+	mu        sync.Mutex
+	window    int
+	maxChars  int
+	entries   map[string][]*MemoryEntry
+	summaries map[string]*memorySummary
+}
+
+// NewMemory creates a decision memory keeping the last window entries per symbol
+func NewMemory(window, maxPromptChars int) *Memory {
+	if window <= 0 {
+		window = 10
+	}
+	return &Memory{
+		window:    window,
+		maxChars:  maxPromptChars,
+		entries:   make(map[string][]*MemoryEntry),
+		summaries: make(map[string]*memorySummary),
+	}
+}
+
+// RecordDecision appends a new decision for symbol taken at price
+func (m *Memory) RecordDecision(symbol string, decision *Decision, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[symbol] = append(m.entries[symbol], &MemoryEntry{
+		Timestamp: time.Now(),
+		Price:     price,
+		Decision:  *decision,
+	})
+
+	for len(m.entries[symbol]) > m.window {
+		m.foldOldest(symbol)
+	}
+}
+
+// RecordExecution attaches the execution result to the latest decision of symbol
+func (m *Memory) RecordExecution(symbol string, executed bool, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry := m.latest(symbol); entry != nil {
+		entry.Executed = executed
+		entry.Result = result
+	}
+}
+
+// RecordOutcome attaches a realized PnL to the latest decision of symbol
+func (m *Memory) RecordOutcome(symbol string, pnl, pnlPercent float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry := m.latest(symbol); entry != nil {
+		entry.Closed = true
+		entry.RealizedPnL = pnl
+		entry.PnLPercent = pnlPercent
+	}
+}
+
+// Entries returns a copy of the verbatim entries kept for symbol
+func (m *Memory) Entries(symbol string) []MemoryEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make([]MemoryEntry, 0, len(m.entries[symbol]))
+	for _, entry := range m.entries[symbol] {
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// Render formats the memory of symbol as a prompt section.
+// Older entries are folded into the summary until the block fits maxChars.
+func (m *Memory) Render(symbol string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	text := m.render(symbol, true)
+	for m.maxChars > 0 && len(text) > m.maxChars && len(m.entries[symbol]) > 1 {
+		m.foldOldest(symbol)
+		text = m.render(symbol, true)
+	}
+	return text
+}
+
+// RenderSummary formats only the summary of entries outside the window
+func (m *Memory) RenderSummary(symbol string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.render(symbol, false)
+}
+
+// chatTurns replays the verbatim entries of symbol as prior user/assistant turns
+func (m *Memory) chatTurns(symbol string) []chatMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var turns []chatMessage
+	previous := ""
+	for _, entry := range m.entries[symbol] {
+		request := fmt.Sprintf("[%s] %s 价格 %.4f, 请给出交易决策。",
+			entry.Timestamp.Format("2006-01-02 15:04"), symbol, entry.Price)
+		if previous != "" {
+			request = previous + "\n" + request
+		}
+
+		answer, err := json.Marshal(entry.Decision)
+		if err != nil {
+			continue
+		}
+
+		turns = append(turns,
+			chatMessage{Role: "user", Content: request},
+			chatMessage{Role: "assistant", Content: string(answer)},
+		)
+		previous = describeOutcome(entry)
+	}
+	return turns
+}
+
+// latest returns the most recent entry of symbol, or nil
+func (m *Memory) latest(symbol string) *MemoryEntry {
+	entries := m.entries[symbol]
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[len(entries)-1]
+}
+
+// foldOldest moves the oldest verbatim entry of symbol into its summary
+func (m *Memory) foldOldest(symbol string) {
+	entries := m.entries[symbol]
+	if len(entries) == 0 {
+		return
+	}
+	oldest := entries[0]
+	m.entries[symbol] = entries[1:]
+
+	summary, ok := m.summaries[symbol]
+	if !ok {
+		summary = &memorySummary{
+			Since:   oldest.Timestamp,
+			Actions: make(map[string]int),
+		}
+		m.summaries[symbol] = summary
+	}
+
+	summary.Decisions++
+	summary.Actions[oldest.Decision.Action]++
+	if oldest.Closed {
+		summary.Trades++
+		summary.RealizedPnL += oldest.RealizedPnL
+		if oldest.RealizedPnL >= 0 {
+			summary.Wins++
+		} else {
+			summary.Losses++
+		}
+	}
+}
+
+// render builds the prompt text, optionally including verbatim entries
+func (m *Memory) render(symbol string, withEntries bool) string {
+	entries := m.entries[symbol]
+	summary := m.summaries[symbol]
+	if len(entries) == 0 && summary == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## 近期决策记忆 - %s\n", symbol))
+
+	if summary != nil {
+		sb.WriteString(fmt.Sprintf("- 早期汇总(自 %s): 共%d次决策 (开多%d/开空%d/加仓%d/平仓%d/观望%d), 已平仓%d笔, 盈利%d笔/亏损%d笔, 累计已实现盈亏 %.2f\n",
+			summary.Since.Format("2006-01-02 15:04"),
+			summary.Decisions,
+			summary.Actions["OPEN_LONG"],
+			summary.Actions["OPEN_SHORT"],
+			summary.Actions["ADD_POSITION"],
+			summary.Actions["CLOSE_POSITION"],
+			summary.Actions["HOLD"],
+			summary.Trades,
+			summary.Wins,
+			summary.Losses,
+			summary.RealizedPnL,
+		))
+	}
+
+	if withEntries {
+		for _, entry := range entries {
+			line := fmt.Sprintf("- [%s] %s 置信度%.2f @ %.4f",
+				entry.Timestamp.Format("2006-01-02 15:04"),
+				entry.Decision.Action,
+				entry.Decision.Confidence,
+				entry.Price,
+			)
+			if outcome := describeOutcome(entry); outcome != "" {
+				line += " | " + outcome
+			}
+			if entry.Decision.Reason != "" {
+				line += " | 理由: " + truncateRunes(entry.Decision.Reason, 80)
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	sb.WriteString("- 请参考以上历史: 没有新的有效信号时不要频繁反向开仓, 亏损平仓后应更加谨慎。\n")
+	return sb.String()
+}
+
+// describeOutcome summarizes the execution and realized result of an entry
+func describeOutcome(entry *MemoryEntry) string {
+	var parts []string
+	if entry.Result != "" {
+		status := "未执行"
+		if entry.Executed {
+			status = "已执行"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", status, entry.Result))
+	}
+	if entry.Closed {
+		parts = append(parts, fmt.Sprintf("已实现盈亏: %.2f (%.2f%%)", entry.RealizedPnL, entry.PnLPercent))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
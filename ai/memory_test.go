@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryWindowFoldsIntoSummary(t *testing.T) {
+	memory := NewMemory(2, 0)
+
+	memory.RecordDecision("ETH", &Decision{Action: "OPEN_LONG", Confidence: 0.8}, 2000)
+	memory.RecordExecution("ETH", true, "ok")
+	memory.RecordDecision("ETH", &Decision{Action: "CLOSE_POSITION", Confidence: 1.0}, 1900)
+	memory.RecordOutcome("ETH", -50, -2.5)
+	memory.RecordDecision("ETH", &Decision{Action: "HOLD", Confidence: 0.5}, 1950)
+
+	entries := memory.Entries("ETH")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 verbatim entries, got %d", len(entries))
+	}
+	if entries[0].Decision.Action != "CLOSE_POSITION" || !entries[0].Closed {
+		t.Errorf("expected closed CLOSE_POSITION entry first, got %+v", entries[0])
+	}
+
+	text := memory.Render("ETH")
+	if !strings.Contains(text, "共1次决策") {
+		t.Errorf("summary should count the folded entry:\n%s", text)
+	}
+	if !strings.Contains(text, "-50.00") {
+		t.Errorf("render should include the realized loss:\n%s", text)
+	}
+
+	if memory.Render("BTC") != "" {
+		t.Error("symbols without history should render empty")
+	}
+}
+
+func TestMemoryRenderSummarizesWhenTooLong(t *testing.T) {
+	memory := NewMemory(20, 400)
+
+	for i := 0; i < 10; i++ {
+		memory.RecordDecision("BTC", &Decision{
+			Action:     "HOLD",
+			Confidence: 0.5,
+			Reason:     strings.Repeat("趋势不明确 ", 10),
+		}, 60000)
+	}
+
+	text := memory.Render("BTC")
+	if len(memory.Entries("BTC")) >= 10 {
+		t.Errorf("render should fold entries when over the size limit")
+	}
+	if !strings.Contains(text, "早期汇总") {
+		t.Errorf("render should include a summary line:\n%s", text)
+	}
+}
+
+func TestMemoryChatTurns(t *testing.T) {
+	memory := NewMemory(5, 0)
+	memory.RecordDecision("DOGE", &Decision{Action: "OPEN_SHORT", Confidence: 0.7}, 0.25)
+	memory.RecordExecution("DOGE", false, "模拟模式")
+	memory.RecordDecision("DOGE", &Decision{Action: "HOLD", Confidence: 0.6}, 0.24)
+
+	turns := memory.chatTurns("DOGE")
+	if len(turns) != 4 {
+		t.Fatalf("expected 4 turns, got %d", len(turns))
+	}
+	if turns[1].Role != "assistant" || !strings.Contains(turns[1].Content, "OPEN_SHORT") {
+		t.Errorf("unexpected assistant turn: %+v", turns[1])
+	}
+	if !strings.Contains(turns[2].Content, "模拟模式") {
+		t.Errorf("next user turn should carry the previous result: %s", turns[2].Content)
+	}
+}
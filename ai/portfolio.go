@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PortfolioSymbol attributes AI cost of portfolio-wide calls
+const PortfolioSymbol = "PORTFOLIO"
+
+// PortfolioAnalysis contains market data for every symbol plus account exposure
+type PortfolioAnalysis struct {
+	Timestamp        time.Time
+	Symbols          []*MarketAnalysis
+	AccountBalance   float64
+	OpenPositions    int
+	MaxOpenPositions int
+	TotalExposure    float64
+}
+
+// SymbolDecision is a ranked decision for one symbol of a portfolio analysis
+type SymbolDecision struct {
+	Symbol string `json:"symbol"`
+	Rank   int    `json:"rank"`
+	Decision
+}
+
+// portfolioResponse is the JSON envelope returned by the model in portfolio mode
+type portfolioResponse struct {
+	Decisions []SymbolDecision `json:"decisions"`
+}
+
+// AnalyzePortfolio asks the AI for ranked decisions across all symbols in one call
+func (dm *DecisionMaker) AnalyzePortfolio(portfolio *PortfolioAnalysis) ([]*SymbolDecision, error) {
+	prompt := dm.buildPortfolioPrompt(portfolio)
+
+	response, err := dm.callAI(PortfolioSymbol, []chatMessage{{Role: "user", Content: prompt}})
+	if err != nil {
+		return nil, fmt.Errorf("AI API call failed: %w", err)
+	}
+
+	decisions, err := dm.parsePortfolioDecisions(response, portfolio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AI portfolio decision: %w", err)
+	}
+
+	if dm.memory != nil {
+		prices := make(map[string]float64)
+		for _, analysis := range portfolio.Symbols {
+			prices[analysis.Symbol] = analysis.Market.CurrentPrice
+		}
+		for _, d := range decisions {
+			dm.memory.RecordDecision(d.Symbol, &d.Decision, prices[d.Symbol])
+		}
+	}
+
+	return decisions, nil
+}
+
+// buildPortfolioPrompt creates a single prompt covering all symbols and the account
+func (dm *DecisionMaker) buildPortfolioPrompt(portfolio *PortfolioAnalysis) string {
+	var sb strings.Builder
+
+	sb.WriteString(`你是一位专业的量化交易员,拥有20年加密货币交易经验。现在作为自动化交易系统的组合决策引擎,你需要同时评估多个币种,在有限的仓位额度内选择最优的交易机会。
+
+`)
+	sb.WriteString(fmt.Sprintf("## 账户状态 @ %s\n", portfolio.Timestamp.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("- 账户余额: %.2f\n", portfolio.AccountBalance))
+	sb.WriteString(fmt.Sprintf("- 当前持仓数: %d / 最大持仓数: %d\n", portfolio.OpenPositions, portfolio.MaxOpenPositions))
+	if portfolio.AccountBalance > 0 {
+		sb.WriteString(fmt.Sprintf("- 总风险敞口: %.2f (%.2f%%)\n", portfolio.TotalExposure, portfolio.TotalExposure/portfolio.AccountBalance*100))
+	}
+	sb.WriteString("\n")
+
+	for _, analysis := range portfolio.Symbols {
+		ind := analysis.Indicators
+		pos := analysis.Position
+		mkt := analysis.Market
+
+		sb.WriteString(fmt.Sprintf("## %s\n", analysis.Symbol))
+		sb.WriteString(fmt.Sprintf("- 价格: %.4f, 24小时变化: %.2f%%, 交易量: %.2f\n", mkt.CurrentPrice, mkt.PriceChange, mkt.Volume24h))
+		sb.WriteString(fmt.Sprintf("- 趋势: %s (EMA10=%.4f, EMA60=%.4f, EMA120=%.4f)\n", ind.TrendStrength, ind.EMA10, ind.EMA60, ind.EMA120))
+		sb.WriteString(fmt.Sprintf("- 动量: %s (MACD HIST=%.4f, RSI14=%.2f)\n", ind.MomentumStatus, ind.MACDHIST, ind.RSI14))
+		sb.WriteString(fmt.Sprintf("- 布林带: %s (上轨=%.4f, 下轨=%.4f, 带宽=%.4f)\n", ind.BBPosition, ind.BBUpper, ind.BBLower, ind.BBWidth))
+		sb.WriteString(fmt.Sprintf("- 量价关系: %s\n", ind.VolumePriceRelation))
+		if pos != nil && pos.Size > 0 {
+			sb.WriteString(fmt.Sprintf("- 持仓: %s %.4f @ %.4f, 盈亏 %.2f%%, 持仓时间 %s\n", pos.Side, pos.Size, pos.EntryPrice, pos.PnLPercent, pos.HoldingTime.String()))
+		} else {
+			sb.WriteString("- 持仓: 无\n")
+		}
+		if dm.memory != nil {
+			sb.WriteString(dm.memory.Render(analysis.Symbol))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(`## 决策要求
+- 为每个币种给出一个决策, 并按执行优先级排序 (rank 1 最优先)
+- 新开仓数量不能超过剩余仓位额度, 额度不足时只保留最优机会, 其余给出 HOLD
+- 避免同时在高度相关的币种上开同方向仓位
+- 开仓必须给出止损和止盈, 风险回报比至少1:2
+- 单次开仓不超过总资金的10%, 总风险敞口不超过25%
+
+请严格按照以下JSON格式返回决策,不要包含任何其他文字:
+
+{
+  "decisions": [
+    {
+      "symbol": "币种",
+      "rank": 1,
+      "action": "OPEN_LONG|OPEN_SHORT|ADD_POSITION|CLOSE_POSITION|HOLD",
+      "confidence": 0.0-1.0,
+      "size": 0.0-1.0,
+      "leverage": 1-20,
+      "reason": "详细的技术分析理由",
+      "stop_loss": 具体价格,
+      "take_profit": 具体价格,
+      "risk_level": "LOW|MEDIUM|HIGH",
+      "expected_holding_period": "SHORT|MEDIUM|LONG"
+    }
+  ]
+}`)
+
+	return sb.String()
+}
+
+// parsePortfolioDecisions extracts, validates and ranks the per-symbol decisions
+func (dm *DecisionMaker) parsePortfolioDecisions(response string, portfolio *PortfolioAnalysis) ([]*SymbolDecision, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no JSON found in response")
+	}
+
+	var parsed portfolioResponse
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w\nResponse: %s", err, response[start:end+1])
+	}
+
+	known := make(map[string]bool)
+	for _, analysis := range portfolio.Symbols {
+		known[analysis.Symbol] = true
+	}
+
+	seen := make(map[string]bool)
+	var decisions []*SymbolDecision
+	for i := range parsed.Decisions {
+		d := &parsed.Decisions[i]
+		if !known[d.Symbol] {
+			return nil, fmt.Errorf("decision for unknown symbol: %s", d.Symbol)
+		}
+		if seen[d.Symbol] {
+			return nil, fmt.Errorf("duplicate decision for symbol: %s", d.Symbol)
+		}
+		seen[d.Symbol] = true
+
+		if err := dm.validateDecision(&d.Decision); err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Symbol, err)
+		}
+		decisions = append(decisions, d)
+	}
+
+	// Unranked decisions go last, ties keep the model's order
+	sort.SliceStable(decisions, func(i, j int) bool {
+		ri, rj := decisions[i].Rank, decisions[j].Rank
+		if ri <= 0 {
+			return false
+		}
+		if rj <= 0 {
+			return true
+		}
+		return ri < rj
+	})
+
+	return decisions, nil
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"testing"
+
+	"aitrading/hyperliquid"
+)
+
+func testPortfolio(symbols ...string) *PortfolioAnalysis {
+	portfolio := &PortfolioAnalysis{}
+	for _, symbol := range symbols {
+		portfolio.Symbols = append(portfolio.Symbols, &MarketAnalysis{
+			Symbol: symbol,
+			Market: &hyperliquid.MarketInfo{Symbol: symbol, CurrentPrice: 100},
+		})
+	}
+	return portfolio
+}
+
+func TestParsePortfolioDecisionsRanksInOrder(t *testing.T) {
+	dm := NewDecisionMaker("test", "", "", "", 0, 0, 1)
+
+	response := "```json\n" + `{"decisions": [
+		{"symbol": "ETH", "rank": 2, "action": "OPEN_LONG", "confidence": 0.7, "size": 0.05, "leverage": 3, "risk_level": "MEDIUM"},
+		{"symbol": "DOGE", "rank": 0, "action": "HOLD", "confidence": 0.5, "size": 0, "leverage": 1, "risk_level": "LOW"},
+		{"symbol": "BTC", "rank": 1, "action": "OPEN_SHORT", "confidence": 0.8, "size": 0.05, "leverage": 2, "risk_level": "LOW"}
+	]}` + "\n```"
+
+	decisions, err := dm.parsePortfolioDecisions(response, testPortfolio("ETH", "BTC", "DOGE"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order := []string{"BTC", "ETH", "DOGE"}
+	if len(decisions) != len(order) {
+		t.Fatalf("expected %d decisions, got %d", len(order), len(decisions))
+	}
+	for i, symbol := range order {
+		if decisions[i].Symbol != symbol {
+			t.Errorf("position %d: expected %s, got %s", i, symbol, decisions[i].Symbol)
+		}
+	}
+}
+
+func TestParsePortfolioDecisionsRejectsUnknownSymbol(t *testing.T) {
+	dm := NewDecisionMaker("test", "", "", "", 0, 0, 1)
+
+	response := `{"decisions": [{"symbol": "SOL", "rank": 1, "action": "HOLD", "confidence": 0.5, "leverage": 1, "risk_level": "LOW"}]}`
+	if _, err := dm.parsePortfolioDecisions(response, testPortfolio("ETH")); err == nil {
+		t.Error("expected error for symbol outside the portfolio")
+	}
+}
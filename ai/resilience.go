@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// provider is one chat completion endpoint the decision maker can call
+type provider struct {
+	name    string
+	apiKey  string
+	baseURL string
+	model   string
+	breaker *circuitBreaker
+}
+
+// APIError is a non-200 response from an AI provider
+type APIError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether a failed call is worth repeating
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	// Transport errors and timeouts
+	return true
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// RetryPolicy configures exponential backoff between attempts on one provider
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// backoff returns the wait before retry number attempt (0-based)
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// circuitBreaker stops calling a provider after repeated failures until a cooldown passes
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool // A trial call is in flight after the cooldown
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow reports whether a call may be attempted. Once the cooldown has
+// elapsed a single trial call is let through (half-open); further calls are
+// rejected until it reports Success or Failure.
+func (cb *circuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.open() {
+		return false
+	}
+	if cb.threshold > 0 && cb.failures >= cb.threshold {
+		cb.probing = true
+	}
+	return true
+}
+
+// Success closes the breaker
+func (cb *circuitBreaker) Success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures = 0
+	cb.probing = false
+}
+
+// Failure records a failed call and (re)opens the breaker at the threshold
+func (cb *circuitBreaker) Failure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.probing = false
+	if cb.threshold > 0 && cb.failures >= cb.threshold {
+		cb.openedAt = cb.now()
+	}
+}
+
+// Open reports whether the breaker is currently rejecting calls, without
+// taking the trial call
+func (cb *circuitBreaker) Open() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.open()
+}
+
+func (cb *circuitBreaker) open() bool {
+	if cb.threshold <= 0 || cb.failures < cb.threshold {
+		return false
+	}
+	return cb.probing || cb.now().Sub(cb.openedAt) < cb.cooldown
+}
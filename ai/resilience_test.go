@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const okCompletion = `{"choices": [{"message": {"content": "ok"}}]}`
+
+func newTestDecisionMaker(baseURL string) (*DecisionMaker, *[]time.Duration) {
+	dm := NewDecisionMaker("primary", "key", baseURL, "model", 0, 100, 5)
+	var waits []time.Duration
+	dm.sleep = func(d time.Duration) { waits = append(waits, d) }
+	return dm, &waits
+}
+
+func TestCallAIRetriesRateLimitWithRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, okCompletion)
+	}))
+	defer server.Close()
+
+	dm, waits := newTestDecisionMaker(server.URL)
+	dm.SetRetryPolicy(3, time.Second, time.Minute)
+
+	content, err := dm.callAI("ETH", []chatMessage{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "ok" {
+		t.Errorf("unexpected content: %s", content)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+		t.Errorf("expected a single 7s Retry-After wait, got %v", *waits)
+	}
+}
+
+func TestCallAIExponentialBackoffAndCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	dm, waits := newTestDecisionMaker(server.URL)
+	dm.SetRetryPolicy(4, time.Second, 5*time.Second)
+
+	if _, err := dm.callAI("ETH", []chatMessage{{Role: "user", Content: "hi"}}); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if fmt.Sprint(*waits) != fmt.Sprint(expected) {
+		t.Errorf("expected waits %v, got %v", expected, *waits)
+	}
+}
+
+func TestCallAIDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	dm, _ := newTestDecisionMaker(server.URL)
+	dm.SetRetryPolicy(3, time.Second, 0)
+
+	if _, err := dm.callAI("ETH", []chatMessage{{Role: "user", Content: "hi"}}); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected a single call for 401, got %d", calls)
+	}
+}
+
+func TestCallAIFailsOverAndTripsBreaker(t *testing.T) {
+	var primaryCalls int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, okCompletion)
+	}))
+	defer fallback.Close()
+
+	dm, _ := newTestDecisionMaker(primary.URL)
+	dm.AddFallback("fallback", "key", fallback.URL, "model")
+	dm.SetCircuitBreaker(2, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, err := dm.callAI("ETH", []chatMessage{{Role: "user", Content: "hi"}}); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+		if dm.LastProvider() != "fallback" {
+			t.Errorf("call %d: expected fallback provider, got %s", i, dm.LastProvider())
+		}
+	}
+
+	if primaryCalls != 2 {
+		t.Errorf("breaker should stop calls to primary after 2 failures, got %d calls", primaryCalls)
+	}
+}
+
+func TestCircuitBreakerHalfOpensAfterCooldown(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(1, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	cb.Failure()
+	if cb.Allow() {
+		t.Fatal("breaker should be open after reaching the threshold")
+	}
+
+	now = now.Add(time.Minute)
+	if cb.Open() {
+		t.Fatal("breaker should half-open after cooldown")
+	}
+	if !cb.Allow() {
+		t.Fatal("breaker should allow a trial call after cooldown")
+	}
+	if cb.Allow() || !cb.Open() {
+		t.Fatal("breaker should allow a single trial call while it is in flight")
+	}
+
+	cb.Success()
+	if cb.Open() {
+		t.Error("breaker should close after a successful call")
+	}
+}
+
+func TestCircuitBreakerReopensAfterFailedTrial(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(2, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	cb.Failure()
+	cb.Failure()
+	now = now.Add(time.Minute)
+	if !cb.Allow() {
+		t.Fatal("breaker should allow a trial call after cooldown")
+	}
+
+	cb.Failure()
+	if cb.Allow() {
+		t.Fatal("a failed trial call should reopen the breaker")
+	}
+	now = now.Add(time.Minute)
+	if !cb.Allow() || cb.Allow() {
+		t.Error("breaker should allow one more trial call after the next cooldown")
+	}
+}
+
+func TestLastProviderConcurrentCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, okCompletion)
+	}))
+	defer server.Close()
+
+	dm, _ := newTestDecisionMaker(server.URL)
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			dm.callAI("ETH", []chatMessage{{Role: "user", Content: "hi"}})
+			dm.LastProvider()
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	if dm.LastProvider() != "primary" {
+		t.Errorf("expected primary provider, got %s", dm.LastProvider())
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -15,50 +16,480 @@ type Config struct {
 	Hyperliquid HyperliquidConfig `yaml:"hyperliquid"`
 	Monitoring  MonitoringConfig  `yaml:"monitoring"`
 	System      SystemConfig      `yaml:"system"`
+	Strategy    StrategyConfig    `yaml:"strategy"`
+	Execution   ExecutionConfig   `yaml:"execution"`
+
+	Account string `yaml:"-"` // Name of the account this config was resolved for by ForAccount
 }
 
+// Market types a trading symbol can declare
+const (
+	MarketPerp = "perp"
+	MarketSpot = "spot"
+)
+
+// spotQuote is the quote token of spot symbols declared as "BASE:spot"
+const spotQuote = "USDC"
+
 type TradingConfig struct {
-	Symbols           []string `yaml:"symbols"`
-	Timeframe         string   `yaml:"timeframe"`
-	Interval          string   `yaml:"interval"`
-	MaxPositionSize   float64  `yaml:"max_position_size"`
-	MinConfidence     float64  `yaml:"min_confidence"`
-	TradingEnabled    bool     `yaml:"trading_enabled"`
-	MaxOpenPositions  int      `yaml:"max_open_positions"`
-	MaxLeverage       int      `yaml:"max_leverage"`
+	Symbols          []string `yaml:"symbols"` // "ETH" or "ETH:perp" for perps, "HYPE:spot" or "HYPE/USDC" for spot
+	Timeframe        string   `yaml:"timeframe"`
+	Interval         string   `yaml:"interval"`
+	MaxPositionSize  float64  `yaml:"max_position_size"`
+	MinConfidence    float64  `yaml:"min_confidence"`
+	TradingEnabled   bool     `yaml:"trading_enabled"`
+	MaxOpenPositions int      `yaml:"max_open_positions"`
+	MaxLeverage      int      `yaml:"max_leverage"`
+	PortfolioMode    bool     `yaml:"portfolio_mode"` // One AI call ranks decisions across all symbols
+
+	SymbolOverrides map[string]SymbolOverride `yaml:"symbol_overrides"` // Per-symbol thresholds, keyed like Symbols
+}
+
+// SymbolOverride replaces trading and risk thresholds for one symbol.
+// Zero values inherit the global setting.
+type SymbolOverride struct {
+	MinConfidence      float64 `yaml:"min_confidence"`
+	MaxPositionSize    float64 `yaml:"max_position_size"`
+	MaxLeverage        int     `yaml:"max_leverage"`
+	MinRiskRewardRatio float64 `yaml:"min_risk_reward_ratio"`
+
+	HoldingTime HoldingTimeConfig `yaml:"holding_time"`
+	Cooldown    CooldownConfig    `yaml:"cooldown"`
+
+	ExecutionAlgo string `yaml:"execution_algo"` // Replaces execution.algo for this symbol
+}
+
+// Override returns the per-symbol override for symbol, or a zero override
+func (t *TradingConfig) Override(symbol string) SymbolOverride {
+	return t.SymbolOverrides[symbol]
+}
+
+// Market returns MarketSpot for spot pairs ("HYPE/USDC") and MarketPerp
+// otherwise
+func (t *TradingConfig) Market(symbol string) string {
+	if strings.Contains(symbol, "/") {
+		return MarketSpot
+	}
+	return MarketPerp
+}
+
+// HasSpot reports whether any traded symbol is a spot pair
+func (t *TradingConfig) HasSpot() bool {
+	for _, symbol := range t.Symbols {
+		if t.Market(symbol) == MarketSpot {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSymbols resolves "SYMBOL:market" entries, in symbols and as
+// symbol_overrides keys, to the symbol the bot trades: "ETH:perp" becomes
+// "ETH" and "HYPE:spot" becomes "HYPE/USDC"
+func (t *TradingConfig) normalizeSymbols() error {
+	for i, entry := range t.Symbols {
+		symbol, err := normalizeSymbol(entry)
+		if err != nil {
+			return err
+		}
+		t.Symbols[i] = symbol
+	}
+
+	for entry, override := range t.SymbolOverrides {
+		symbol, err := normalizeSymbol(entry)
+		if err != nil {
+			return fmt.Errorf("symbol_overrides: %w", err)
+		}
+		if symbol == entry {
+			continue
+		}
+		if _, ok := t.SymbolOverrides[symbol]; ok {
+			return fmt.Errorf("symbol_overrides: %q and %q are the same symbol", entry, symbol)
+		}
+		delete(t.SymbolOverrides, entry)
+		t.SymbolOverrides[symbol] = override
+	}
+	return nil
+}
+
+// normalizeSymbol resolves one "SYMBOL:market" entry
+func normalizeSymbol(entry string) (string, error) {
+	symbol, market, ok := strings.Cut(entry, ":")
+	if !ok {
+		return entry, nil
+	}
+	switch strings.ToLower(market) {
+	case MarketPerp:
+		if strings.Contains(symbol, "/") {
+			return "", fmt.Errorf("symbol %q: a spot pair cannot be traded as a perp", entry)
+		}
+		return symbol, nil
+	case MarketSpot:
+		if !strings.Contains(symbol, "/") {
+			symbol += "/" + spotQuote
+		}
+		return symbol, nil
+	default:
+		return "", fmt.Errorf("symbol %q: unknown market %q, expected %q or %q", entry, market, MarketPerp, MarketSpot)
+	}
 }
 
 type RiskConfig struct {
-	MaxDrawdown         float64 `yaml:"max_drawdown"`
-	DailyLossLimit      float64 `yaml:"daily_loss_limit"`
+	MaxDrawdown          float64 `yaml:"max_drawdown"`
+	DailyLossLimit       float64 `yaml:"daily_loss_limit"`
 	PositionRiskPerTrade float64 `yaml:"position_risk_per_trade"`
-	MaxTotalExposure    float64 `yaml:"max_total_exposure"`
-	CorrelationLimit    float64 `yaml:"correlation_limit"`
-	MinRiskRewardRatio  float64 `yaml:"min_risk_reward_ratio"`
+	MaxTotalExposure     float64 `yaml:"max_total_exposure"`
+	CorrelationLimit     float64 `yaml:"correlation_limit"`
+	MinRiskRewardRatio   float64 `yaml:"min_risk_reward_ratio"`
+
+	CorrelationWindow int    `yaml:"correlation_window"` // Candle returns used for correlation, default 100
+	CorrelationAction string `yaml:"correlation_action"` // "reduce" (default) scales size down, "reject" blocks the order
+
+	// Portfolio-wide caps as fractions of account value; zero disables a cap
+	MaxNetExposure       float64 `yaml:"max_net_exposure"`       // |long - short| notional
+	MaxLongExposure      float64 `yaml:"max_long_exposure"`      // Long notional
+	MaxShortExposure     float64 `yaml:"max_short_exposure"`     // Short notional
+	MaxMarginUtilization float64 `yaml:"max_margin_utilization"` // Margin used
+
+	// Position sizing: "fixed" (default) keeps the decision's balance fraction,
+	// "risk" risks position_risk_per_trade to the stop loss, "volatility" risks
+	// it over atr_multiplier ATRs
+	SizingMode    string  `yaml:"sizing_mode"`
+	ATRMultiplier float64 `yaml:"atr_multiplier"` // Stop distance in ATRs for volatility sizing, default 2
+	KellyFraction float64 `yaml:"kelly_fraction"` // Caps risk at this fraction of the Kelly bet; zero disables
+
+	MarginMode        string `yaml:"margin_mode"`        // "cross" (default) or "isolated" for new positions
+	LiquidationAction string `yaml:"liquidation_action"` // "reduce" (default) lowers isolated leverage, "reject" blocks the order
+
+	KillSwitch KillSwitchConfig `yaml:"kill_switch"`
+
+	HoldingTime HoldingTimeConfig `yaml:"holding_time"`
+
+	Cooldown CooldownConfig `yaml:"cooldown"`
+}
+
+// CooldownConfig throttles re-entries and churn per symbol. Zero disables a limit.
+type CooldownConfig struct {
+	AfterClose      int `yaml:"after_close"`        // Seconds before a symbol may be reopened after a close
+	AfterStopLoss   int `yaml:"after_stop_loss"`    // Seconds before reopening after a stop-out, replaces after_close
+	MinFlipInterval int `yaml:"min_flip_interval"`  // Seconds between entries in opposite directions
+	MaxTradesPerDay int `yaml:"max_trades_per_day"` // Opens and adds per symbol per day
+
+	StateFile string `yaml:"state_file"` // Persists recent trades across restarts; ignored in symbol overrides
+}
+
+// ExecutionConfig controls how orders are priced when they are sent
+type ExecutionConfig struct {
+	OrderType   string  `yaml:"order_type"`   // "gtc" (default) rests a limit at the live mid, "ioc" crosses the book
+	PriceBand   float64 `yaml:"price_band"`   // Max move of the live mid from the analysis price for entries; zero disables
+	MaxSlippage float64 `yaml:"max_slippage"` // IOC limit beyond the best bid/ask as a fraction, default 0.005
+
+	// Algo works orders: "limit" (default) sends one order, "market" sends one
+	// IOC order whatever order_type is, "post_only" rests Alo orders at the
+	// touch and reprices, "twap" slices over time, "iceberg" sends chunks one
+	// at a time and "native_twap" uses the exchange's TWAP. Stop loss, take
+	// profit and flatten exits are always sent as "market".
+	Algo            string  `yaml:"algo"`
+	RepriceInterval int     `yaml:"reprice_interval"` // Seconds a post-only order rests before repricing, default 10
+	MaxReprices     int     `yaml:"max_reprices"`     // Post-only attempts before giving up, default 5
+	TWAPMinutes     int     `yaml:"twap_minutes"`     // TWAP duration, default 10
+	TWAPSlices      int     `yaml:"twap_slices"`      // Child orders of the software TWAP, default 5
+	TWAPRandomize   bool    `yaml:"twap_randomize"`   // Randomize native TWAP slice timing
+	IcebergChunk    float64 `yaml:"iceberg_chunk"`    // Max notional in USD per iceberg child, default 1000
+	IcebergWait     int     `yaml:"iceberg_wait"`     // Seconds a resting child may wait for fills, default 30
+
+	// ProtectiveOrders places the stop loss and take profit of new perp
+	// positions on the exchange as reduce-only trigger orders, moved when the
+	// levels change, rather than only checking them each cycle
+	ProtectiveOrders bool `yaml:"protective_orders"`
+
+	// DeadMansSwitch arms the exchange's scheduleCancel this many seconds
+	// ahead, refreshed while the bot runs, so resting orders are cancelled if
+	// it dies. The cancel takes every open order, protective triggers
+	// included, leaving positions unprotected. Zero disables; the exchange
+	// requires at least 5.
+	DeadMansSwitch int `yaml:"dead_mans_switch"`
+}
+
+// HoldingTimeConfig sets the maximum holding time per expected holding period
+// as Go durations, e.g. "4h". Empty fields use 4h, 24h and 168h.
+type HoldingTimeConfig struct {
+	Short   string `yaml:"short"`
+	Medium  string `yaml:"medium"`
+	Long    string `yaml:"long"`
+	Enforce bool   `yaml:"enforce"` // Force CLOSE_POSITION when exceeded instead of only warning
+}
+
+// KillSwitchConfig controls the trading state machine (ACTIVE, REDUCE_ONLY,
+// HALTED, FLATTEN). Empty states use the defaults noted per field.
+type KillSwitchConfig struct {
+	StateFile    string `yaml:"state_file"`     // Persists the state across restarts
+	TriggerFile  string `yaml:"trigger_file"`   // Creating this file switches state; its content names the state
+	OnDrawdown   string `yaml:"on_drawdown"`    // Default HALTED
+	OnDailyLoss  string `yaml:"on_daily_loss"`  // Default REDUCE_ONLY
+	ErrorBurst   int    `yaml:"error_burst"`    // Cycle errors that trigger OnErrorBurst; zero disables
+	ErrorWindow  int    `yaml:"error_window"`   // Seconds the error burst is counted over, default 600
+	OnErrorBurst string `yaml:"on_error_burst"` // Default HALTED
 }
 
 type AIConfig struct {
-	Provider    string     `yaml:"provider"`
-	APIKey      string     `yaml:"api_key"`
-	BaseURL     string     `yaml:"base_url"`
-	Model       string     `yaml:"model"`
-	Temperature float64    `yaml:"temperature"`
-	MaxTokens   int        `yaml:"max_tokens"`
-	Timeout     int        `yaml:"timeout"`
-	Qwen        QwenConfig `yaml:"qwen"`
+	Provider    string       `yaml:"provider"`
+	APIKey      Secret       `yaml:"api_key"`
+	BaseURL     string       `yaml:"base_url"`
+	Model       string       `yaml:"model"`
+	Temperature float64      `yaml:"temperature"`
+	MaxTokens   int          `yaml:"max_tokens"`
+	Timeout     int          `yaml:"timeout"`
+	Qwen        QwenConfig   `yaml:"qwen"`
+	Memory      MemoryConfig `yaml:"memory"`
+
+	FallbackProvider string               `yaml:"fallback_provider"` // Provider used when the primary fails, e.g. "qwen"
+	CircuitBreaker   CircuitBreakerConfig `yaml:"circuit_breaker"`
+	Budget           BudgetConfig         `yaml:"budget"`
+}
+
+// BudgetConfig prices AI token usage and caps the daily spend
+type BudgetConfig struct {
+	DailyLimit     float64                 `yaml:"daily_limit"`     // Spend per day that triggers on_exceed, 0 disables
+	HardLimit      float64                 `yaml:"hard_limit"`      // Spend per day beyond which every call is skipped, 0 disables
+	OnExceed       string                  `yaml:"on_exceed"`       // "hold" or "downgrade"
+	DowngradeModel string                  `yaml:"downgrade_model"` // Cheaper model of the primary provider used when downgrading
+	StateFile      string                  `yaml:"state_file"`      // Persists today's totals across restarts
+	Pricing        map[string]ModelPricing `yaml:"pricing"`         // Keyed by model name
+}
+
+// validateBudget rejects models the bot can call without a price while a
+// budget limit is set, since their calls would count as free
+func (a *AIConfig) validateBudget() error {
+	switch a.Budget.OnExceed {
+	case "", "hold", "downgrade":
+	default:
+		return fmt.Errorf("budget.on_exceed must be \"hold\" or \"downgrade\", got %q", a.Budget.OnExceed)
+	}
+	if a.Budget.DailyLimit <= 0 && a.Budget.HardLimit <= 0 {
+		return nil
+	}
+
+	models := []string{a.providerModel(a.Provider)}
+	if a.FallbackProvider != "" {
+		models = append(models, a.providerModel(a.FallbackProvider))
+	}
+	if a.Budget.OnExceed == "downgrade" && a.Budget.DowngradeModel != "" {
+		models = append(models, a.Budget.DowngradeModel)
+	}
+	for _, model := range models {
+		if _, ok := a.Budget.Pricing[model]; !ok {
+			return fmt.Errorf("budget.pricing has no price for model %q", model)
+		}
+	}
+	return nil
+}
+
+// providerModel returns the model configured for a provider
+func (a *AIConfig) providerModel(provider string) string {
+	if provider == "qwen" {
+		return a.Qwen.Model
+	}
+	return a.Model
+}
+
+// ModelPricing is the price per million tokens of a model
+type ModelPricing struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// CircuitBreakerConfig controls when a failing AI provider is skipped
+type CircuitBreakerConfig struct {
+	FailureThreshold int `yaml:"failure_threshold"` // Consecutive failed calls before tripping, 0 disables
+	Cooldown         int `yaml:"cooldown"`          // Seconds before a tripped provider is tried again
+}
+
+// MemoryConfig controls the rolling per-symbol decision memory fed into prompts
+type MemoryConfig struct {
+	Enabled        bool `yaml:"enabled"`
+	Window         int  `yaml:"window"`           // Recent entries rendered verbatim per symbol
+	MaxPromptChars int  `yaml:"max_prompt_chars"` // Older entries are summarized once the block exceeds this
+	AsChatTurns    bool `yaml:"as_chat_turns"`    // Also replay recent decisions as prior chat messages
 }
 
 type QwenConfig struct {
-	APIKey  string `yaml:"api_key"`
+	APIKey  Secret `yaml:"api_key"`
 	BaseURL string `yaml:"base_url"`
 	Model   string `yaml:"model"`
 }
 
+// StrategyConfig controls the rule-based strategy engine
+type StrategyConfig struct {
+	Mode           string  `yaml:"mode"`             // "off", "fallback", "veto" or "standalone"
+	MinSignals     int     `yaml:"min_signals"`      // Agreeing entry rules required to open
+	CloseSignals   int     `yaml:"close_signals"`    // Opposing rules that close an open position
+	VetoMinSignals int     `yaml:"veto_min_signals"` // Confirming rules an AI entry needs in veto mode
+	VolumeFactor   float64 `yaml:"volume_factor"`    // Volume over VMA20 that counts as confirmation
+	Size           float64 `yaml:"size"`             // Fraction of balance per rule-based entry
+	Leverage       int     `yaml:"leverage"`
+	StopLossPct    float64 `yaml:"stop_loss_pct"` // Stop distance as a fraction of price
+	RiskReward     float64 `yaml:"risk_reward"`   // Take profit distance as a multiple of the stop
+}
+
 type HyperliquidConfig struct {
-	APIURL         string `yaml:"api_url"`
-	PrivateKey     string `yaml:"private_key"`
-	AccountAddress string `yaml:"account_address"`
-	Testnet        bool   `yaml:"testnet"`
+	APIURL         string `yaml:"api_url"`         // Defaults to the endpoint of the network testnet selects
+	PrivateKey     Secret `yaml:"private_key"`     // Master key; only needed to approve agents unless allow_master_key
+	AccountAddress string `yaml:"account_address"` // Master account address
+	VaultAddress   string `yaml:"vault_address"`   // Vault or sub-account traded on behalf of; the key's account signs
+
+	// Testnet selects the testnet endpoint and signs for the testnet chain.
+	// Trading on mainnet is refused unless ConfirmMainnet is set.
+	Testnet        bool `yaml:"testnet"`
+	ConfirmMainnet bool `yaml:"confirm_mainnet"`
+
+	// AgentKey is the key of an API agent wallet approved for the master
+	// account; it can trade but not withdraw. The bot refuses to sign with
+	// the master key unless AllowMasterKey is set.
+	AgentKey       Secret `yaml:"agent_key"`
+	AllowMasterKey bool   `yaml:"allow_master_key"`
+
+	// Accounts are traded concurrently, each with its own symbols and risk
+	// limits. When empty the single account above is traded.
+	Accounts []AccountConfig `yaml:"accounts"`
+}
+
+// SigningKey resolves the key the bot trades with: the agent key when set,
+// otherwise the master key
+func (h *HyperliquidConfig) SigningKey() (string, error) {
+	if h.AgentKey != "" {
+		return h.AgentKey.Resolve()
+	}
+	return h.PrivateKey.Resolve()
+}
+
+// TradingAddress returns the address whose positions are traded: the vault
+// or sub-account when set, otherwise the account itself
+func (h *HyperliquidConfig) TradingAddress() string {
+	if h.VaultAddress != "" {
+		return h.VaultAddress
+	}
+	return h.AccountAddress
+}
+
+// AccountConfig is one named account. Empty keys and addresses inherit the
+// hyperliquid section, empty symbols inherit trading.symbols, and fields set
+// under risk replace the global risk settings for this account only.
+type AccountConfig struct {
+	Name           string    `yaml:"name"`
+	PrivateKey     Secret    `yaml:"private_key"`
+	AgentKey       Secret    `yaml:"agent_key"`
+	AccountAddress string    `yaml:"account_address"`
+	VaultAddress   string    `yaml:"vault_address"`
+	Symbols        []string  `yaml:"symbols"`
+	Risk           yaml.Node `yaml:"risk"`
+}
+
+// Accounts returns the config of every account to trade: one per entry of
+// hyperliquid.accounts, or the config itself when there are none
+func (c *Config) Accounts() ([]*Config, error) {
+	if len(c.Hyperliquid.Accounts) == 0 {
+		return []*Config{c}, nil
+	}
+	configs := make([]*Config, 0, len(c.Hyperliquid.Accounts))
+	for _, account := range c.Hyperliquid.Accounts {
+		accountConfig, err := c.ForAccount(account)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, accountConfig)
+	}
+	return configs, nil
+}
+
+// ForAccount returns a copy of the config that trades account alone. The
+// kill switch state file gets the account name as a suffix, unless the
+// account sets its own, so each account halts independently.
+func (c *Config) ForAccount(account AccountConfig) (*Config, error) {
+	accountConfig := *c
+	accountConfig.Account = account.Name
+	accountConfig.Hyperliquid.Accounts = nil
+
+	if account.PrivateKey != "" {
+		accountConfig.Hyperliquid.PrivateKey = account.PrivateKey
+	}
+	if account.AgentKey != "" {
+		accountConfig.Hyperliquid.AgentKey = account.AgentKey
+	}
+	if account.AccountAddress != "" {
+		accountConfig.Hyperliquid.AccountAddress = account.AccountAddress
+	}
+	if account.VaultAddress != "" {
+		accountConfig.Hyperliquid.VaultAddress = account.VaultAddress
+	}
+	if len(account.Symbols) > 0 {
+		accountConfig.Trading.Symbols = append([]string(nil), account.Symbols...)
+		if err := accountConfig.Trading.normalizeSymbols(); err != nil {
+			return nil, fmt.Errorf("account %s: %w", account.Name, err)
+		}
+	}
+
+	if !account.Risk.IsZero() {
+		if err := account.Risk.Decode(&accountConfig.Risk); err != nil {
+			return nil, fmt.Errorf("account %s: invalid risk config: %w", account.Name, err)
+		}
+	}
+	if stateFile := accountConfig.Risk.KillSwitch.StateFile; stateFile != "" && stateFile == c.Risk.KillSwitch.StateFile {
+		accountConfig.Risk.KillSwitch.StateFile = accountStateFile(stateFile, account.Name)
+	}
+	if stateFile := accountConfig.Risk.Cooldown.StateFile; stateFile != "" && stateFile == c.Risk.Cooldown.StateFile {
+		accountConfig.Risk.Cooldown.StateFile = accountStateFile(stateFile, account.Name)
+	}
+
+	return &accountConfig, nil
+}
+
+// accountStateFile names an account's copy of a shared state file
+func accountStateFile(stateFile, account string) string {
+	ext := filepath.Ext(stateFile)
+	return strings.TrimSuffix(stateFile, ext) + "." + account + ext
+}
+
+// validateAccounts checks account names, which name state files, and
+// resolves each account's symbols and risk settings
+func (c *Config) validateAccounts() error {
+	seen := make(map[string]bool)
+	for i := range c.Hyperliquid.Accounts {
+		account := &c.Hyperliquid.Accounts[i]
+		account.PrivateKey = Secret(expandEnv(string(account.PrivateKey)))
+		account.AgentKey = Secret(expandEnv(string(account.AgentKey)))
+		account.AccountAddress = expandEnv(account.AccountAddress)
+		account.VaultAddress = expandEnv(account.VaultAddress)
+
+		if !validAccountName(account.Name) {
+			return fmt.Errorf("account %d: name %q must be non-empty letters, digits, '-' or '_'", i, account.Name)
+		}
+		if seen[account.Name] {
+			return fmt.Errorf("account %s is defined twice", account.Name)
+		}
+		seen[account.Name] = true
+
+		symbols := TradingConfig{Symbols: account.Symbols}
+		if err := symbols.normalizeSymbols(); err != nil {
+			return fmt.Errorf("account %s: %w", account.Name, err)
+		}
+		if _, err := c.ForAccount(*account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validAccountName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
 }
 
 type MonitoringConfig struct {
@@ -70,7 +501,8 @@ type MonitoringConfig struct {
 
 type SystemConfig struct {
 	MaxRetries          int `yaml:"max_retries"`
-	RetryDelay          int `yaml:"retry_delay"`
+	RetryDelay          int `yaml:"retry_delay"`     // Base backoff in seconds, doubled per retry
+	MaxRetryDelay       int `yaml:"max_retry_delay"` // Backoff cap in seconds, 0 for no cap
 	HealthCheckInterval int `yaml:"health_check_interval"`
 }
 
@@ -89,9 +521,27 @@ func Load(filename string) (*Config, error) {
 	}
 
 	// Expand environment variables in fields that might contain them
-	config.AI.APIKey = expandEnv(config.AI.APIKey)
-	config.Hyperliquid.PrivateKey = expandEnv(config.Hyperliquid.PrivateKey)
+	config.AI.APIKey = Secret(expandEnv(string(config.AI.APIKey)))
+	config.AI.Qwen.APIKey = Secret(expandEnv(string(config.AI.Qwen.APIKey)))
+	config.Hyperliquid.PrivateKey = Secret(expandEnv(string(config.Hyperliquid.PrivateKey)))
+	config.Hyperliquid.AgentKey = Secret(expandEnv(string(config.Hyperliquid.AgentKey)))
 	config.Hyperliquid.AccountAddress = expandEnv(config.Hyperliquid.AccountAddress)
+	config.Hyperliquid.VaultAddress = expandEnv(config.Hyperliquid.VaultAddress)
+
+	if err := config.Trading.normalizeSymbols(); err != nil {
+		return nil, fmt.Errorf("invalid trading config: %w", err)
+	}
+	if err := config.AI.validateBudget(); err != nil {
+		return nil, fmt.Errorf("invalid ai config: %w", err)
+	}
+	switch config.Strategy.Mode {
+	case "", "off", "fallback", "veto", "standalone":
+	default:
+		return nil, fmt.Errorf("invalid strategy config: mode must be \"off\", \"fallback\", \"veto\" or \"standalone\", got %q", config.Strategy.Mode)
+	}
+	if err := config.validateAccounts(); err != nil {
+		return nil, fmt.Errorf("invalid hyperliquid config: %w", err)
+	}
 
 	return &config, nil
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -13,8 +14,8 @@ func TestLoadConfig(t *testing.T) {
 	}
 
 	// Verify trading config
-	if cfg.Trading.Symbol == "" {
-		t.Error("Symbol should not be empty")
+	if len(cfg.Trading.Symbols) == 0 {
+		t.Error("Symbols should not be empty")
 	}
 	if cfg.Trading.Interval == "" {
 		t.Error("Interval should not be empty")
@@ -49,6 +50,9 @@ func TestLoadTestConfig(t *testing.T) {
 	if cfg.Trading.TradingEnabled {
 		t.Error("Test config should have trading disabled")
 	}
+	if !cfg.Hyperliquid.Testnet {
+		t.Error("Test config should run on testnet")
+	}
 
 	// Test config should be more conservative
 	if cfg.Trading.MaxPositionSize > 0.1 {
@@ -64,7 +68,7 @@ func TestEnvironmentVariableExpansion(t *testing.T) {
 	// Create a temporary config file
 	configContent := `
 trading:
-  symbol: "BTC"
+  symbols: ["BTC"]
   interval: "5m"
   timeframe: "5m"
   max_position_size: 0.1
@@ -128,3 +132,166 @@ system:
 		t.Errorf("Environment variable expansion failed: got %s, expected test_key_123", cfg.AI.APIKey)
 	}
 }
+
+func TestSymbolMarkets(t *testing.T) {
+	trading := TradingConfig{
+		Symbols:         []string{"ETH", "BTC:perp", "HYPE:spot", "PURR/USDC", "PURR/USDC:spot"},
+		SymbolOverrides: map[string]SymbolOverride{"HYPE:spot": {MaxLeverage: 1}, "BTC": {MaxLeverage: 5}},
+	}
+	if err := trading.normalizeSymbols(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"ETH", "BTC", "HYPE/USDC", "PURR/USDC", "PURR/USDC"}
+	for i, symbol := range want {
+		if trading.Symbols[i] != symbol {
+			t.Errorf("symbol %d: expected %s, got %s", i, symbol, trading.Symbols[i])
+		}
+	}
+	// Overrides apply to the symbols as they are traded
+	if trading.Override("HYPE/USDC").MaxLeverage != 1 || trading.Override("BTC").MaxLeverage != 5 {
+		t.Errorf("overrides should be keyed by traded symbol, got %v", trading.SymbolOverrides)
+	}
+	if trading.Market("BTC") != MarketPerp || trading.Market("HYPE/USDC") != MarketSpot {
+		t.Error("unexpected market types")
+	}
+
+	for _, bad := range []string{"ETH:option", "PURR/USDC:perp"} {
+		trading := TradingConfig{Symbols: []string{bad}}
+		if err := trading.normalizeSymbols(); err == nil {
+			t.Errorf("%s should be rejected", bad)
+		}
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	os.Setenv("TEST_VAULT", "0x00000000000000000000000000000000000000aa")
+	defer os.Unsetenv("TEST_VAULT")
+
+	content := `
+trading:
+  symbols: ["ETH", "BTC"]
+risk:
+  max_drawdown: 0.1
+  daily_loss_limit: 0.05
+  kill_switch:
+    state_file: "logs/trading_state.json"
+    on_drawdown: "FLATTEN"
+  cooldown:
+    state_file: "logs/cooldowns.json"
+hyperliquid:
+  private_key: "0xkey"
+  account_address: "0x0000000000000000000000000000000000000001"
+  accounts:
+    - name: "vault"
+      vault_address: "${TEST_VAULT}"
+      symbols: ["HYPE:spot"]
+      risk:
+        max_drawdown: 0.05
+        kill_switch:
+          on_drawdown: "HALTED"
+    - name: "sub-1"
+      account_address: "0x0000000000000000000000000000000000000002"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	accounts, err := cfg.Accounts()
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d (%v)", len(accounts), err)
+	}
+	vault, sub := accounts[0], accounts[1]
+
+	if vault.Account != "vault" || vault.Hyperliquid.TradingAddress() != "0x00000000000000000000000000000000000000aa" ||
+		vault.Hyperliquid.PrivateKey != "0xkey" || len(vault.Hyperliquid.Accounts) != 0 {
+		t.Errorf("unexpected vault account: %+v", vault.Hyperliquid)
+	}
+	if len(vault.Trading.Symbols) != 1 || vault.Trading.Symbols[0] != "HYPE/USDC" {
+		t.Errorf("vault symbols should be its own, got %v", vault.Trading.Symbols)
+	}
+	if vault.Risk.MaxDrawdown != 0.05 || vault.Risk.DailyLossLimit != 0.05 || vault.Risk.KillSwitch.OnDrawdown != "HALTED" {
+		t.Errorf("vault risk should override only the fields set, got %+v", vault.Risk)
+	}
+	if vault.Risk.KillSwitch.StateFile != "logs/trading_state.vault.json" || vault.Risk.Cooldown.StateFile != "logs/cooldowns.vault.json" {
+		t.Errorf("each account should persist its own trading state, got %s and %s", vault.Risk.KillSwitch.StateFile, vault.Risk.Cooldown.StateFile)
+	}
+
+	if sub.Hyperliquid.TradingAddress() != "0x0000000000000000000000000000000000000002" || len(sub.Trading.Symbols) != 2 {
+		t.Errorf("unexpected sub-account: %+v %v", sub.Hyperliquid, sub.Trading.Symbols)
+	}
+	if cfg.Risk.MaxDrawdown != 0.1 || cfg.Risk.KillSwitch.OnDrawdown != "FLATTEN" || cfg.Trading.Symbols[0] != "ETH" {
+		t.Error("account overrides should not change the global config")
+	}
+
+	for _, bad := range []string{
+		"hyperliquid:\n  accounts:\n    - name: \"\"\n",
+		"hyperliquid:\n  accounts:\n    - name: a\n    - name: a\n",
+		"hyperliquid:\n  accounts:\n    - name: \"../x\"\n",
+		"hyperliquid:\n  accounts:\n    - name: a\n      risk:\n        max_drawdown: high\n",
+	} {
+		if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected an error for:\n%s", bad)
+		}
+	}
+}
+
+func TestBudgetPricing(t *testing.T) {
+	priced := func(models ...string) map[string]ModelPricing {
+		pricing := make(map[string]ModelPricing)
+		for _, model := range models {
+			pricing[model] = ModelPricing{Input: 1, Output: 1}
+		}
+		return pricing
+	}
+	ai := func(budget BudgetConfig) *AIConfig {
+		return &AIConfig{
+			Provider:         "deepseek",
+			Model:            "deepseek-chat",
+			FallbackProvider: "qwen",
+			Qwen:             QwenConfig{Model: "qwen3-max"},
+			Budget:           budget,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		budget BudgetConfig
+		ok     bool
+	}{
+		{"no limit", BudgetConfig{Pricing: priced()}, true},
+		{"all priced", BudgetConfig{DailyLimit: 2, OnExceed: "downgrade", DowngradeModel: "qwen-turbo",
+			Pricing: priced("deepseek-chat", "qwen3-max", "qwen-turbo")}, true},
+		{"fallback unpriced", BudgetConfig{HardLimit: 3, Pricing: priced("deepseek-chat")}, false},
+		{"downgrade unpriced", BudgetConfig{DailyLimit: 2, OnExceed: "downgrade", DowngradeModel: "qwen-turbo",
+			Pricing: priced("deepseek-chat", "qwen3-max")}, false},
+		{"hold needs no downgrade price", BudgetConfig{DailyLimit: 2, OnExceed: "hold", DowngradeModel: "qwen-turbo",
+			Pricing: priced("deepseek-chat", "qwen3-max")}, true},
+		{"unknown on_exceed", BudgetConfig{OnExceed: "pause"}, false},
+	}
+	for _, c := range cases {
+		if err := ai(c.budget).validateBudget(); (err == nil) != c.ok {
+			t.Errorf("%s: unexpected result %v", c.name, err)
+		}
+	}
+}
+
+func TestStrategyMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	for mode, ok := range map[string]bool{"fallback": true, "standalone": true, "": true, "fallbak": false} {
+		if err := os.WriteFile(path, []byte("strategy:\n  mode: \""+mode+"\"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); (err == nil) != ok {
+			t.Errorf("mode %q: unexpected result %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/term"
+)
+
+// redacted replaces secret values in config dumps and logs
+const redacted = "[REDACTED]"
+
+// Secret is a credential in the config: either the value itself or a
+// reference resolved on first use. References take the form "scheme:ref":
+//
+//	env:NAME          the environment variable NAME
+//	file:PATH         the trimmed contents of PATH
+//	keystore:PATH     an encrypted go-ethereum keystore JSON; the password is
+//	                  read from $KEYSTORE_PASSWORD or prompted for
+//
+// Other schemes are served by providers added with RegisterSecretProvider.
+// A Secret prints, marshals and logs as [REDACTED].
+type Secret string
+
+// SecretProvider resolves the reference part of a "scheme:ref" secret, e.g. to
+// read it from a secrets manager
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretProviderFunc adapts a function to SecretProvider
+type SecretProviderFunc func(ref string) (string, error)
+
+// Resolve calls f(ref)
+func (f SecretProviderFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]SecretProvider{
+		"env":      SecretProviderFunc(envSecret),
+		"file":     SecretProviderFunc(fileSecret),
+		"keystore": KeystoreProvider{PasswordEnv: "KEYSTORE_PASSWORD"},
+	}
+
+	// resolved caches secrets by reference so keystores are decrypted and
+	// passwords prompted for once per process
+	resolvedMu    sync.Mutex
+	resolved      = make(map[Secret]string)
+	resolvedCount int // Grows with resolved, so Redactors know to list secrets again
+)
+
+// RegisterSecretProvider makes "scheme:ref" secrets resolve through provider,
+// replacing any provider registered for scheme
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[scheme] = provider
+}
+
+// String redacts the secret
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString redacts the secret in %#v
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+// MarshalYAML redacts the secret in config dumps
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// MarshalJSON redacts the secret in config dumps
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Resolve returns the secret's value, resolving a reference through its
+// provider. Values without a registered scheme are returned as they are.
+func (s Secret) Resolve() (string, error) {
+	scheme, ref, ok := strings.Cut(string(s), ":")
+	if !ok {
+		return string(s), nil
+	}
+	providersMu.RLock()
+	provider, ok := providers[scheme]
+	providersMu.RUnlock()
+	if !ok {
+		return string(s), nil
+	}
+
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	if value, ok := resolved[s]; ok {
+		return value, nil
+	}
+	value, err := provider.Resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret: %w", scheme, err)
+	}
+	resolved[s] = value
+	resolvedCount++
+	return value, nil
+}
+
+// Secrets returns every secret value in the config that has been resolved or
+// is set inline, for redaction from logs. Unresolved references are skipped so
+// listing them never prompts.
+func (c *Config) Secrets() []string {
+	secrets := []Secret{
+		c.AI.APIKey,
+		c.AI.Qwen.APIKey,
+		c.Hyperliquid.PrivateKey,
+		c.Hyperliquid.AgentKey,
+	}
+	for _, account := range c.Hyperliquid.Accounts {
+		secrets = append(secrets, account.PrivateKey, account.AgentKey)
+	}
+
+	seen := make(map[string]bool)
+	var values []string
+	for _, secret := range secrets {
+		value, ok := secret.value()
+		if ok && value != "" && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	// Longest first, so a secret containing another is redacted whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
+
+// value returns the secret's value when it is known without resolving
+func (s Secret) value() (string, bool) {
+	scheme, _, ok := strings.Cut(string(s), ":")
+	if !ok {
+		return string(s), true
+	}
+	providersMu.RLock()
+	_, isRef := providers[scheme]
+	providersMu.RUnlock()
+	if !isRef {
+		return string(s), true
+	}
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	value, ok := resolved[s]
+	return value, ok
+}
+
+// Redactor redacts a config's secrets from log output. The secrets are
+// listed once and again only after another reference has been resolved, not
+// for every string.
+type Redactor struct {
+	config *Config
+
+	mu      sync.Mutex
+	listed  int // resolvedCount when secrets were listed; -1 before
+	secrets []string
+}
+
+// Redactor returns a Redactor for the config's secrets
+func (c *Config) Redactor() *Redactor {
+	return &Redactor{config: c, listed: -1}
+}
+
+// Secrets returns the config's secret values as Config.Secrets does
+func (r *Redactor) Secrets() []string {
+	resolvedMu.Lock()
+	count := resolvedCount
+	resolvedMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listed != count {
+		r.secrets = r.config.Secrets()
+		r.listed = count
+	}
+	return r.secrets
+}
+
+// Redact replaces every occurrence of secrets in s
+func Redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+func envSecret(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func fileSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return value, nil
+}
+
+// KeystoreProvider decrypts go-ethereum keystore files to a hex private key.
+// The password comes from the PasswordEnv environment variable, or is prompted
+// for on the terminal when it is unset.
+type KeystoreProvider struct {
+	PasswordEnv string
+}
+
+// ErrKeystorePassword is returned when a keystore does not decrypt, which
+// almost always means the password is wrong
+var ErrKeystorePassword = keystore.ErrDecrypt
+
+// Resolve decrypts the keystore at path and returns the private key as
+// 0x-prefixed hex
+func (k KeystoreProvider) Resolve(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	password, ok := os.LookupEnv(k.PasswordEnv)
+	if !ok {
+		if password, err = promptPassword(fmt.Sprintf("Password for keystore %s: ", path)); err != nil {
+			return "", fmt.Errorf("no password in $%s and prompt failed: %w", k.PasswordEnv, err)
+		}
+	}
+
+	key, err := keystore.DecryptKey(data, password)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return "0x" + hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)), nil
+}
+
+// promptPassword reads a password from the terminal without echoing it
+var promptPassword = func(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("stdin is not a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+const testSecretKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// writeKeystore encrypts key with go-ethereum's keystore, with cheap scrypt
+// parameters
+func writeKeystore(t *testing.T, key, password string) string {
+	t.Helper()
+	privateKey, err := crypto.HexToECDSA(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := keystore.EncryptKey(&keystore.Key{
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, password, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeystoreSecret(t *testing.T) {
+	path := writeKeystore(t, testSecretKey, "hunter2")
+
+	prompts := 0
+	defer func(prompt func(string) (string, error)) { promptPassword = prompt }(promptPassword)
+	promptPassword = func(string) (string, error) {
+		prompts++
+		return "hunter2", nil
+	}
+
+	// Without $KEYSTORE_PASSWORD the password is prompted for, once
+	os.Unsetenv("KEYSTORE_PASSWORD")
+	secret := Secret("keystore:" + path)
+	for i := 0; i < 2; i++ {
+		key, err := secret.Resolve()
+		if err != nil || key != "0x"+testSecretKey {
+			t.Fatalf("unexpected key %q (%v)", key, err)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("expected one prompt, got %d", prompts)
+	}
+
+	t.Setenv("KEYSTORE_PASSWORD", "wrong")
+	other := writeKeystore(t, testSecretKey, "hunter2")
+	if _, err := Secret("keystore:" + other).Resolve(); !errors.Is(err, ErrKeystorePassword) {
+		t.Errorf("expected ErrKeystorePassword, got %v", err)
+	}
+}
+
+func TestSecretProviders(t *testing.T) {
+	t.Setenv("TEST_SECRET", "from-env")
+	file := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(file, []byte("from-file\n"), 0600)
+	RegisterSecretProvider("vault", SecretProviderFunc(func(ref string) (string, error) {
+		return "from-vault-" + ref, nil
+	}))
+
+	cases := map[Secret]string{
+		"0xabc":                     "0xabc",
+		"env:TEST_SECRET":           "from-env",
+		"file:" + Secret(file):      "from-file",
+		"vault:trading/hl":          "from-vault-trading/hl",
+		"unknown:scheme-is-a-value": "unknown:scheme-is-a-value",
+	}
+	for secret, expected := range cases {
+		if got, err := secret.Resolve(); err != nil || got != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", string(secret), expected, got, err)
+		}
+	}
+
+	if _, err := Secret("env:TEST_SECRET_UNSET").Resolve(); err == nil {
+		t.Error("an unset environment variable should fail")
+	}
+	if _, err := Secret("file:" + file + ".missing").Resolve(); err == nil {
+		t.Error("a missing file should fail")
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	t.Setenv("TEST_AGENT_SECRET", "agent-secret-value")
+	cfg := &Config{}
+	cfg.AI.APIKey = "sk-inline-api-key"
+	cfg.Hyperliquid.PrivateKey = "keystore:/nonexistent/key.json"
+	cfg.Hyperliquid.AgentKey = "env:TEST_AGENT_SECRET"
+
+	yamlDump, _ := yaml.Marshal(cfg)
+	jsonDump, _ := json.Marshal(cfg)
+	for _, dump := range []string{fmt.Sprintf("%v", cfg), fmt.Sprintf("%+v", cfg), fmt.Sprintf("%#v", cfg), string(yamlDump), string(jsonDump)} {
+		for _, secret := range []string{"sk-inline-api-key", "nonexistent", "TEST_AGENT_SECRET"} {
+			if strings.Contains(dump, secret) {
+				t.Errorf("config dump leaks %s:\n%s", secret, dump)
+			}
+		}
+	}
+	if !strings.Contains(string(yamlDump), "private_key: '[REDACTED]'") {
+		t.Errorf("expected a redacted private key, got:\n%s", yamlDump)
+	}
+
+	// Unresolved references are not resolved, and so not prompted for, just
+	// to redact logs
+	redactor := cfg.Redactor()
+	if secrets := redactor.Secrets(); len(secrets) != 1 || secrets[0] != "sk-inline-api-key" {
+		t.Errorf("expected only the inline key, got %v", secrets)
+	}
+	// Resolving a reference makes redactors list the secrets again
+	cfg.Hyperliquid.AgentKey.Resolve()
+	secrets := redactor.Secrets()
+	if len(secrets) != 2 || secrets[0] != "agent-secret-value" {
+		t.Errorf("expected the resolved agent key too, got %v", secrets)
+	}
+	if got := Redact("signing with agent-secret-value", secrets); got != "signing with [REDACTED]" {
+		t.Errorf("unexpected redaction: %s", got)
+	}
+}
@@ -0,0 +1,553 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"aitrading/ai"
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+// Execution algos
+const (
+	AlgoLimit      = "limit"       // One order priced by order_type
+	AlgoMarket     = "market"      // One IOC order max_slippage beyond the touch, whatever order_type
+	AlgoPostOnly   = "post_only"   // Alo orders at the touch, repriced until filled
+	AlgoTWAP       = "twap"        // Equal slices spread over twap_minutes
+	AlgoIceberg    = "iceberg"     // Chunks of iceberg_chunk notional, one at a time
+	AlgoNativeTWAP = "native_twap" // The exchange's TWAP order
+)
+
+const (
+	defaultRepriceInterval = 10 * time.Second
+	defaultMaxReprices     = 5
+	defaultTWAPMinutes     = 10
+	defaultTWAPSlices      = 5
+	defaultIcebergChunk    = 1000.0
+	defaultIcebergWait     = 30 * time.Second
+
+	// pollInterval is how often a resting child order's status is checked
+	pollInterval = 2 * time.Second
+
+	// stopWait bounds how long a close waits for a stopped algo to cancel its
+	// resting child order
+	stopWait = 15 * time.Second
+)
+
+// errOrderRejected marks orders the exchange refused, e.g. an Alo that would cross
+var errOrderRejected = errors.New("order rejected")
+
+// errAlgoStopped is returned by algos stopped before finishing
+var errAlgoStopped = errors.New("execution algo stopped")
+
+// fill accumulates the executions of an algo's child orders
+type fill struct {
+	size     float64
+	notional float64
+	orderID  string // Last child order
+}
+
+func (f *fill) add(size, price float64) {
+	f.size += size
+	f.notional += size * price
+}
+
+func (f *fill) merge(other fill) {
+	f.size += other.size
+	f.notional += other.notional
+	if other.orderID != "" {
+		f.orderID = other.orderID
+	}
+}
+
+func (f *fill) avgPrice() float64 {
+	if f.size <= 0 {
+		return 0
+	}
+	return f.notional / f.size
+}
+
+// knownAlgo reports whether name is an execution algo
+func knownAlgo(name string) bool {
+	switch name {
+	case AlgoLimit, AlgoMarket, AlgoPostOnly, AlgoTWAP, AlgoIceberg, AlgoNativeTWAP:
+		return true
+	}
+	return false
+}
+
+// algoFor returns the algo the decision asks for, else the configured one.
+// An unknown algo on the decision, which the AI may set, is ignored.
+func (e *Executor) algoFor(decision *ai.Decision) string {
+	if knownAlgo(decision.ExecutionAlgo) {
+		return decision.ExecutionAlgo
+	}
+	if decision.ExecutionAlgo != "" {
+		e.logger.WithField("algo", decision.ExecutionAlgo).Warn("Unknown execution algo on decision, using configured algo")
+	}
+	if e.config.Algo != "" {
+		return e.config.Algo
+	}
+	return AlgoLimit
+}
+
+// algoJob is an order worked by a background algo
+type algoJob struct {
+	algo          string
+	symbol        string
+	action        string
+	isBuy         bool
+	size          float64
+	analysisPrice float64
+	reduceOnly    bool
+	stopLoss      float64
+	stop          chan struct{} // Closed to stop the algo before its next child order
+	stopOnce      sync.Once
+	done          chan struct{} // Closed once the worker has settled its last child order
+}
+
+// cancel stops the job before its next child order
+func (j *algoJob) cancel() {
+	j.stopOnce.Do(func() { close(j.stop) })
+}
+
+// workOrder executes size with the decision's algo. Limit orders complete
+// before it returns and record the size and average price traded in result.
+// The slicing algos work in the background, so a slow TWAP does not hold up
+// other symbols or the next cycle; their result is marked Pending with
+// nothing filled yet. A native TWAP is likewise pending on the exchange.
+func (e *Executor) workOrder(symbol string, decision *ai.Decision, isBuy bool, size float64, analysisPrice float64, reduceOnly bool, result *ExecutionResult) (*hyperliquid.OrderResult, error) {
+	algo := e.algoFor(decision)
+
+	switch algo {
+	case AlgoLimit:
+		return e.placeOrder(symbol, isBuy, size, analysisPrice, reduceOnly, e.config.OrderType, result)
+	case AlgoMarket:
+		return e.placeOrder(symbol, isBuy, size, analysisPrice, reduceOnly, OrderTypeIOC, result)
+	case AlgoNativeTWAP:
+		return e.nativeTWAP(symbol, isBuy, size, analysisPrice, reduceOnly, result)
+	case AlgoPostOnly, AlgoTWAP, AlgoIceberg:
+	default:
+		return nil, fmt.Errorf("unknown execution algo: %s", algo)
+	}
+
+	e.startJob(&algoJob{
+		algo:          algo,
+		symbol:        symbol,
+		action:        decision.Action,
+		isBuy:         isBuy,
+		size:          size,
+		analysisPrice: analysisPrice,
+		reduceOnly:    reduceOnly,
+		stopLoss:      decision.StopLoss,
+		stop:          make(chan struct{}),
+	})
+
+	result.Pending = true
+	return &hyperliquid.OrderResult{
+		Success: true,
+		Message: fmt.Sprintf("%s working %.6g in the background", algo, size),
+	}, nil
+}
+
+// startJob runs job in a tracked background worker, replacing any job on the
+// same symbol in the tracking
+func (e *Executor) startJob(job *algoJob) {
+	job.done = make(chan struct{})
+	e.mu.Lock()
+	if e.running == nil {
+		e.running = make(map[string]*algoJob)
+	}
+	e.running[job.symbol] = job
+	e.mu.Unlock()
+
+	e.workers.Add(1)
+	go func() {
+		defer e.workers.Done()
+		defer func() {
+			e.mu.Lock()
+			if e.running[job.symbol] == job {
+				delete(e.running, job.symbol)
+			}
+			e.mu.Unlock()
+			close(job.done)
+		}()
+		e.runJob(job)
+	}()
+}
+
+// stopJob stops job and waits, up to stopWait, for it to cancel its resting
+// child order, so nothing of it fills after it returns. It reports whether the
+// job finished in time.
+func (e *Executor) stopJob(job *algoJob) bool {
+	job.cancel()
+	select {
+	case <-job.done:
+		return true
+	case <-time.After(stopWait):
+		return false
+	}
+}
+
+// runJob works job to completion, logs and returns what filled
+func (e *Executor) runJob(job *algoJob) (fill, error) {
+	var total fill
+	var err error
+	switch job.algo {
+	case AlgoPostOnly:
+		total, err = e.postOnly(job)
+	case AlgoTWAP:
+		total, err = e.twap(job)
+	case AlgoIceberg:
+		total, err = e.iceberg(job)
+	}
+
+	// A remainder below the exchange minimum is dust, not a failure
+	if errors.Is(err, hyperliquid.ErrOrderTooSmall) && total.size > 0 {
+		err = nil
+	}
+
+	fields := logrus.Fields{
+		"symbol":    job.symbol,
+		"algo":      job.algo,
+		"requested": job.size,
+		"filled":    total.size,
+		"avg_price": total.avgPrice(),
+		"order_id":  total.orderID,
+	}
+	if err != nil {
+		e.logger.WithError(err).WithFields(fields).Warn("Execution algo stopped early")
+		return total, err
+	}
+	e.logger.WithFields(fields).Info("Execution algo finished")
+	return total, nil
+}
+
+// runningJob returns the background job working symbol, if any
+func (e *Executor) runningJob(symbol string) *algoJob {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.running[symbol]
+}
+
+// Working reports whether a background algo is still working symbol
+func (e *Executor) Working(symbol string) bool {
+	return e.runningJob(symbol) != nil
+}
+
+// Stop stops every background algo before its next child order, cancelling
+// resting child orders, and waits for them to finish
+func (e *Executor) Stop() {
+	e.mu.Lock()
+	for _, job := range e.running {
+		job.cancel()
+	}
+	e.mu.Unlock()
+	e.Wait()
+}
+
+// Wait blocks until every background algo has finished
+func (e *Executor) Wait() {
+	e.workers.Wait()
+}
+
+// checkSlice is consulted before each child order: the job must not have been
+// stopped, the trading state must still allow its action and an entry must not
+// continue once the price has gone through the decision's stop loss
+func (e *Executor) checkSlice(job *algoJob, book *hyperliquid.OrderBook) error {
+	select {
+	case <-job.stop:
+		return errAlgoStopped
+	default:
+	}
+	if e.killSwitch != nil {
+		if halt := e.killSwitch.State(); !halt.State.Allows(job.action) {
+			return fmt.Errorf("trading state %s does not allow %s: %s", halt.State, job.action, halt.Reason)
+		}
+	}
+	if !job.reduceOnly && job.stopLoss > 0 {
+		mid := book.Mid()
+		if (job.isBuy && mid <= job.stopLoss) || (!job.isBuy && mid >= job.stopLoss) {
+			return fmt.Errorf("%s at %.6g is through the stop loss %.6g", job.symbol, mid, job.stopLoss)
+		}
+	}
+	return nil
+}
+
+// sliceBook re-quotes the job's book and checks the next child order may go
+func (e *Executor) sliceBook(job *algoJob) (*hyperliquid.OrderBook, error) {
+	book, err := e.book(job.symbol, job.analysisPrice, !job.reduceOnly)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkSlice(job, book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// restOrder places one child order and, if it rests, waits up to wait for it
+// to fill, or until stop closes, before cancelling the remainder
+func (e *Executor) restOrder(symbol string, isBuy bool, size, price float64, reduceOnly bool, tif string, wait time.Duration, stop <-chan struct{}) (fill, error) {
+	orderResult, err := e.submit(symbol, isBuy, size, price, reduceOnly, tif)
+	if err != nil {
+		return fill{}, err
+	}
+	if !orderResult.Success {
+		return fill{}, fmt.Errorf("%w: %s", errOrderRejected, orderResult.Message)
+	}
+
+	f := fill{orderID: orderResult.OrderID}
+	if orderResult.FilledSize > 0 || tif == hyperliquid.TifIoc || orderResult.OrderID == "" {
+		f.add(orderResult.FilledSize, orderResult.AvgPrice)
+		return f, nil
+	}
+
+	// Resting: wait until filled, cancelled elsewhere or out of time
+	if status := e.awaitFill(f.orderID, wait, stop); status != nil && status.Status != hyperliquid.OrderStatusOpen {
+		f.add(status.FilledSize(), status.LimitPrice)
+		return f, nil
+	}
+
+	filled, price, err := e.settle(symbol, orderResult.Cloid)
+	f.add(filled, price)
+	return f, err
+}
+
+// awaitFill polls a resting order until it leaves the book, wait passes or
+// stop closes, returning the last status read, or nil if none could be
+func (e *Executor) awaitFill(orderID string, wait time.Duration, stop <-chan struct{}) *hyperliquid.OrderStatus {
+	var last *hyperliquid.OrderStatus
+	deadline := e.now().Add(wait)
+	for {
+		step := deadline.Sub(e.now())
+		if step > pollInterval {
+			step = pollInterval
+		}
+		if step <= 0 || !e.sleep(step, stop) {
+			return last
+		}
+
+		status, err := e.trader.GetOrderStatus(orderID)
+		if err != nil {
+			continue
+		}
+		last = status
+		if status.Status != hyperliquid.OrderStatusOpen {
+			return last
+		}
+	}
+}
+
+// settle cancels a resting order and returns how much of it filled. Resting
+// orders fill at their limit price.
+func (e *Executor) settle(symbol, cloid string) (float64, float64, error) {
+	if err := e.trader.CancelByCloid([]hyperliquid.CloidCancel{{Symbol: symbol, Cloid: cloid}}); err != nil {
+		e.logger.WithError(err).WithField("cloid", cloid).Warn("Failed to cancel resting order")
+	}
+
+	status, err := e.trader.GetOrderStatus(cloid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get status of order %s: %w", cloid, err)
+	}
+	return status.FilledSize(), status.LimitPrice, nil
+}
+
+// postOnly rests an Alo order at the best bid (buys) or ask (sells), moving
+// it to the new touch every reprice_interval until filled or max_reprices is
+// reached. The order is amended in place, so no fill is lost between a cancel
+// and its replacement.
+func (e *Executor) postOnly(job *algoJob) (fill, error) {
+	symbol, isBuy, size, reduceOnly := job.symbol, job.isBuy, job.size, job.reduceOnly
+	interval := time.Duration(e.config.RepriceInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultRepriceInterval
+	}
+	attempts := e.config.MaxReprices
+	if attempts <= 0 {
+		attempts = defaultMaxReprices
+	}
+
+	var total fill
+	var cloid string    // Our order resting on the book, if any
+	var counted float64 // How much of the resting order is already in total
+	for i := 0; i < attempts && total.size < size; i++ {
+		book, err := e.sliceBook(job)
+		if err != nil {
+			if cloid != "" {
+				filled, price, _ := e.settle(symbol, cloid)
+				total.add(filled-counted, price)
+			}
+			return total, err
+		}
+		price := book.BestBid()
+		if !isBuy {
+			price = book.BestAsk()
+		}
+
+		if cloid == "" {
+			orderResult, err := e.submit(symbol, isBuy, size-total.size, price, reduceOnly, hyperliquid.TifAlo)
+			if err != nil {
+				return total, err
+			}
+			if !orderResult.Success {
+				// The book moved through our price; reprice once it settles
+				if !e.sleep(interval, job.stop) {
+					return total, errAlgoStopped
+				}
+				continue
+			}
+			total.orderID = orderResult.OrderID
+			if !orderResult.Resting {
+				total.add(orderResult.FilledSize, orderResult.AvgPrice)
+				continue
+			}
+			cloid = orderResult.Cloid
+			counted = 0
+		} else if err := e.AmendOrder(symbol, cloid, price, size-total.size); err != nil {
+			// e.g. the new price would cross; leave the order where it is
+			e.logger.WithError(err).WithField("cloid", cloid).Debug("Failed to reprice post-only order")
+		} else {
+			// The amended order starts with a fresh filled size
+			counted = 0
+		}
+
+		status := e.awaitFill(cloid, interval, job.stop)
+		if status == nil {
+			continue
+		}
+		total.add(status.FilledSize()-counted, status.LimitPrice)
+		counted = status.FilledSize()
+		if status.Status != hyperliquid.OrderStatusOpen {
+			cloid = ""
+		}
+	}
+
+	if cloid != "" {
+		filled, price, err := e.settle(symbol, cloid)
+		total.add(filled-counted, price)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// twap splits size into twap_slices orders spread evenly over twap_minutes.
+// Unfilled size of a slice carries into the next.
+func (e *Executor) twap(job *algoJob) (fill, error) {
+	symbol, isBuy, size, reduceOnly := job.symbol, job.isBuy, job.size, job.reduceOnly
+	minutes := e.config.TWAPMinutes
+	if minutes <= 0 {
+		minutes = defaultTWAPMinutes
+	}
+	slices := e.config.TWAPSlices
+	if slices <= 0 {
+		slices = defaultTWAPSlices
+	}
+	slices = sliceCount(size, job.analysisPrice, slices)
+	interval := time.Duration(minutes) * time.Minute / time.Duration(slices)
+
+	var total fill
+	start := e.now()
+	for i := 0; i < slices; i++ {
+		if wait := start.Add(time.Duration(i) * interval).Sub(e.now()); wait > 0 && !e.sleep(wait, job.stop) {
+			return total, errAlgoStopped
+		}
+
+		book, err := e.sliceBook(job)
+		if err != nil {
+			return total, err
+		}
+		price, tif := e.limitPrice(book, isBuy, e.config.OrderType)
+
+		// Spread what is left over the remaining slices
+		sliceSize := (size - total.size) / float64(slices-i)
+		f, err := e.restOrder(symbol, isBuy, sliceSize, price, reduceOnly, tif, interval, job.stop)
+		total.merge(f)
+		if err != nil && !errors.Is(err, errOrderRejected) {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// iceberg shows at most iceberg_chunk notional at a time, sending the next
+// chunk once the previous one fills or iceberg_wait passes
+func (e *Executor) iceberg(job *algoJob) (fill, error) {
+	symbol, isBuy, size, reduceOnly := job.symbol, job.isBuy, job.size, job.reduceOnly
+	chunkNotional := e.config.IcebergChunk
+	if chunkNotional <= 0 {
+		chunkNotional = defaultIcebergChunk
+	}
+	wait := time.Duration(e.config.IcebergWait) * time.Second
+	if wait <= 0 {
+		wait = defaultIcebergWait
+	}
+
+	var total fill
+	for total.size < size {
+		book, err := e.sliceBook(job)
+		if err != nil {
+			return total, err
+		}
+		price, tif := e.limitPrice(book, isBuy, e.config.OrderType)
+
+		mid := book.Mid()
+		chunk := math.Min(chunkNotional/mid, size-total.size)
+		// Fold a remainder too small to trade into this chunk
+		if (size-total.size-chunk)*mid < hyperliquid.MinOrderValue {
+			chunk = size - total.size
+		}
+
+		f, err := e.restOrder(symbol, isBuy, chunk, price, reduceOnly, tif, wait, job.stop)
+		total.merge(f)
+		if err != nil {
+			return total, err
+		}
+		if f.size <= 0 {
+			return total, fmt.Errorf("iceberg chunk of %s did not fill", symbol)
+		}
+	}
+	return total, nil
+}
+
+// nativeTWAP hands size to the exchange's TWAP, which keeps running after
+// this call returns. result is marked Pending: nothing has filled yet.
+func (e *Executor) nativeTWAP(symbol string, isBuy bool, size float64, analysisPrice float64, reduceOnly bool, result *ExecutionResult) (*hyperliquid.OrderResult, error) {
+	book, err := e.book(symbol, analysisPrice, !reduceOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := e.config.TWAPMinutes
+	if minutes <= 0 {
+		minutes = defaultTWAPMinutes
+	}
+
+	orderResult, err := e.trader.PlaceTWAP(symbol, isBuy, size, book.Mid(), minutes, e.config.TWAPRandomize, reduceOnly)
+	if err != nil {
+		return nil, err
+	}
+	if orderResult.Success {
+		orderResult.Message = fmt.Sprintf("TWAP %s running over %d minutes", orderResult.OrderID, minutes)
+	}
+
+	result.Pending = orderResult.Success
+	return orderResult, nil
+}
+
+// sliceCount lowers slices so each slice meets the exchange minimum notional
+func sliceCount(size, price float64, slices int) int {
+	if max := int(size * price / hyperliquid.MinOrderValue); max < slices {
+		slices = max
+	}
+	if slices < 1 {
+		return 1
+	}
+	return slices
+}
@@ -0,0 +1,420 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"aitrading/risk"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeExchange serves the info and exchange endpoints the algos use. respond
+// decides each order's status entry; resting orders report restStatus, or
+// statusAt's answer for the nth status poll when set.
+type fakeExchange struct {
+	mu         sync.Mutex
+	orders     []hyperliquid.PlaceOrderRequest
+	actions    []string // Exchange action types in the order they arrived
+	leverage   []hyperliquid.UpdateLeverageAction
+	cancels    int
+	modifies   int
+	polls      int
+	respond    func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{}
+	restStatus string
+	statusAt   func(poll int) string
+}
+
+func (f *fakeExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var req map[string]json.RawMessage
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var reply interface{}
+	if r.URL.Path == "/info" {
+		var kind string
+		json.Unmarshal(req["type"], &kind)
+		switch kind {
+		case "meta":
+			reply = map[string]interface{}{"universe": []interface{}{
+				map[string]interface{}{"name": "ETH", "szDecimals": 4, "maxLeverage": 50},
+			}}
+		case "l2Book":
+			reply = map[string]interface{}{"levels": []interface{}{
+				[]interface{}{map[string]interface{}{"px": "1999", "sz": "10", "n": 1}},
+				[]interface{}{map[string]interface{}{"px": "2001", "sz": "10", "n": 1}},
+			}}
+		case "orderStatus":
+			f.polls++
+			status := f.restStatus
+			if f.statusAt != nil {
+				status = f.statusAt(f.polls)
+			}
+			last := f.orders[len(f.orders)-1]
+			remaining := last.Size
+			if status == hyperliquid.OrderStatusFilled {
+				remaining = "0"
+			}
+			reply = map[string]interface{}{"status": "order", "order": map[string]interface{}{
+				"status": status,
+				"order":  map[string]interface{}{"limitPx": last.Price, "origSz": last.Size, "sz": remaining},
+			}}
+		}
+	} else {
+		var action struct {
+			Type     string                          `json:"type"`
+			Orders   []hyperliquid.PlaceOrderRequest `json:"orders"`
+			Modifies []hyperliquid.ModifyWire        `json:"modifies"`
+		}
+		json.Unmarshal(req["action"], &action)
+		f.actions = append(f.actions, action.Type)
+		switch action.Type {
+		case "order":
+			var statuses []interface{}
+			for _, order := range action.Orders {
+				f.orders = append(f.orders, order)
+				statuses = append(statuses, f.respond(len(f.orders), order))
+			}
+			reply = map[string]interface{}{"status": "ok", "response": map[string]interface{}{
+				"data": map[string]interface{}{"statuses": statuses},
+			}}
+		case "batchModify":
+			f.modifies++
+			f.orders = append(f.orders, action.Modifies[0].Order)
+			reply = map[string]interface{}{"status": "ok", "response": map[string]interface{}{
+				"data": map[string]interface{}{"statuses": []interface{}{map[string]interface{}{"resting": map[string]interface{}{"oid": 8}}}},
+			}}
+		case "updateLeverage":
+			var update hyperliquid.UpdateLeverageAction
+			json.Unmarshal(req["action"], &update)
+			f.leverage = append(f.leverage, update)
+			reply = map[string]interface{}{"status": "ok", "response": map[string]interface{}{"type": "default"}}
+		case "twapOrder":
+			reply = map[string]interface{}{"status": "ok", "response": map[string]interface{}{
+				"type": "twapOrder",
+				"data": map[string]interface{}{"status": map[string]interface{}{"running": map[string]interface{}{"twapId": 3}}},
+			}}
+		case "cancelByCloid":
+			f.cancels++
+			reply = map[string]interface{}{"status": "ok", "response": map[string]interface{}{
+				"data": map[string]interface{}{"statuses": []interface{}{"success"}},
+			}}
+		}
+	}
+	json.NewEncoder(w).Encode(reply)
+}
+
+// filledAt fills an order completely at px
+func filledAt(order hyperliquid.PlaceOrderRequest, px string) map[string]interface{} {
+	return map[string]interface{}{"filled": map[string]interface{}{"totalSz": order.Size, "avgPx": px, "oid": 1}}
+}
+
+func newTestExecutor(t *testing.T, exchange *fakeExchange, cfg config.ExecutionConfig) (*Executor, *[]time.Duration) {
+	server := httptest.NewServer(exchange)
+	t.Cleanup(server.Close)
+
+	// Any key will do; the fake exchange does not check signatures
+	key, err := hyperliquid.NewAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := hyperliquid.NewClient(server.URL)
+	trader, err := hyperliquid.NewTrader(client, key.PrivateKey, "0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	e := NewExecutor(trader, client, "0x0000000000000000000000000000000000000001", logger)
+	e.SetExecutionConfig(cfg)
+
+	// A fake clock so waits take no real time
+	clock := time.Unix(1700000000, 0)
+	var sleeps []time.Duration
+	e.now = func() time.Time { return clock }
+	e.sleep = func(d time.Duration, stop <-chan struct{}) bool {
+		select {
+		case <-stop:
+			return false
+		default:
+		}
+		sleeps = append(sleeps, d)
+		clock = clock.Add(d)
+		return true
+	}
+	return e, &sleeps
+}
+
+// newJob returns a job as workOrder starts it, to run in the foreground
+func newJob(algo string, isBuy bool, size float64, reduceOnly bool) *algoJob {
+	return &algoJob{
+		algo:          algo,
+		symbol:        "ETH",
+		action:        "OPEN_LONG",
+		isBuy:         isBuy,
+		size:          size,
+		analysisPrice: 2000,
+		reduceOnly:    reduceOnly,
+		stop:          make(chan struct{}),
+	}
+}
+
+func TestTWAPSlicesOverTime(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return filledAt(order, "2001")
+	}}
+	e, sleeps := newTestExecutor(t, exchange, config.ExecutionConfig{
+		OrderType: OrderTypeIOC, Algo: AlgoTWAP, TWAPMinutes: 10, TWAPSlices: 5,
+	})
+
+	total, err := e.runJob(newJob(AlgoTWAP, true, 1.0, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exchange.orders) != 5 || exchange.orders[0].Size != "0.2" || exchange.orders[0].OrderType.Limit.Tif != "Ioc" {
+		t.Errorf("expected 5 IOC slices of 0.2, got %+v", exchange.orders)
+	}
+	if len(*sleeps) != 4 || (*sleeps)[0] != 2*time.Minute {
+		t.Errorf("slices should be 2 minutes apart, got %v", *sleeps)
+	}
+	if math.Abs(total.size-1) > 1e-9 || math.Abs(total.avgPrice()-2001) > 1e-9 {
+		t.Errorf("unexpected fill: %+v", total)
+	}
+}
+
+func TestPostOnlyReprices(t *testing.T) {
+	exchange := &fakeExchange{
+		restStatus: hyperliquid.OrderStatusFilled,
+		respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+			if n == 1 {
+				return map[string]interface{}{"error": "Post only order would have immediately matched"}
+			}
+			return map[string]interface{}{"resting": map[string]interface{}{"oid": 7}}
+		},
+	}
+	e, sleeps := newTestExecutor(t, exchange, config.ExecutionConfig{Algo: AlgoPostOnly, RepriceInterval: 4})
+
+	total, err := e.runJob(newJob(AlgoPostOnly, true, 0.5, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exchange.orders) != 2 {
+		t.Fatalf("a crossed Alo should be repriced once, got %d orders", len(exchange.orders))
+	}
+	if len(*sleeps) == 0 || (*sleeps)[0] != 4*time.Second {
+		t.Errorf("a crossed Alo should wait the reprice interval before repricing, got %v", *sleeps)
+	}
+	if order := exchange.orders[1]; order.OrderType.Limit.Tif != "Alo" || order.Price != "1999" {
+		t.Errorf("post-only buys should rest at the best bid, got %+v", order)
+	}
+	if total.size != 0.5 || total.avgPrice() != 1999 {
+		t.Errorf("unexpected fill: %+v", total)
+	}
+}
+
+func TestPostOnlyAmendsInPlace(t *testing.T) {
+	exchange := &fakeExchange{
+		// Open through the first reprice interval, then filled
+		statusAt: func(poll int) string {
+			if poll <= 2 {
+				return hyperliquid.OrderStatusOpen
+			}
+			return hyperliquid.OrderStatusFilled
+		},
+		respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+			return map[string]interface{}{"resting": map[string]interface{}{"oid": 7}}
+		},
+	}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{Algo: AlgoPostOnly, RepriceInterval: 4})
+
+	job := newJob(AlgoPostOnly, false, 0.5, true)
+	job.action = "CLOSE_POSITION"
+	total, err := e.runJob(job)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exchange.modifies != 1 || exchange.cancels != 0 {
+		t.Fatalf("the resting order should be amended, not replaced: %d modifies, %d cancels", exchange.modifies, exchange.cancels)
+	}
+	amended := exchange.orders[1]
+	if amended.IsBuy || !amended.ReduceOnly || amended.OrderType.Limit.Tif != "Alo" || amended.Price != "2001" || amended.Cloid == "" {
+		t.Errorf("the amend should keep side, reduce-only, Alo and cloid at the best ask, got %+v", amended)
+	}
+	if math.Abs(total.size-0.5) > 1e-9 || total.avgPrice() != 2001 {
+		t.Errorf("unexpected fill: %+v", total)
+	}
+}
+
+func TestRestingOrderCancelledByCloid(t *testing.T) {
+	exchange := &fakeExchange{
+		restStatus: hyperliquid.OrderStatusOpen,
+		respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+			return map[string]interface{}{"resting": map[string]interface{}{"oid": 9}}
+		},
+	}
+	e, sleeps := newTestExecutor(t, exchange, config.ExecutionConfig{})
+
+	f, err := e.restOrder("ETH", true, 0.5, 1999, false, hyperliquid.TifGtc, 5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exchange.orders) != 1 || len(exchange.orders[0].Cloid) != 34 {
+		t.Fatalf("orders should carry a client order id, got %+v", exchange.orders)
+	}
+	if exchange.cancels != 1 || len(*sleeps) != 3 {
+		t.Errorf("an unfilled order should be cancelled after its wait, got %d cancels after %v", exchange.cancels, *sleeps)
+	}
+	if f.orderID != "9" {
+		t.Errorf("expected order id 9, got %q", f.orderID)
+	}
+}
+
+func TestIcebergChunks(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return filledAt(order, "1999")
+	}}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{OrderType: OrderTypeIOC, IcebergChunk: 1000})
+
+	total, err := e.runJob(newJob(AlgoIceberg, false, 1.2, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sizes []string
+	for _, order := range exchange.orders {
+		sizes = append(sizes, order.Size)
+	}
+	if fmt.Sprint(sizes) != "[0.5 0.5 0.2]" {
+		t.Errorf("expected $1000 chunks, got %v", sizes)
+	}
+	if math.Abs(total.size-1.2) > 1e-9 {
+		t.Errorf("expected the full size filled, got %v", total.size)
+	}
+}
+
+func TestAlgoWorksInBackground(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return filledAt(order, "2001")
+	}}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{OrderType: OrderTypeIOC, Algo: AlgoTWAP, TWAPSlices: 5})
+
+	// Hold the TWAP before its second slice
+	waiting, release := make(chan struct{}), make(chan struct{})
+	e.sleep = func(d time.Duration, stop <-chan struct{}) bool {
+		close(waiting)
+		<-release
+		select {
+		case <-stop:
+			return false
+		default:
+			return true
+		}
+	}
+
+	result := &ExecutionResult{}
+	orderResult, err := e.workOrder("ETH", &ai.Decision{Action: "OPEN_LONG"}, true, 1.0, 2000, false, result)
+	if err != nil || !orderResult.Success {
+		t.Fatalf("unexpected failure: %+v (%v)", orderResult, err)
+	}
+	if !result.Pending || result.Size != 0 {
+		t.Errorf("the result should be pending with nothing reported filled, got %+v", result)
+	}
+
+	<-waiting
+	if !e.Working("ETH") {
+		t.Fatal("the TWAP should be tracked while it works")
+	}
+	if _, err := e.Execute("ETH", &ai.Decision{Action: "ADD_POSITION"}, 2000, 10000); err == nil {
+		t.Error("a second order on a working symbol should be refused")
+	}
+
+	// Stopping, as a close on the symbol does, waits for the worker to finish
+	stopped := make(chan bool)
+	go func() { stopped <- e.stopJob(e.runningJob("ETH")) }()
+	select {
+	case <-stopped:
+		t.Fatal("stopping should wait for the worker to settle")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if !<-stopped {
+		t.Error("the worker should finish once released")
+	}
+	e.Wait()
+	exchange.mu.Lock()
+	defer exchange.mu.Unlock()
+	if len(exchange.orders) != 1 || e.Working("ETH") {
+		t.Errorf("a stopped TWAP should send no more slices, got %d orders", len(exchange.orders))
+	}
+}
+
+func TestSliceChecksTradingState(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return filledAt(order, "2001")
+	}}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{OrderType: OrderTypeIOC, TWAPSlices: 5})
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	ks := risk.NewKillSwitch(config.KillSwitchConfig{}, logger)
+	e.SetKillSwitch(ks)
+
+	// The kill switch trips during the second wait
+	sleep := e.sleep
+	waits := 0
+	e.sleep = func(d time.Duration, stop <-chan struct{}) bool {
+		if waits++; waits == 2 {
+			ks.Set(risk.StateReduceOnly, "test", "daily loss")
+		}
+		return sleep(d, stop)
+	}
+
+	total, err := e.runJob(newJob(AlgoTWAP, true, 1.0, false))
+	if err == nil || len(exchange.orders) != 2 || math.Abs(total.size-0.4) > 1e-9 {
+		t.Errorf("entry slices should stop once the trading state forbids them, got %d orders, %v filled (%v)", len(exchange.orders), total.size, err)
+	}
+
+	// Stops through the decision's stop loss end an entry too
+	exchange.orders = nil
+	ks.Clear("test")
+	job := newJob(AlgoIceberg, true, 1.0, false)
+	job.stopLoss = 2005
+	if _, err := e.runJob(job); err == nil || len(exchange.orders) != 0 {
+		t.Errorf("an entry below its stop loss should not be sent, got %d orders (%v)", len(exchange.orders), err)
+	}
+}
+
+func TestNativeTWAPPending(t *testing.T) {
+	e, _ := newTestExecutor(t, &fakeExchange{}, config.ExecutionConfig{Algo: AlgoNativeTWAP})
+
+	result := &ExecutionResult{}
+	orderResult, err := e.workOrder("ETH", &ai.Decision{Action: "OPEN_LONG"}, true, 1.0, 2000, false, result)
+	if err != nil || !orderResult.Success || orderResult.OrderID != "3" {
+		t.Fatalf("unexpected result: %+v (%v)", orderResult, err)
+	}
+	if !result.Pending || result.Size != 0 || result.Price != 0 {
+		t.Errorf("a native TWAP has filled nothing yet, got %+v", result)
+	}
+}
+
+func TestSliceCount(t *testing.T) {
+	if got := sliceCount(0.02, 2000, 5); got != 4 {
+		t.Errorf("$40 should allow 4 slices of $10, got %d", got)
+	}
+	if got := sliceCount(0.001, 2000, 5); got != 1 {
+		t.Errorf("tiny orders should use a single slice, got %d", got)
+	}
+	if got := sliceCount(1, 2000, 5); got != 5 {
+		t.Errorf("large orders should keep the configured slices, got %d", got)
+	}
+}
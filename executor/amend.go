@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"fmt"
+
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+// AmendOrder moves a resting entry order to a new price and size in one
+// atomic modify. The order keeps its side and reduce-only flag; a zero size
+// keeps the current size.
+func (e *Executor) AmendOrder(symbol, orderID string, price, size float64) error {
+	if price <= 0 {
+		return fmt.Errorf("invalid price for amending order %s: %v", orderID, price)
+	}
+	if err := e.trader.ModifyOrder(symbol, orderID, price, size); err != nil {
+		return fmt.Errorf("failed to amend order %s: %w", orderID, err)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"symbol":   symbol,
+		"order_id": orderID,
+		"price":    price,
+		"size":     size,
+	}).Info("Order amended")
+	return nil
+}
+
+// AmendProtective moves the trigger price of a resting stop loss or take
+// profit order, e.g. to trail a stop, and resizes it to a position that grew.
+// The limit price moves with the trigger; a zero size keeps the current size.
+func (e *Executor) AmendProtective(symbol, orderID string, triggerPrice, size float64) error {
+	if triggerPrice <= 0 {
+		return fmt.Errorf("invalid trigger price for amending order %s: %v", orderID, triggerPrice)
+	}
+	err := e.trader.ModifyOrders([]hyperliquid.OrderModify{{
+		Symbol:       symbol,
+		OrderID:      orderID,
+		Price:        triggerPrice,
+		Size:         size,
+		TriggerPrice: triggerPrice,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to amend protective order %s: %w", orderID, err)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"symbol":        symbol,
+		"order_id":      orderID,
+		"trigger_price": triggerPrice,
+		"size":          size,
+	}).Info("Protective order amended")
+	return nil
+}
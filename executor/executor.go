@@ -1,11 +1,18 @@
 package executor
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"aitrading/ai"
+	"aitrading/config"
 	"aitrading/hyperliquid"
+	"aitrading/risk"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,8 +22,29 @@ type Executor struct {
 	client         *hyperliquid.Client
 	accountAddress string
 	logger         *logrus.Logger
+	killSwitch     *risk.KillSwitch
+	config         config.ExecutionConfig
+	marginMode     string
+	now            func() time.Time
+	sleep          func(time.Duration, <-chan struct{}) bool // Reports false when stopped first
+
+	mu      sync.Mutex
+	running map[string]*algoJob // Background algos by symbol, guarded by mu
+	workers sync.WaitGroup
 }
 
+// Order types
+const (
+	OrderTypeGTC = "gtc"
+	OrderTypeIOC = "ioc"
+)
+
+const defaultMaxSlippage = 0.005
+
+// ErrPriceMoved is returned when the live price left the price band around
+// the price the decision was analysed at
+var ErrPriceMoved = errors.New("price moved beyond band since analysis")
+
 // NewExecutor creates a new trade executor
 func NewExecutor(trader *hyperliquid.Trader, client *hyperliquid.Client, accountAddress string, logger *logrus.Logger) *Executor {
 	return &Executor{
@@ -24,24 +52,55 @@ func NewExecutor(trader *hyperliquid.Trader, client *hyperliquid.Client, account
 		client:         client,
 		accountAddress: accountAddress,
 		logger:         logger,
+		now:            time.Now,
+		sleep:          sleepUnlessStopped,
+	}
+}
+
+// sleepUnlessStopped sleeps for d, returning false early if stop closes
+func sleepUnlessStopped(d time.Duration, stop <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
 	}
 }
 
+// SetKillSwitch makes Execute refuse actions the trading state does not allow
+func (e *Executor) SetKillSwitch(ks *risk.KillSwitch) {
+	e.killSwitch = ks
+}
+
+// SetExecutionConfig sets how orders are re-quoted and priced
+func (e *Executor) SetExecutionConfig(cfg config.ExecutionConfig) {
+	e.config = cfg
+}
+
+// SetMarginMode sets whether new positions are opened with "cross" (default)
+// or "isolated" margin
+func (e *Executor) SetMarginMode(mode string) {
+	e.marginMode = mode
+}
+
 // ExecutionResult represents the result of trade execution
 type ExecutionResult struct {
-	Success     bool
-	Action      string
-	Symbol      string
-	Side        string
-	Size        float64
-	Price       float64
-	OrderID     string
-	Message     string
-	Timestamp   time.Time
-	Confidence  float64
-	Reason      string
-	StopLoss    float64
-	TakeProfit  float64
+	Success    bool
+	Action     string
+	Symbol     string
+	Side       string
+	Size       float64
+	Price      float64
+	OrderID    string
+	Message    string
+	Timestamp  time.Time
+	Confidence float64
+	Reason     string
+	StopLoss   float64
+	TakeProfit float64
+	Pending    bool // An algo keeps working the order; Size and Price are what filled so far
 }
 
 // Execute executes a trading decision
@@ -63,6 +122,33 @@ func (e *Executor) Execute(symbol string, decision *ai.Decision, currentPrice fl
 		"reason":     decision.Reason,
 	}).Info("Executing trading decision")
 
+	if e.killSwitch != nil {
+		if halt := e.killSwitch.State(); !halt.State.Allows(decision.Action) {
+			result.Success = false
+			result.Message = fmt.Sprintf("Blocked by trading state %s: %s", halt.State, halt.Reason)
+			e.logger.WithField("state", halt.State).Warn("Execution blocked by kill switch")
+			return result, fmt.Errorf("trading state %s does not allow %s", halt.State, decision.Action)
+		}
+	}
+
+	// One algo per symbol: a close stops it, anything else waits for it
+	if job := e.runningJob(symbol); job != nil {
+		switch decision.Action {
+		case "HOLD":
+		case "CLOSE_POSITION":
+			// Wait for its resting child order to be cancelled, or it could
+			// fill after the close reads the position
+			e.logger.WithField("algo", job.algo).Warn("Stopping background algo to close the position")
+			if !e.stopJob(job) {
+				e.logger.WithField("algo", job.algo).Warn("Background algo did not stop in time, closing anyway")
+			}
+		default:
+			result.Success = false
+			result.Message = fmt.Sprintf("%s is still working %s", job.algo, symbol)
+			return result, fmt.Errorf("%s is still working %s", job.algo, symbol)
+		}
+	}
+
 	switch decision.Action {
 	case "OPEN_LONG":
 		return e.executeOpenLong(symbol, decision, currentPrice, accountBalance, result)
@@ -89,21 +175,166 @@ func (e *Executor) Execute(symbol string, decision *ai.Decision, currentPrice fl
 	}
 }
 
+// orderQuantity returns the coin quantity to trade: the risk-sized quantity
+// when set, otherwise the decision's fraction of the account balance
+func orderQuantity(decision *ai.Decision, currentPrice float64, accountBalance float64) float64 {
+	if decision.Quantity > 0 {
+		return decision.Quantity
+	}
+	return accountBalance * decision.Size / currentPrice
+}
+
+// quote re-prices an order against the live book
+func (e *Executor) quote(symbol string, isBuy bool, analysisPrice float64, checkBand bool, orderType string) (float64, string, error) {
+	book, err := e.book(symbol, analysisPrice, checkBand)
+	if err != nil {
+		return 0, "", err
+	}
+	price, tif := e.limitPrice(book, isBuy, orderType)
+	return price, tif, nil
+}
+
+// book fetches the live order book. Entries are rejected when the mid moved
+// more than price_band from the analysis price; closes are never blocked.
+func (e *Executor) book(symbol string, analysisPrice float64, checkBand bool) (*hyperliquid.OrderBook, error) {
+	book, err := e.client.GetL2Book(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-quote %s: %w", symbol, err)
+	}
+	mid := book.Mid()
+	if mid <= 0 {
+		return nil, fmt.Errorf("no two-sided book for %s", symbol)
+	}
+
+	if checkBand && e.config.PriceBand > 0 && analysisPrice > 0 {
+		if move := math.Abs(mid-analysisPrice) / analysisPrice; move > e.config.PriceBand {
+			return nil, fmt.Errorf("%w: %s %.2f -> %.2f (%.2f%% > %.2f%%)",
+				ErrPriceMoved, symbol, analysisPrice, mid, move*100, e.config.PriceBand*100)
+		}
+	}
+	return book, nil
+}
+
+// limitPrice prices an order of orderType from the book: the mid for GTC
+// orders, or for IOC orders max_slippage beyond the best bid or ask
+func (e *Executor) limitPrice(book *hyperliquid.OrderBook, isBuy bool, orderType string) (float64, string) {
+	if orderType != OrderTypeIOC {
+		return book.Mid(), hyperliquid.TifGtc
+	}
+
+	slippage := e.config.MaxSlippage
+	if slippage <= 0 {
+		slippage = defaultMaxSlippage
+	}
+	if isBuy {
+		return book.BestAsk() * (1 + slippage), hyperliquid.TifIoc
+	}
+	return book.BestBid() * (1 - slippage), hyperliquid.TifIoc
+}
+
+// placeOrder re-quotes and sends an order of orderType, recording the size
+// and price actually traded in result
+func (e *Executor) placeOrder(symbol string, isBuy bool, size float64, analysisPrice float64, reduceOnly bool, orderType string, result *ExecutionResult) (*hyperliquid.OrderResult, error) {
+	price, tif, err := e.quote(symbol, isBuy, analysisPrice, !reduceOnly, orderType)
+	if err != nil {
+		return nil, err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"symbol":         symbol,
+		"analysis_price": analysisPrice,
+		"limit_price":    price,
+		"tif":            tif,
+	}).Debug("Order re-quoted")
+
+	orderResult, err := e.submit(symbol, isBuy, size, price, reduceOnly, tif)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Size = size
+	result.Price = price
+	if orderResult.FilledSize > 0 {
+		result.Size = orderResult.FilledSize
+		result.Price = orderResult.AvgPrice
+	}
+	return orderResult, nil
+}
+
+// submit places one order tagged with a fresh client order id, so it can be
+// cancelled without waiting for the exchange order id
+func (e *Executor) submit(symbol string, isBuy bool, size, price float64, reduceOnly bool, tif string) (*hyperliquid.OrderResult, error) {
+	results, err := e.trader.PlaceOrders([]hyperliquid.OrderSpec{{
+		Symbol:     symbol,
+		IsBuy:      isBuy,
+		Size:       size,
+		Price:      price,
+		ReduceOnly: reduceOnly,
+		Tif:        tif,
+		Cloid:      newCloid(),
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// position fetches the account's current position in symbol from a fresh
+// account snapshot
+func (e *Executor) position(symbol string) (*hyperliquid.Position, error) {
+	account, err := e.client.GetAccountState(e.accountAddress)
+	if err != nil {
+		return nil, err
+	}
+	if hyperliquid.IsSpot(symbol) {
+		if err := e.client.AddSpotHoldings(account, e.accountAddress, []string{symbol}); err != nil {
+			return nil, err
+		}
+	}
+	return account.Position(symbol), nil
+}
+
+// newCloid returns a random client order id: 0x followed by 32 hex digits
+func newCloid() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return "0x" + hex.EncodeToString(id[:])
+}
+
+// applyLeverage sets the decision's leverage on the exchange before an order
+// increases exposure, so the position is margined as the risk checks sized it.
+// Existing positions keep their margin mode.
+func (e *Executor) applyLeverage(symbol string, decision *ai.Decision, position *hyperliquid.Position) error {
+	if decision.Leverage <= 0 || hyperliquid.IsSpot(symbol) {
+		return nil
+	}
+	isolated := e.marginMode == "isolated"
+	if position != nil && position.MarginMode != "" {
+		isolated = position.MarginMode == "isolated"
+	}
+	if err := e.trader.UpdateLeverage(symbol, decision.Leverage, isolated); err != nil {
+		return fmt.Errorf("failed to set leverage to %dx: %w", decision.Leverage, err)
+	}
+	return nil
+}
+
 // executeOpenLong opens a long position
 func (e *Executor) executeOpenLong(symbol string, decision *ai.Decision, currentPrice float64, accountBalance float64, result *ExecutionResult) (*ExecutionResult, error) {
-	// Calculate position size based on account balance and decision size
-	positionValue := accountBalance * decision.Size
-	size := positionValue / currentPrice
+	size := orderQuantity(decision, currentPrice, accountBalance)
 
 	e.logger.WithFields(logrus.Fields{
-		"symbol":   symbol,
-		"size":     size,
-		"price":    currentPrice,
-		"stop_loss": decision.StopLoss,
+		"symbol":      symbol,
+		"size":        size,
+		"price":       currentPrice,
+		"stop_loss":   decision.StopLoss,
 		"take_profit": decision.TakeProfit,
 	}).Info("Opening long position")
 
-	orderResult, err := e.trader.OpenLongPosition(symbol, size, currentPrice)
+	err := e.applyLeverage(symbol, decision, nil)
+	var orderResult *hyperliquid.OrderResult
+	if err == nil {
+		orderResult, err = e.workOrder(symbol, decision, true, size, currentPrice, false, result)
+	}
 	if err != nil {
 		result.Success = false
 		result.Message = fmt.Sprintf("Failed to open long position: %v", err)
@@ -113,8 +344,6 @@ func (e *Executor) executeOpenLong(symbol string, decision *ai.Decision, current
 
 	result.Success = orderResult.Success
 	result.Side = "LONG"
-	result.Size = size
-	result.Price = currentPrice
 	result.OrderID = orderResult.OrderID
 	result.Message = orderResult.Message
 
@@ -128,9 +357,7 @@ func (e *Executor) executeOpenLong(symbol string, decision *ai.Decision, current
 
 // executeOpenShort opens a short position
 func (e *Executor) executeOpenShort(symbol string, decision *ai.Decision, currentPrice float64, accountBalance float64, result *ExecutionResult) (*ExecutionResult, error) {
-	// Calculate position size
-	positionValue := accountBalance * decision.Size
-	size := positionValue / currentPrice
+	size := orderQuantity(decision, currentPrice, accountBalance)
 
 	e.logger.WithFields(logrus.Fields{
 		"symbol":      symbol,
@@ -140,7 +367,11 @@ func (e *Executor) executeOpenShort(symbol string, decision *ai.Decision, curren
 		"take_profit": decision.TakeProfit,
 	}).Info("Opening short position")
 
-	orderResult, err := e.trader.OpenShortPosition(symbol, size, currentPrice)
+	err := e.applyLeverage(symbol, decision, nil)
+	var orderResult *hyperliquid.OrderResult
+	if err == nil {
+		orderResult, err = e.workOrder(symbol, decision, false, size, currentPrice, false, result)
+	}
 	if err != nil {
 		result.Success = false
 		result.Message = fmt.Sprintf("Failed to open short position: %v", err)
@@ -150,8 +381,6 @@ func (e *Executor) executeOpenShort(symbol string, decision *ai.Decision, curren
 
 	result.Success = orderResult.Success
 	result.Side = "SHORT"
-	result.Size = size
-	result.Price = currentPrice
 	result.OrderID = orderResult.OrderID
 	result.Message = orderResult.Message
 
@@ -166,7 +395,7 @@ func (e *Executor) executeOpenShort(symbol string, decision *ai.Decision, curren
 // executeAddPosition adds to existing position
 func (e *Executor) executeAddPosition(symbol string, decision *ai.Decision, currentPrice float64, accountBalance float64, result *ExecutionResult) (*ExecutionResult, error) {
 	// Get current position
-	position, err := e.client.GetPosition(symbol, e.accountAddress)
+	position, err := e.position(symbol)
 	if err != nil {
 		result.Success = false
 		result.Message = fmt.Sprintf("Failed to get position: %v", err)
@@ -181,8 +410,7 @@ func (e *Executor) executeAddPosition(symbol string, decision *ai.Decision, curr
 	}
 
 	// Calculate additional size
-	positionValue := accountBalance * decision.Size
-	additionalSize := positionValue / currentPrice
+	additionalSize := orderQuantity(decision, currentPrice, accountBalance)
 
 	e.logger.WithFields(logrus.Fields{
 		"symbol":          symbol,
@@ -192,13 +420,10 @@ func (e *Executor) executeAddPosition(symbol string, decision *ai.Decision, curr
 		"price":           currentPrice,
 	}).Info("Adding to position")
 
+	result.Side = position.Side
 	var orderResult *hyperliquid.OrderResult
-	if position.Side == "LONG" {
-		orderResult, err = e.trader.OpenLongPosition(symbol, additionalSize, currentPrice)
-		result.Side = "LONG"
-	} else {
-		orderResult, err = e.trader.OpenShortPosition(symbol, additionalSize, currentPrice)
-		result.Side = "SHORT"
+	if err = e.applyLeverage(symbol, decision, position); err == nil {
+		orderResult, err = e.workOrder(symbol, decision, position.Side == "LONG", additionalSize, currentPrice, false, result)
 	}
 
 	if err != nil {
@@ -209,8 +434,6 @@ func (e *Executor) executeAddPosition(symbol string, decision *ai.Decision, curr
 	}
 
 	result.Success = orderResult.Success
-	result.Size = additionalSize
-	result.Price = currentPrice
 	result.OrderID = orderResult.OrderID
 	result.Message = orderResult.Message
 
@@ -225,7 +448,7 @@ func (e *Executor) executeAddPosition(symbol string, decision *ai.Decision, curr
 // executeClosePosition closes existing position
 func (e *Executor) executeClosePosition(symbol string, decision *ai.Decision, currentPrice float64, result *ExecutionResult) (*ExecutionResult, error) {
 	// Get current position
-	position, err := e.client.GetPosition(symbol, e.accountAddress)
+	position, err := e.position(symbol)
 	if err != nil {
 		result.Success = false
 		result.Message = fmt.Sprintf("Failed to get position: %v", err)
@@ -247,7 +470,8 @@ func (e *Executor) executeClosePosition(symbol string, decision *ai.Decision, cu
 		"pnl":    position.CurrentPnL,
 	}).Info("Closing position")
 
-	orderResult, err := e.trader.ClosePosition(symbol, position.Side, position.Size, currentPrice)
+	// Buy back a short, sell a long
+	orderResult, err := e.workOrder(symbol, decision, position.Side == "SHORT", position.Size, currentPrice, true, result)
 	if err != nil {
 		result.Success = false
 		result.Message = fmt.Sprintf("Failed to close position: %v", err)
@@ -257,8 +481,6 @@ func (e *Executor) executeClosePosition(symbol string, decision *ai.Decision, cu
 
 	result.Success = orderResult.Success
 	result.Side = position.Side
-	result.Size = position.Size
-	result.Price = currentPrice
 	result.OrderID = orderResult.OrderID
 	result.Message = fmt.Sprintf("Position closed. PnL: %.2f%%", position.PnLPercent)
 
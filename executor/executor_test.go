@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"testing"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+)
+
+func TestOpenSetsLeverageFirst(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return filledAt(order, "2001")
+	}}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{OrderType: OrderTypeIOC})
+	e.SetMarginMode("isolated")
+
+	// Risk lowered the leverage to 3x; the exchange must margin the position so
+	decision := &ai.Decision{Action: "OPEN_LONG", Size: 0.1, Leverage: 3}
+	result, err := e.executeOpenLong("ETH", decision, 2000, 1000, &ExecutionResult{})
+	if err != nil || !result.Success {
+		t.Fatalf("unexpected failure: %+v (%v)", result, err)
+	}
+	if len(exchange.actions) != 2 || exchange.actions[0] != "updateLeverage" || exchange.actions[1] != "order" {
+		t.Fatalf("expected updateLeverage then order, got %v", exchange.actions)
+	}
+	if update := exchange.leverage[0]; update.Asset != 0 || update.Leverage != 3 || update.IsCross {
+		t.Errorf("expected isolated 3x on ETH, got %+v", update)
+	}
+
+	// Without a leverage nothing but the order is sent
+	exchange.actions = nil
+	decision.Leverage = 0
+	if _, err := e.executeOpenShort("ETH", decision, 2000, 1000, &ExecutionResult{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exchange.actions) != 1 || exchange.actions[0] != "order" {
+		t.Errorf("expected only the order, got %v", exchange.actions)
+	}
+}
+
+func TestMarketAlgoCrossesWhateverOrderType(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return filledAt(order, "1999")
+	}}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{OrderType: OrderTypeGTC, MaxSlippage: 0.01})
+
+	// A stop or flatten close must not rest at the mid under order_type gtc
+	decision := &ai.Decision{Action: "CLOSE_POSITION", ExecutionAlgo: AlgoMarket}
+	result := &ExecutionResult{}
+	if _, err := e.workOrder("ETH", decision, false, 0.5, 2000, true, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order := exchange.orders[0]
+	if order.OrderType.Limit == nil || order.OrderType.Limit.Tif != "Ioc" || order.Price != "1979" || !order.ReduceOnly {
+		t.Errorf("expected a reduce-only IOC 1%% below the best bid, got %+v", order)
+	}
+}
@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"fmt"
+
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+// ProtectiveOrders are the client order ids of the reduce-only trigger
+// orders guarding a position. An empty id means that order is not placed.
+type ProtectiveOrders struct {
+	StopLoss   string
+	TakeProfit string
+}
+
+// Protect places a stop loss and a take profit trigger order for a position
+// of size on side, each executing at market once the mark price reaches it.
+// Zero prices are skipped. They are reduce-only, so they can never open a
+// position, and stay on the exchange if the bot stops.
+func (e *Executor) Protect(symbol, side string, size, stopLoss, takeProfit float64) (ProtectiveOrders, error) {
+	var orders ProtectiveOrders
+	if hyperliquid.IsSpot(symbol) {
+		return orders, fmt.Errorf("protective trigger orders are not supported for spot %s", symbol)
+	}
+
+	var specs []hyperliquid.OrderSpec
+	var ids []*string
+	add := func(price float64, kind string, id *string) {
+		if price <= 0 {
+			return
+		}
+		specs = append(specs, hyperliquid.OrderSpec{
+			Symbol:     symbol,
+			IsBuy:      side == "SHORT",
+			Size:       size,
+			Price:      price,
+			ReduceOnly: true,
+			Cloid:      newCloid(),
+			Trigger:    &hyperliquid.Trigger{Price: price, IsMarket: true, Kind: kind},
+		})
+		ids = append(ids, id)
+	}
+	add(stopLoss, "sl", &orders.StopLoss)
+	add(takeProfit, "tp", &orders.TakeProfit)
+	if len(specs) == 0 {
+		return orders, nil
+	}
+
+	results, err := e.trader.PlaceOrders(specs)
+	if err != nil {
+		return orders, fmt.Errorf("failed to place protective orders: %w", err)
+	}
+	for i, result := range results {
+		if !result.Success {
+			err = fmt.Errorf("protective %s order rejected: %s", specs[i].Trigger.Kind, result.Message)
+			continue
+		}
+		*ids[i] = specs[i].Cloid
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"symbol":      symbol,
+		"size":        size,
+		"stop_loss":   stopLoss,
+		"take_profit": takeProfit,
+	}).Info("Protective orders placed")
+	return orders, err
+}
+
+// CancelProtective cancels the protective orders still resting
+func (e *Executor) CancelProtective(symbol string, orders ProtectiveOrders) error {
+	var cancels []hyperliquid.CloidCancel
+	for _, cloid := range []string{orders.StopLoss, orders.TakeProfit} {
+		if cloid != "" {
+			cancels = append(cancels, hyperliquid.CloidCancel{Symbol: symbol, Cloid: cloid})
+		}
+	}
+	if err := e.trader.CancelByCloid(cancels); err != nil {
+		return fmt.Errorf("failed to cancel protective orders: %w", err)
+	}
+	return nil
+}
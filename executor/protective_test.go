@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"testing"
+
+	"aitrading/config"
+	"aitrading/hyperliquid"
+)
+
+func TestProtectiveOrders(t *testing.T) {
+	exchange := &fakeExchange{respond: func(n int, order hyperliquid.PlaceOrderRequest) map[string]interface{} {
+		return map[string]interface{}{"resting": map[string]interface{}{"oid": n}}
+	}}
+	e, _ := newTestExecutor(t, exchange, config.ExecutionConfig{})
+
+	orders, err := e.Protect("ETH", "LONG", 0.5, 1900, 2200)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if orders.StopLoss == "" || orders.TakeProfit == "" {
+		t.Fatalf("expected both orders placed, got %+v", orders)
+	}
+	if len(exchange.actions) != 1 || len(exchange.orders) != 2 {
+		t.Fatalf("expected both triggers in one action, got %v with %d orders", exchange.actions, len(exchange.orders))
+	}
+	stop, target := exchange.orders[0], exchange.orders[1]
+	if stop.IsBuy || !stop.ReduceOnly || stop.Size != "0.5" || stop.Cloid != orders.StopLoss ||
+		stop.OrderType.Trigger == nil || stop.OrderType.Trigger.Tpsl != "sl" || stop.OrderType.Trigger.TriggerPx != "1900" {
+		t.Errorf("expected a reduce-only sell stop at 1900, got %+v", stop)
+	}
+	if target.OrderType.Trigger == nil || target.OrderType.Trigger.Tpsl != "tp" || target.OrderType.Trigger.TriggerPx != "2200" {
+		t.Errorf("expected a take profit at 2200, got %+v", target)
+	}
+
+	// Trailing the stop and growing the position amends the trigger in place
+	if err := e.AmendProtective("ETH", orders.StopLoss, 1950, 0.8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	amended := exchange.orders[2]
+	if exchange.modifies != 1 || amended.OrderType.Trigger == nil || amended.OrderType.Trigger.TriggerPx != "1950" ||
+		amended.Size != "0.8" || amended.IsBuy || !amended.ReduceOnly {
+		t.Errorf("expected the stop moved to 1950 for 0.8, got %+v", amended)
+	}
+	if err := e.AmendProtective("ETH", orders.StopLoss, 0, 0); err == nil {
+		t.Error("a zero trigger price should be refused")
+	}
+
+	if err := e.CancelProtective("ETH", orders); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exchange.cancels != 1 {
+		t.Errorf("expected one cancel action, got %d", exchange.cancels)
+	}
+
+	if _, err := e.Protect("HYPE/USDC", "LONG", 1, 20, 30); err == nil {
+		t.Error("spot positions cannot be protected by trigger orders")
+	}
+}
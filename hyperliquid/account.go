@@ -0,0 +1,151 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AccountState is a snapshot of the account's perp positions and margin from a
+// single clearinghouseState call. AddSpotHoldings adds spot balances.
+type AccountState struct {
+	Positions    map[string]*Position // Open positions by symbol
+	Margin       MarginSummary        // Whole account, cross and isolated
+	CrossMargin  MarginSummary        // Cross margin only
+	Withdrawable float64
+	Time         time.Time
+
+	Spot []SpotBalance // Spot token balances, including USDC
+}
+
+// Balance returns the perp account value. Spot balances are not included.
+func (s *AccountState) Balance() float64 {
+	return s.Margin.AccountValue
+}
+
+// BalanceFor returns the balance an order in symbol is sized from: the
+// available quote token balance for a spot pair, which needs AddSpotHoldings,
+// otherwise the perp account value
+func (s *AccountState) BalanceFor(symbol string) float64 {
+	if !IsSpot(symbol) {
+		return s.Balance()
+	}
+	quote := symbol[strings.Index(symbol, "/")+1:]
+	for _, balance := range s.Spot {
+		if balance.Coin == quote {
+			return balance.Available()
+		}
+	}
+	return 0
+}
+
+// Position returns the open position in symbol, or a flat position with side
+// NONE when there is none
+func (s *AccountState) Position(symbol string) *Position {
+	if position, ok := s.Positions[symbol]; ok {
+		return position
+	}
+	return &Position{Symbol: symbol, Side: "NONE"}
+}
+
+// OpenPositions returns every open position ordered by symbol
+func (s *AccountState) OpenPositions() []*Position {
+	positions := make([]*Position, 0, len(s.Positions))
+	for _, position := range s.Positions {
+		positions = append(positions, position)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions
+}
+
+// Exposure returns the notional of every open position at entry price
+func (s *AccountState) Exposure() float64 {
+	total := 0.0
+	for _, position := range s.Positions {
+		total += position.Size * position.EntryPrice
+	}
+	return total
+}
+
+// GetAccountState fetches every position with its leverage, liquidation price,
+// margin and funding, plus the margin summary and withdrawable balance. Open
+// times come from one additional fills query, made only when a position is
+// open.
+func (c *Client) GetAccountState(accountAddress string) (*AccountState, error) {
+	state, err := c.getClearinghouseState(accountAddress)
+	if err != nil {
+		return nil, err
+	}
+	if state.MarginSummary == nil {
+		return nil, fmt.Errorf("failed to parse account state: no margin summary")
+	}
+
+	account := &AccountState{
+		Positions:    make(map[string]*Position),
+		Margin:       marginSummary(state.MarginSummary),
+		Withdrawable: state.Withdrawable.Float(),
+	}
+	if state.CrossMarginSummary != nil {
+		account.CrossMargin = marginSummary(state.CrossMarginSummary)
+	}
+	if state.Time > 0 {
+		account.Time = time.UnixMilli(state.Time)
+	}
+
+	for _, assetPosition := range state.AssetPositions {
+		position := newPosition(assetPosition.Position)
+		if position.Size > 0 {
+			account.Positions[position.Symbol] = position
+		}
+	}
+
+	// The open time is only known from the fill that opened the position
+	if len(account.Positions) > 0 {
+		if fills, err := c.GetUserFills(accountAddress); err == nil {
+			for symbol, position := range account.Positions {
+				position.OpenTime = PositionOpenTime(fills, symbol)
+				if !position.OpenTime.IsZero() {
+					position.HoldingTime = time.Since(position.OpenTime)
+				}
+			}
+		}
+	}
+
+	return account, nil
+}
+
+// newPosition converts a clearinghouseState position
+func newPosition(wire PerpPosition) *Position {
+	position := &Position{
+		Symbol:           wire.Coin,
+		Size:             wire.Szi.Float(),
+		EntryPrice:       wire.EntryPx.Float(),
+		CurrentPnL:       wire.UnrealizedPnl.Float(),
+		PositionValue:    wire.PositionValue.Float(),
+		Leverage:         wire.Leverage.Value,
+		MarginMode:       wire.Leverage.Type,
+		MarginUsed:       wire.MarginUsed.Float(),
+		LiquidationPrice: wire.LiquidationPx.Float(),
+		ReturnOnEquity:   wire.ReturnOnEquity.Float(),
+		Funding:          wire.CumFunding.SinceOpen.Float(),
+	}
+	if position.Size > 0 {
+		position.Side = "LONG"
+	} else if position.Size < 0 {
+		position.Side = "SHORT"
+		position.Size = -position.Size
+	}
+	if position.EntryPrice > 0 {
+		position.PnLPercent = (position.CurrentPnL / position.EntryPrice) * 100
+	}
+	return position
+}
+
+func marginSummary(wire *AccountMargin) MarginSummary {
+	return MarginSummary{
+		AccountValue:  wire.AccountValue.Float(),
+		TotalNotional: wire.TotalNtlPos.Float(),
+		MarginUsed:    wire.TotalMarginUsed.Float(),
+	}
+}
@@ -0,0 +1,103 @@
+package hyperliquid
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Agent is an API agent wallet: a key that can trade for a master account but
+// cannot withdraw or transfer its funds
+type Agent struct {
+	Address    string
+	PrivateKey string // Hex without 0x prefix
+}
+
+// NewAgent generates a fresh agent key
+func NewAgent() (*Agent, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate agent key: %w", err)
+	}
+	return &Agent{
+		Address:    crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		PrivateKey: fmt.Sprintf("%x", crypto.FromECDSA(key)),
+	}, nil
+}
+
+// KeyAddress returns the address of a hex private key
+func KeyAddress(privateKeyHex string) (string, error) {
+	key, err := parsePrivateKey(privateKeyHex)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
+}
+
+// parsePrivateKey decodes a hex private key with or without 0x prefix
+func parsePrivateKey(privateKeyHex string) (*ecdsa.PrivateKey, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return privateKey, nil
+}
+
+// SignerAddress returns the address of the key the trader signs with: the
+// agent's address when trading through an API agent
+func (t *Trader) SignerAddress() string {
+	return crypto.PubkeyToAddress(t.privateKey.PublicKey).Hex()
+}
+
+// ApproveAgent authorizes agentAddress to trade for this trader's account.
+// The trader must sign with the master key. Approving a new agent under an
+// existing name replaces the old one.
+func (t *Trader) ApproveAgent(agentAddress, name string) error {
+	if !common.IsHexAddress(agentAddress) {
+		return fmt.Errorf("invalid agent address %q", agentAddress)
+	}
+	if !strings.EqualFold(t.SignerAddress(), t.address.Hex()) {
+		return fmt.Errorf("approveAgent must be signed by the master key of %s, not %s", t.address.Hex(), t.SignerAddress())
+	}
+
+	nonce := time.Now().UnixMilli()
+	network := t.client.Network()
+	action := ApproveAgentAction{
+		Type:             "approveAgent",
+		HyperliquidChain: network.Name,
+		SignatureChainID: network.SignatureChainID,
+		AgentAddress:     strings.ToLower(agentAddress),
+		AgentName:        name,
+		Nonce:            nonce,
+	}
+
+	signature, err := t.signUserAction("HyperliquidTransaction:ApproveAgent", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "agentAddress", Type: "address"},
+		{Name: "agentName", Type: "string"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": action.HyperliquidChain,
+		"agentAddress":     action.AgentAddress,
+		"agentName":        action.AgentName,
+		"nonce":            big.NewInt(nonce),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign approveAgent: %w", err)
+	}
+
+	resp, err := t.post(action, nonce, signature, "")
+	if err != nil {
+		return err
+	}
+	if message := resp.Rejected(); message != "" {
+		return fmt.Errorf("approve agent rejected: %s", message)
+	}
+	return nil
+}
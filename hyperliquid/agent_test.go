@@ -0,0 +1,106 @@
+package hyperliquid
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func TestApproveAgent(t *testing.T) {
+	url, requests := captureServer(t, exchangeReply(`{"status":"ok","response":{"type":"default"}}`))
+
+	master, err := KeyAddress(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := NewAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address, err := KeyAddress(agent.PrivateKey); err != nil || address != agent.Address {
+		t.Fatalf("agent key should derive its address, got %s (%v)", address, err)
+	}
+
+	trader, err := NewTrader(NewClient(url), testKey, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// approveAgent is signed for the master account, never for a vault
+	trader.SetVault("0x00000000000000000000000000000000000000aa")
+	if err := trader.ApproveAgent(agent.Address, "bot"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := (*requests)[0]
+	action := req["action"].(map[string]interface{})
+	if action["type"] != "approveAgent" || action["agentName"] != "bot" || action["hyperliquidChain"] != "Mainnet" {
+		t.Errorf("unexpected approveAgent action: %v", action)
+	}
+	if action["nonce"] != req["nonce"] {
+		t.Errorf("the action nonce should match the request nonce: %v / %v", action["nonce"], req["nonce"])
+	}
+	if _, ok := req["vaultAddress"]; ok {
+		t.Error("approveAgent should not carry a vaultAddress")
+	}
+	// Signed by the master key as ApproveAgent typed data on the action's chain
+	signer, err := approveAgentSigner(action, req["signature"].(map[string]interface{}))
+	if err != nil || signer != master {
+		t.Errorf("approveAgent should recover to %s, got %s (%v)", master, signer, err)
+	}
+
+	// An agent cannot approve agents
+	delegated, err := NewTrader(NewClient(url), agent.PrivateKey, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delegated.SignerAddress() != agent.Address {
+		t.Errorf("expected signer %s, got %s", agent.Address, delegated.SignerAddress())
+	}
+	if err := delegated.ApproveAgent(agent.Address, "bot"); err == nil || len(*requests) != 1 {
+		t.Error("approveAgent signed by an agent key should fail before reaching the exchange")
+	}
+}
+
+// approveAgentSigner recovers the address that signed an approveAgent action
+// from the EIP-712 typed data the exchange checks it against
+func approveAgentSigner(action, signature map[string]interface{}) (string, error) {
+	hash, _, err := apitypes.TypedDataAndHash(apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712Domain,
+			"HyperliquidTransaction:ApproveAgent": {
+				{Name: "hyperliquidChain", Type: "string"},
+				{Name: "agentAddress", Type: "address"},
+				{Name: "agentName", Type: "string"},
+				{Name: "nonce", Type: "uint64"},
+			},
+		},
+		PrimaryType: "HyperliquidTransaction:ApproveAgent",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "HyperliquidSignTransaction",
+			Version:           "1",
+			ChainId:           (*math.HexOrDecimal256)(math.MustParseBig256(action["signatureChainId"].(string))),
+			VerifyingContract: "0x0000000000000000000000000000000000000000",
+		},
+		Message: apitypes.TypedDataMessage{
+			"hyperliquidChain": action["hyperliquidChain"],
+			"agentAddress":     action["agentAddress"],
+			"agentName":        action["agentName"],
+			"nonce":            action["nonce"],
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sig := make([]byte, 65)
+	math.MustParseBig256(signature["r"].(string)).FillBytes(sig[:32])
+	math.MustParseBig256(signature["s"].(string)).FillBytes(sig[32:64])
+	sig[64] = byte(signature["v"].(float64)) - 27
+	key, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*key).Hex(), nil
+}
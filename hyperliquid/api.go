@@ -0,0 +1,501 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Wire types for the info and exchange endpoints. Fields the client does not
+// use are left out; decoding ignores them.
+
+// InfoRequest is the body of an info endpoint query. Fields a query type does
+// not take are omitted.
+type InfoRequest struct {
+	Type string         `json:"type"`
+	User string         `json:"user,omitempty"`
+	Coin string         `json:"coin,omitempty"`
+	Oid  interface{}    `json:"oid,omitempty"` // Number for an oid, string for a cloid
+	Req  *CandleRequest `json:"req,omitempty"`
+}
+
+// CandleRequest selects the candles of a candleSnapshot query
+type CandleRequest struct {
+	Coin      string `json:"coin"`
+	Interval  string `json:"interval"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+}
+
+// MetaResponse is the reply to meta: the perpetual universe and margin tables
+type MetaResponse struct {
+	Universe     []UniverseAsset `json:"universe"`
+	MarginTables []MarginTable   `json:"marginTables"`
+}
+
+// UniverseAsset is one perpetual of the universe. Its index in the universe
+// is its asset id.
+type UniverseAsset struct {
+	Name          string `json:"name"`
+	SzDecimals    int    `json:"szDecimals"`
+	MaxLeverage   int    `json:"maxLeverage"`
+	OnlyIsolated  bool   `json:"onlyIsolated"`
+	MarginTableID int    `json:"marginTableId"`
+}
+
+// MarginTable is one [id, {"marginTiers": [...]}] entry of marginTables
+type MarginTable struct {
+	ID    int
+	Tiers []MarginTierWire
+}
+
+// MarginTierWire is one tier of a margin table
+type MarginTierWire struct {
+	LowerBound  Decimal `json:"lowerBound"`
+	MaxLeverage int     `json:"maxLeverage"`
+}
+
+// UnmarshalJSON decodes the [id, table] pair
+func (m *MarginTable) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("margin table: expected [id, table], got %d elements", len(pair))
+	}
+	var table struct {
+		MarginTiers []MarginTierWire `json:"marginTiers"`
+	}
+	if err := json.Unmarshal(pair[0], &m.ID); err != nil {
+		return fmt.Errorf("margin table id: %w", err)
+	}
+	if err := json.Unmarshal(pair[1], &table); err != nil {
+		return fmt.Errorf("margin table %d: %w", m.ID, err)
+	}
+	m.Tiers = table.MarginTiers
+	return nil
+}
+
+// AssetCtx is the live market context of one perpetual
+type AssetCtx struct {
+	DayNtlVlm    Decimal `json:"dayNtlVlm"`
+	PrevDayPx    Decimal `json:"prevDayPx"`
+	MarkPx       Decimal `json:"markPx"`
+	MidPx        Decimal `json:"midPx"` // Zero when the book is empty
+	OraclePx     Decimal `json:"oraclePx"`
+	Funding      Decimal `json:"funding"`
+	OpenInterest Decimal `json:"openInterest"`
+}
+
+// MetaAndAssetCtxs is the reply to metaAndAssetCtxs: [meta, ctxs], where ctxs
+// is in universe order
+type MetaAndAssetCtxs struct {
+	Meta MetaResponse
+	Ctxs []AssetCtx
+}
+
+// UnmarshalJSON decodes the [meta, ctxs] pair
+func (m *MetaAndAssetCtxs) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("metaAndAssetCtxs: expected [meta, ctxs], got %d elements", len(pair))
+	}
+	if err := json.Unmarshal(pair[0], &m.Meta); err != nil {
+		return fmt.Errorf("metaAndAssetCtxs meta: %w", err)
+	}
+	if err := json.Unmarshal(pair[1], &m.Ctxs); err != nil {
+		return fmt.Errorf("metaAndAssetCtxs ctxs: %w", err)
+	}
+	return nil
+}
+
+// SpotMetaResponse is the reply to spotMeta: the spot tokens and the pairs
+// traded between them
+type SpotMetaResponse struct {
+	Tokens   []SpotToken `json:"tokens"`
+	Universe []SpotPair  `json:"universe"`
+}
+
+// SpotToken is one token of the spot universe
+type SpotToken struct {
+	Name        string `json:"name"`
+	SzDecimals  int    `json:"szDecimals"`
+	WeiDecimals int    `json:"weiDecimals"`
+	Index       int    `json:"index"`
+}
+
+// SpotPair is one spot market. Its asset id is 10000 + Index; Name is the
+// coin used by info queries, e.g. "PURR/USDC" or "@107".
+type SpotPair struct {
+	Name        string `json:"name"`
+	Tokens      []int  `json:"tokens"` // [base, quote] token indices
+	Index       int    `json:"index"`
+	IsCanonical bool   `json:"isCanonical"`
+}
+
+// SpotAssetCtx is the live market context of one spot pair
+type SpotAssetCtx struct {
+	Coin      string  `json:"coin"`
+	DayNtlVlm Decimal `json:"dayNtlVlm"`
+	PrevDayPx Decimal `json:"prevDayPx"`
+	MarkPx    Decimal `json:"markPx"`
+	MidPx     Decimal `json:"midPx"` // Zero when the book is empty
+}
+
+// SpotMetaAndAssetCtxs is the reply to spotMetaAndAssetCtxs: [meta, ctxs]
+type SpotMetaAndAssetCtxs struct {
+	Meta SpotMetaResponse
+	Ctxs []SpotAssetCtx
+}
+
+// UnmarshalJSON decodes the [meta, ctxs] pair
+func (m *SpotMetaAndAssetCtxs) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("spotMetaAndAssetCtxs: expected [meta, ctxs], got %d elements", len(pair))
+	}
+	if err := json.Unmarshal(pair[0], &m.Meta); err != nil {
+		return fmt.Errorf("spotMetaAndAssetCtxs meta: %w", err)
+	}
+	if err := json.Unmarshal(pair[1], &m.Ctxs); err != nil {
+		return fmt.Errorf("spotMetaAndAssetCtxs ctxs: %w", err)
+	}
+	return nil
+}
+
+// SpotClearinghouseState is the reply to spotClearinghouseState: the
+// account's token balances
+type SpotClearinghouseState struct {
+	Balances []SpotBalanceWire `json:"balances"`
+}
+
+// SpotBalanceWire is one token balance. Hold is the part reserved by resting
+// orders; EntryNtl is the USDC cost of the balance.
+type SpotBalanceWire struct {
+	Coin     string  `json:"coin"`
+	Token    int     `json:"token"`
+	Hold     Decimal `json:"hold"`
+	Total    Decimal `json:"total"`
+	EntryNtl Decimal `json:"entryNtl"`
+}
+
+// Candle is one entry of a candleSnapshot reply
+type Candle struct {
+	OpenTime  int64   `json:"t"`
+	CloseTime int64   `json:"T"`
+	Symbol    string  `json:"s"`
+	Interval  string  `json:"i"`
+	Open      Decimal `json:"o"`
+	High      Decimal `json:"h"`
+	Low       Decimal `json:"l"`
+	Close     Decimal `json:"c"`
+	Volume    Decimal `json:"v"`
+	Trades    int     `json:"n"`
+}
+
+// ClearinghouseState is the reply to clearinghouseState: the account's perp
+// positions and margin
+type ClearinghouseState struct {
+	AssetPositions     []AssetPosition `json:"assetPositions"`
+	MarginSummary      *AccountMargin  `json:"marginSummary"`
+	CrossMarginSummary *AccountMargin  `json:"crossMarginSummary"`
+	Withdrawable       Decimal         `json:"withdrawable"`
+	Time               int64           `json:"time"`
+}
+
+// AccountMargin is the account-wide margin summary of a clearinghouseState
+type AccountMargin struct {
+	AccountValue    Decimal `json:"accountValue"`
+	TotalNtlPos     Decimal `json:"totalNtlPos"`
+	TotalRawUsd     Decimal `json:"totalRawUsd"`
+	TotalMarginUsed Decimal `json:"totalMarginUsed"`
+}
+
+// AssetPosition wraps one position of a clearinghouseState
+type AssetPosition struct {
+	Type     string       `json:"type"` // "oneWay"
+	Position PerpPosition `json:"position"`
+}
+
+// PerpPosition is a perpetual position. Szi is signed: negative for shorts.
+type PerpPosition struct {
+	Coin           string         `json:"coin"`
+	Szi            Decimal        `json:"szi"`
+	EntryPx        Decimal        `json:"entryPx"`
+	PositionValue  Decimal        `json:"positionValue"`
+	UnrealizedPnl  Decimal        `json:"unrealizedPnl"`
+	ReturnOnEquity Decimal        `json:"returnOnEquity"`
+	LiquidationPx  Decimal        `json:"liquidationPx"` // null when there is none
+	MarginUsed     Decimal        `json:"marginUsed"`
+	MaxLeverage    int            `json:"maxLeverage"`
+	Leverage       PerpLeverage   `json:"leverage"`
+	CumFunding     PerpCumFunding `json:"cumFunding"`
+}
+
+// PerpLeverage is a position's leverage setting
+type PerpLeverage struct {
+	Type   string  `json:"type"` // "cross" or "isolated"
+	Value  int     `json:"value"`
+	RawUsd Decimal `json:"rawUsd"` // Isolated only
+}
+
+// PerpCumFunding is the funding a position has paid; positive means paid
+type PerpCumFunding struct {
+	AllTime     Decimal `json:"allTime"`
+	SinceOpen   Decimal `json:"sinceOpen"`
+	SinceChange Decimal `json:"sinceChange"`
+}
+
+// L2BookResponse is the reply to l2Book: levels is [bids, asks]
+type L2BookResponse struct {
+	Coin   string      `json:"coin"`
+	Time   int64       `json:"time"`
+	Levels [][]L2Level `json:"levels"`
+}
+
+// L2Level is one price level of an l2Book reply
+type L2Level struct {
+	Px Decimal `json:"px"`
+	Sz Decimal `json:"sz"`
+	N  int     `json:"n"`
+}
+
+// UserFill is one entry of a userFills reply
+type UserFill struct {
+	Coin          string  `json:"coin"`
+	Px            Decimal `json:"px"`
+	Sz            Decimal `json:"sz"`
+	Side          string  `json:"side"`
+	Time          int64   `json:"time"`
+	StartPosition Decimal `json:"startPosition"`
+	Dir           string  `json:"dir"`
+	ClosedPnl     Decimal `json:"closedPnl"`
+	Oid           int64   `json:"oid"`
+	Fee           Decimal `json:"fee"`
+}
+
+// OpenOrder is an order as the openOrders and orderStatus queries report it
+type OpenOrder struct {
+	Coin             string  `json:"coin"`
+	Side             string  `json:"side"` // "B" for buys, "A" for sells
+	LimitPx          Decimal `json:"limitPx"`
+	Sz               Decimal `json:"sz"` // Remaining size
+	OrigSz           Decimal `json:"origSz"`
+	Oid              int64   `json:"oid"`
+	Timestamp        int64   `json:"timestamp"`
+	ReduceOnly       bool    `json:"reduceOnly"`
+	Tif              string  `json:"tif"`   // null for triggers
+	Cloid            string  `json:"cloid"` // null when none was sent
+	OrderType        string  `json:"orderType"`
+	IsTrigger        bool    `json:"isTrigger"`
+	TriggerPx        Decimal `json:"triggerPx"`
+	TriggerCondition string  `json:"triggerCondition"`
+}
+
+// OrderStatusResponse is the reply to orderStatus. Status is "order" when the
+// order was found.
+type OrderStatusResponse struct {
+	Status string `json:"status"`
+	Order  *struct {
+		Order           OpenOrder `json:"order"`
+		Status          string    `json:"status"`
+		StatusTimestamp int64     `json:"statusTimestamp"`
+	} `json:"order"`
+}
+
+// ExchangeRequest is the signed body posted to the exchange endpoint
+type ExchangeRequest struct {
+	Action       interface{} `json:"action"`
+	Nonce        int64       `json:"nonce"`
+	Signature    Signature   `json:"signature"`
+	VaultAddress string      `json:"vaultAddress,omitempty"` // Set when trading for a vault or sub-account
+}
+
+// Signature is an ECDSA signature split into r, s and v
+type Signature struct {
+	R string `json:"r"`
+	S string `json:"s"`
+	V int    `json:"v"`
+}
+
+// OrderAction places orders
+type OrderAction struct {
+	Type     string              `json:"type"` // "order"
+	Orders   []PlaceOrderRequest `json:"orders"`
+	Grouping string              `json:"grouping"`
+}
+
+// CancelAction cancels orders by oid
+type CancelAction struct {
+	Type    string       `json:"type"` // "cancel"
+	Cancels []CancelWire `json:"cancels"`
+}
+
+// CancelWire references an order of a CancelAction
+type CancelWire struct {
+	Asset int   `json:"a"`
+	Oid   int64 `json:"o"`
+}
+
+// CancelByCloidAction cancels orders by client order id
+type CancelByCloidAction struct {
+	Type    string            `json:"type"` // "cancelByCloid"
+	Cancels []CloidCancelWire `json:"cancels"`
+}
+
+// CloidCancelWire references an order of a CancelByCloidAction
+type CloidCancelWire struct {
+	Asset int    `json:"asset"`
+	Cloid string `json:"cloid"`
+}
+
+// BatchModifyAction replaces several resting orders
+type BatchModifyAction struct {
+	Type     string       `json:"type"` // "batchModify"
+	Modifies []ModifyWire `json:"modifies"`
+}
+
+// ModifyWire is one modification of a BatchModifyAction
+type ModifyWire struct {
+	Oid   interface{}       `json:"oid"`
+	Order PlaceOrderRequest `json:"order"`
+}
+
+// ApproveAgentAction authorizes an API agent wallet to trade for the signing
+// master account. It is signed by the master key and carries its own nonce.
+type ApproveAgentAction struct {
+	Type             string `json:"type"`             // "approveAgent"
+	HyperliquidChain string `json:"hyperliquidChain"` // "Mainnet" or "Testnet"
+	SignatureChainID string `json:"signatureChainId"` // Hex chain id of the signature
+	AgentAddress     string `json:"agentAddress"`
+	AgentName        string `json:"agentName,omitempty"` // Named agents stay valid until replaced
+	Nonce            int64  `json:"nonce"`
+}
+
+// ScheduleCancelAction arms or, without a time, disarms the dead man's switch
+type ScheduleCancelAction struct {
+	Type string `json:"type"`           // "scheduleCancel"
+	Time *int64 `json:"time,omitempty"` // Unix milliseconds
+}
+
+// UpdateLeverageAction sets an asset's leverage and margin mode
+type UpdateLeverageAction struct {
+	Type     string `json:"type"` // "updateLeverage"
+	Asset    int    `json:"asset"`
+	IsCross  bool   `json:"isCross"`
+	Leverage int    `json:"leverage"`
+}
+
+// TwapOrderAction starts a native TWAP
+type TwapOrderAction struct {
+	Type string   `json:"type"` // "twapOrder"
+	Twap TwapWire `json:"twap"`
+}
+
+// TwapWire is the order of a TwapOrderAction
+type TwapWire struct {
+	Asset      int    `json:"a"`
+	IsBuy      bool   `json:"b"`
+	Size       string `json:"s"`
+	ReduceOnly bool   `json:"r"`
+	Minutes    int    `json:"m"`
+	Randomize  bool   `json:"t"`
+}
+
+// ExchangeResponse is the reply to every exchange action:
+// {"status": "ok", "response": {"type": "order", "data": {...}}} on success or
+// {"status": "err", "response": "message"} when the action is rejected
+type ExchangeResponse struct {
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// ExchangeData is the data of an accepted action. Order, cancel and modify
+// actions report statuses; a TWAP reports status.
+type ExchangeData struct {
+	Statuses []ActionStatus `json:"statuses"`
+	Status   *TwapStatus    `json:"status"`
+}
+
+// ActionStatus is one entry of an action's statuses: an order that rested or
+// filled, an error, or a plain string such as "success" for cancels
+type ActionStatus struct {
+	Resting *RestingStatus `json:"resting"`
+	Filled  *FilledStatus  `json:"filled"`
+	Error   string         `json:"error"`
+	Message string         `json:"-"` // String statuses
+}
+
+// RestingStatus reports an order resting on the book
+type RestingStatus struct {
+	Oid   int64  `json:"oid"`
+	Cloid string `json:"cloid"`
+}
+
+// FilledStatus reports an order that filled immediately
+type FilledStatus struct {
+	TotalSz Decimal `json:"totalSz"`
+	AvgPx   Decimal `json:"avgPx"`
+	Oid     int64   `json:"oid"`
+	Cloid   string  `json:"cloid"`
+}
+
+// TwapStatus reports a TWAP that started or was refused
+type TwapStatus struct {
+	Running *struct {
+		TwapID int64 `json:"twapId"`
+	} `json:"running"`
+	Error string `json:"error"`
+}
+
+// UnmarshalJSON accepts both object and string statuses
+func (s *ActionStatus) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &s.Message)
+	}
+	type plain ActionStatus
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// Rejected returns the message of a rejected action, or "" when it was accepted
+func (r *ExchangeResponse) Rejected() string {
+	if r.Status == "ok" {
+		return ""
+	}
+	var message string
+	if err := json.Unmarshal(r.Response, &message); err != nil || message == "" {
+		message = r.Status
+	}
+	if message == "" {
+		message = "empty response"
+	}
+	return message
+}
+
+// Data decodes the data of an accepted action. Actions without data, such as
+// a single modify, return empty data.
+func (r *ExchangeResponse) Data() (*ExchangeData, error) {
+	if message := r.Rejected(); message != "" {
+		return nil, fmt.Errorf("action rejected: %s", message)
+	}
+
+	var body struct {
+		Type string        `json:"type"`
+		Data *ExchangeData `json:"data"`
+	}
+	if len(r.Response) > 0 {
+		if err := json.Unmarshal(r.Response, &body); err != nil {
+			return nil, fmt.Errorf("failed to parse exchange response: %w", err)
+		}
+	}
+	if body.Data == nil {
+		return &ExchangeData{}, nil
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,281 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// fixtureServer stands in for the API. Info queries are answered with the
+// recorded reply in testdata/<type>.json, exchange actions with
+// testdata/exchange_<exchange>.json. overrides replaces the body of a fixture
+// by name, e.g. to simulate a schema change.
+func fixtureServer(t *testing.T, exchange string, overrides map[string]string) *Trader {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		name := req.Type
+		if r.URL.Path == "/exchange" {
+			name = "exchange_" + exchange
+		}
+		if body, ok := overrides[name]; ok {
+			w.Write([]byte(body))
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	trader, err := NewTrader(NewClient(server.URL), testKey, "0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trader
+}
+
+// captureServer stands in for the API, recording every request body in
+// order. reply returns the body to answer each with, by path ("/info" or
+// "/exchange") and request.
+func captureServer(t *testing.T, reply func(path string, req map[string]interface{}) string) (string, *[]map[string]interface{}) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		w.Write([]byte(reply(r.URL.Path, req)))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
+
+// exchangeReply answers every exchange action with body and info queries
+// with the perp meta
+func exchangeReply(body string) func(string, map[string]interface{}) string {
+	return func(path string, req map[string]interface{}) string {
+		if path == "/info" {
+			return metaReply
+		}
+		return body
+	}
+}
+
+const metaReply = `{"universe":[{"name":"BTC","szDecimals":5},{"name":"ETH","szDecimals":4}]}`
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestDecimal(t *testing.T) {
+	var d Decimal
+	if err := json.Unmarshal([]byte(`"3456.65"`), &d); err != nil || d.Float() != 3456.65 {
+		t.Errorf("expected 3456.65, got %v (%v)", d, err)
+	}
+	if err := json.Unmarshal([]byte(`null`), &d); err != nil || d != 0 {
+		t.Errorf("null should decode as zero, got %v (%v)", d, err)
+	}
+	for _, bad := range []string{`"n/a"`, `""`, `"NaN"`, `1.5`, `{"px":"1"}`} {
+		if err := json.Unmarshal([]byte(bad), &d); err == nil {
+			t.Errorf("%s should fail to decode", bad)
+		}
+	}
+
+	out, err := json.Marshal(Decimal(0.0001))
+	if err != nil || string(out) != `"0.0001"` {
+		t.Errorf("expected \"0.0001\", got %s (%v)", out, err)
+	}
+}
+
+func TestFixtureMarketData(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+
+	market, err := client.GetMarketData("ETH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if market.CurrentPrice != 3456.65 || market.Volume24h != 912376401.93 || !near(market.PriceChange, 2) {
+		t.Errorf("unexpected ETH market data: %+v", market)
+	}
+
+	// A null midPx on the asset context is not an error
+	if _, err := client.GetMarketData("DOGE"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	candles, err := client.GetCandlestickData("ETH", "5m", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candles) != 2 || candles[0].Timestamp != 1700000000000 || candles[1].Close != 1999.7 || candles[1].Volume != 1876.02 {
+		t.Errorf("unexpected candles: %+v", candles)
+	}
+
+	book, err := client.GetL2Book("ETH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if book.BestBid() != 3456.5 || book.BestAsk() != 3456.8 || book.Asks[1].Orders != 17 {
+		t.Errorf("unexpected book: %+v", book)
+	}
+}
+
+func TestFixtureAccount(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+	const user = "0x0000000000000000000000000000000000000001"
+
+	account, err := client.GetAccountState(user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eth := account.Position("ETH")
+	if eth.Side != "SHORT" || eth.Size != 1.5 || eth.EntryPrice != 3500 || eth.Leverage != 10 || eth.MarginMode != "cross" ||
+		eth.LiquidationPrice != 4120.77 || eth.MarginUsed != 518.5 || eth.PositionValue != 5185 {
+		t.Errorf("unexpected ETH position: %+v", eth)
+	}
+	if !eth.OpenTime.Equal(time.UnixMilli(1699990000000)) {
+		t.Errorf("open time should come from the opening fill, got %v", eth.OpenTime)
+	}
+
+	if sol := account.Position("SOL"); sol.Side != "LONG" || sol.MarginMode != "isolated" || sol.LiquidationPrice != 0 {
+		t.Errorf("unexpected SOL position: %+v", sol)
+	}
+
+	if btc := account.Position("BTC"); btc.Side != "NONE" {
+		t.Errorf("expected no BTC position, got %+v", btc)
+	}
+
+	if balance := account.Balance(); balance != 10843.96 {
+		t.Errorf("expected balance 10843.96, got %v", balance)
+	}
+	if summary := account.Margin; summary.TotalNotional != 8232 || summary.MarginUsed != 1127.9 {
+		t.Errorf("unexpected margin summary: %+v", summary)
+	}
+}
+
+func TestFixtureMeta(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+
+	assets, err := client.GetMeta()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doge := assets["DOGE"]; doge.Index != 3 || doge.SzDecimals != 0 || doge.MaxLeverage != 10 {
+		t.Errorf("unexpected DOGE meta: %+v", doge)
+	}
+
+	tiers, err := client.GetMarginTiers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tiers["BTC"]) != 2 || tiers["BTC"][1].LowerBound != 150000000 || tiers["BTC"][1].MaxLeverage != 20 {
+		t.Errorf("unexpected BTC tiers: %+v", tiers["BTC"])
+	}
+	// SOL's margin table is not in the fixture
+	if len(tiers["SOL"]) != 1 || tiers["SOL"][0].MaxLeverage != 20 {
+		t.Errorf("SOL should fall back to its max leverage, got %+v", tiers["SOL"])
+	}
+}
+
+func TestFixtureOrders(t *testing.T) {
+	trader := fixtureServer(t, "order", nil)
+
+	status, err := trader.GetOrderStatus("90560117")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Status != OrderStatusOpen || status.IsBuy || !status.ReduceOnly || status.Tif != TifAlo || !near(status.FilledSize(), 0.6) {
+		t.Errorf("unexpected order status: %+v", status)
+	}
+
+	orders, err := trader.GetOpenOrders("ETH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Oid != 90560117 || orders[0].LimitPx != 3600 {
+		t.Errorf("expected the one ETH order, got %+v", orders)
+	}
+
+	result, err := trader.PlaceOrder("ETH", true, 0.02, 1891.4, false, TifIoc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Success || result.OrderID != "77738308" || result.FilledSize != 0.02 || result.AvgPrice != 1891.4 {
+		t.Errorf("unexpected order result: %+v", result)
+	}
+
+	rejected := fixtureServer(t, "rejected", nil)
+	result, err = rejected.PlaceOrder("ETH", true, 0.02, 1891.4, false, TifIoc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Success || result.Message == "" {
+		t.Errorf("a rejected action should fail the order: %+v", result)
+	}
+	if err := rejected.CancelOrder("ETH", "77738308"); err == nil {
+		t.Error("a rejected cancel should return an error")
+	}
+}
+
+func TestFixtureSchemaChange(t *testing.T) {
+	const user = "0x0000000000000000000000000000000000000001"
+
+	// Sizes sent as numbers instead of decimal strings
+	client := fixtureServer(t, "", map[string]string{
+		"clearinghouseState": `{"assetPositions":[{"type":"oneWay","position":{"coin":"ETH","szi":-1.5}}],
+			"marginSummary":{"accountValue":"100"}}`,
+	}).client
+	if _, err := client.GetAccountState(user); err == nil {
+		t.Error("a numeric szi should fail instead of reading as zero")
+	}
+
+	client = fixtureServer(t, "", map[string]string{"allMids": `{"ETH":"n/a"}`}).client
+	if _, err := client.GetMarketData("ETH"); err == nil {
+		t.Error("an unparsable price should fail instead of reading as zero")
+	}
+
+	client = fixtureServer(t, "", map[string]string{"clearinghouseState": `{"assetPositions":[]}`}).client
+	if _, err := client.GetAccountState(user); err == nil {
+		t.Error("a missing margin summary should fail instead of reading as zero")
+	}
+}
+
+func TestFixtureAccountState(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+
+	account, err := client.GetAccountState("0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.Balance() != 10843.96 || account.Withdrawable != 9125.32 || account.Margin.MarginUsed != 1127.9 || account.CrossMargin.AccountValue != 10234.56 {
+		t.Errorf("unexpected account summary: %+v", account)
+	}
+
+	positions := account.OpenPositions()
+	if len(positions) != 2 || positions[0].Symbol != "ETH" || positions[1].Symbol != "SOL" {
+		t.Fatalf("expected ETH and SOL positions, got %+v", positions)
+	}
+	eth := positions[0]
+	if !near(eth.ReturnOnEquity, 0.1238095) || eth.Funding != -3.2 || eth.OpenTime.IsZero() {
+		t.Errorf("unexpected ETH position: %+v", eth)
+	}
+	if account.Position("BTC").Side != "NONE" {
+		t.Error("an unheld symbol should report a flat position")
+	}
+	if !near(account.Exposure(), 1.5*3500+20*150) {
+		t.Errorf("unexpected exposure: %v", account.Exposure())
+	}
+}
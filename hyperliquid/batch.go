@@ -0,0 +1,222 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// minScheduleCancelDelay is the earliest the exchange accepts a scheduled cancel
+const minScheduleCancelDelay = 5 * time.Second
+
+// OrderSpec describes one limit order of a batch
+type OrderSpec struct {
+	Symbol     string
+	IsBuy      bool
+	Size       float64
+	Price      float64
+	ReduceOnly bool
+	Tif        string   // TifGtc, TifIoc or TifAlo; ignored for triggers
+	Cloid      string   // Optional client order id, 0x followed by 32 hex digits
+	Trigger    *Trigger // Makes the order a stop loss or take profit
+}
+
+// Trigger is the activation condition of a protective order
+type Trigger struct {
+	Price    float64 // Mark price that activates the order
+	IsMarket bool    // Execute as a market order once triggered
+	Kind     string  // "tp" or "sl"
+}
+
+// OrderCancel identifies an order to cancel by its exchange order id
+type OrderCancel struct {
+	Symbol  string
+	OrderID string
+}
+
+// CloidCancel identifies an order to cancel by its client order id
+type CloidCancel struct {
+	Symbol string
+	Cloid  string
+}
+
+// PlaceOrders places orders in a single signed action. Results are in the
+// order of specs. Every order is normalized first; one invalid order fails
+// the batch before anything is sent.
+func (t *Trader) PlaceOrders(specs []OrderSpec) ([]*OrderResult, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	orders := make([]PlaceOrderRequest, 0, len(specs))
+	for _, spec := range specs {
+		order, err := t.wireOrder(spec)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	resp, err := t.sendAction(OrderAction{
+		Type:     "order",
+		Orders:   orders,
+		Grouping: "na",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*OrderResult, len(specs))
+	for i, spec := range specs {
+		results[i] = &OrderResult{Success: true, Cloid: spec.Cloid, Message: resp.Status}
+	}
+
+	if message := resp.Rejected(); message != "" {
+		for _, result := range results {
+			result.Success = false
+			result.Message = message
+		}
+		return results, nil
+	}
+	data, err := resp.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		if i < len(data.Statuses) {
+			parseOrderStatus(data.Statuses[i], result)
+		}
+		if result.Resting {
+			t.track(result.OrderID, specs[i])
+		}
+	}
+
+	return results, nil
+}
+
+// wireOrder converts spec to the exchange's order form, rounding to the
+// asset's tick and lot size and enforcing the minimum notional
+func (t *Trader) wireOrder(spec OrderSpec) (PlaceOrderRequest, error) {
+	meta, err := t.client.AssetMeta(spec.Symbol)
+	if err != nil {
+		return PlaceOrderRequest{}, fmt.Errorf("failed to get asset metadata: %w", err)
+	}
+	// Spot has no positions to reduce; a sell can only spend the balance held
+	reduceOnly := spec.ReduceOnly && !meta.Spot
+	priceStr, sizeStr, err := meta.NormalizeOrder(spec.Price, spec.Size, reduceOnly)
+	if err != nil {
+		return PlaceOrderRequest{}, err
+	}
+
+	order := PlaceOrderRequest{
+		Asset:      meta.Index,
+		IsBuy:      spec.IsBuy,
+		Price:      priceStr,
+		Size:       sizeStr,
+		ReduceOnly: reduceOnly,
+		Cloid:      spec.Cloid,
+	}
+
+	if spec.Trigger != nil {
+		order.OrderType.Trigger = &TriggerOrderType{
+			TriggerPx: formatDecimal(meta.RoundPrice(spec.Trigger.Price), meta.priceDecimals()),
+			IsMarket:  spec.Trigger.IsMarket,
+			Tpsl:      spec.Trigger.Kind,
+		}
+		return order, nil
+	}
+
+	tif := spec.Tif
+	if tif == "" {
+		tif = TifGtc
+	}
+	order.OrderType.Limit = &LimitOrderType{Tif: tif}
+	return order, nil
+}
+
+// CancelOrders cancels orders by exchange order id in a single signed action
+func (t *Trader) CancelOrders(cancels []OrderCancel) error {
+	if len(cancels) == 0 {
+		return nil
+	}
+
+	wire := make([]CancelWire, 0, len(cancels))
+	for _, cancel := range cancels {
+		meta, err := t.client.AssetMeta(cancel.Symbol)
+		if err != nil {
+			return fmt.Errorf("failed to get asset metadata: %w", err)
+		}
+		oid, err := strconv.ParseInt(cancel.OrderID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid order id %q: %w", cancel.OrderID, err)
+		}
+		wire = append(wire, CancelWire{Asset: meta.Index, Oid: oid})
+	}
+
+	return t.sendCancels(CancelAction{Type: "cancel", Cancels: wire})
+}
+
+// CancelByCloid cancels orders by client order id in a single signed action
+func (t *Trader) CancelByCloid(cancels []CloidCancel) error {
+	if len(cancels) == 0 {
+		return nil
+	}
+
+	wire := make([]CloidCancelWire, 0, len(cancels))
+	for _, cancel := range cancels {
+		meta, err := t.client.AssetMeta(cancel.Symbol)
+		if err != nil {
+			return fmt.Errorf("failed to get asset metadata: %w", err)
+		}
+		wire = append(wire, CloidCancelWire{Asset: meta.Index, Cloid: cancel.Cloid})
+	}
+
+	return t.sendCancels(CancelByCloidAction{Type: "cancelByCloid", Cancels: wire})
+}
+
+// sendCancels sends a cancel action and reports the first per-order error
+func (t *Trader) sendCancels(action interface{}) error {
+	resp, err := t.sendAction(action)
+	if err != nil {
+		return err
+	}
+
+	if message := resp.Rejected(); message != "" {
+		return fmt.Errorf("cancel rejected: %s", message)
+	}
+	data, err := resp.Data()
+	if err != nil {
+		return err
+	}
+	// Successful cancels report the string "success"
+	for _, status := range data.Statuses {
+		if status.Error != "" {
+			return fmt.Errorf("cancel failed: %s", status.Error)
+		}
+	}
+	return nil
+}
+
+// ScheduleCancel arms the dead man's switch: every open order is cancelled at
+// the given time unless it is pushed back by another call first. The zero time
+// disarms it.
+func (t *Trader) ScheduleCancel(at time.Time) error {
+	action := ScheduleCancelAction{Type: "scheduleCancel"}
+	if !at.IsZero() {
+		if time.Until(at) < minScheduleCancelDelay {
+			return fmt.Errorf("scheduled cancel must be at least %s ahead", minScheduleCancelDelay)
+		}
+		ms := at.UnixMilli()
+		action.Time = &ms
+	}
+
+	resp, err := t.sendAction(action)
+	if err != nil {
+		return err
+	}
+	if message := resp.Rejected(); message != "" {
+		return fmt.Errorf("schedule cancel rejected: %s", message)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package hyperliquid
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingExchange answers meta and records every exchange action. Actions
+// get replies in turn, the last one repeating.
+func recordingExchange(t *testing.T, replies ...string) (*Trader, *[]map[string]interface{}) {
+	var actions []map[string]interface{}
+	url, _ := captureServer(t, func(path string, req map[string]interface{}) string {
+		if path == "/info" {
+			return metaReply
+		}
+		actions = append(actions, req["action"].(map[string]interface{}))
+		if len(actions) <= len(replies) {
+			return replies[len(actions)-1]
+		}
+		return replies[len(replies)-1]
+	})
+
+	trader, err := NewTrader(NewClient(url), testKey, "0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trader, &actions
+}
+
+func TestPlaceOrdersBatch(t *testing.T) {
+	trader, actions := recordingExchange(t, `{"status":"ok","response":{"type":"order","data":{"statuses":[
+		{"resting":{"oid":11}},
+		{"error":"Insufficient margin to place order."}
+	]}}}`)
+
+	results, err := trader.PlaceOrders([]OrderSpec{
+		{Symbol: "ETH", IsBuy: true, Size: 0.5, Price: 2000, Cloid: "0x0000000000000000000000000000000a"},
+		{Symbol: "BTC", Size: 0.01, Price: 60000, Tif: TifAlo},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*actions) != 1 {
+		t.Fatalf("a batch should be one signed action, got %d", len(*actions))
+	}
+	orders := (*actions)[0]["orders"].([]interface{})
+	first := orders[0].(map[string]interface{})
+	second := orders[1].(map[string]interface{})
+	if len(orders) != 2 || first["a"] != float64(1) || first["c"] != "0x0000000000000000000000000000000a" || second["a"] != float64(0) {
+		t.Errorf("unexpected wire orders: %v", orders)
+	}
+	if _, ok := second["c"]; ok {
+		t.Error("orders without a cloid should omit it")
+	}
+
+	if !results[0].Success || results[0].OrderID != "11" || results[0].Cloid == "" {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if results[1].Success || results[1].Message != "Insufficient margin to place order." {
+		t.Errorf("unexpected second result: %+v", results[1])
+	}
+}
+
+func TestCancelByCloid(t *testing.T) {
+	trader, actions := recordingExchange(t, `{"status":"ok","response":{"type":"cancel","data":{"statuses":["success"]}}}`)
+
+	if err := trader.CancelByCloid([]CloidCancel{{Symbol: "ETH", Cloid: "0x0000000000000000000000000000000a"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	action := (*actions)[0]
+	cancel := action["cancels"].([]interface{})[0].(map[string]interface{})
+	if action["type"] != "cancelByCloid" || cancel["asset"] != float64(1) || cancel["cloid"] != "0x0000000000000000000000000000000a" {
+		t.Errorf("unexpected cancel action: %v", action)
+	}
+}
+
+func TestScheduleCancel(t *testing.T) {
+	trader, actions := recordingExchange(t, `{"status":"ok","response":{"type":"scheduleCancel"}}`)
+
+	if err := trader.ScheduleCancel(time.Now().Add(time.Second)); err == nil {
+		t.Error("a cancel under 5 seconds ahead should be refused")
+	}
+	at := time.Now().Add(time.Minute)
+	if err := trader.ScheduleCancel(at); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := trader.ScheduleCancel(time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*actions) != 2 || (*actions)[0]["time"] != float64(at.UnixMilli()) {
+		t.Errorf("unexpected schedule actions: %v", *actions)
+	}
+	if _, ok := (*actions)[1]["time"]; ok {
+		t.Error("disarming should omit the time")
+	}
+}
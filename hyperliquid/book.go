@@ -0,0 +1,81 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"time"
+)
+
+// BookLevel is one price level of the order book
+type BookLevel struct {
+	Price  float64
+	Size   float64
+	Orders int
+}
+
+// OrderBook is a snapshot of the top of an asset's L2 book
+type OrderBook struct {
+	Symbol string
+	Bids   []BookLevel // Best first
+	Asks   []BookLevel // Best first
+	Time   time.Time
+}
+
+// BestBid returns the highest bid, or zero when there are no bids
+func (b *OrderBook) BestBid() float64 {
+	if len(b.Bids) == 0 {
+		return 0
+	}
+	return b.Bids[0].Price
+}
+
+// BestAsk returns the lowest ask, or zero when there are no asks
+func (b *OrderBook) BestAsk() float64 {
+	if len(b.Asks) == 0 {
+		return 0
+	}
+	return b.Asks[0].Price
+}
+
+// Mid returns the midpoint of the best bid and ask, or zero for a one-sided book
+func (b *OrderBook) Mid() float64 {
+	bid, ask := b.BestBid(), b.BestAsk()
+	if bid <= 0 || ask <= 0 {
+		return 0
+	}
+	return (bid + ask) / 2
+}
+
+// GetL2Book fetches the live order book of symbol
+func (c *Client) GetL2Book(symbol string) (*OrderBook, error) {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var resp L2BookResponse
+	req := InfoRequest{Type: "l2Book", Coin: c.infoCoin(symbol)}
+	if err := c.doRequest("POST", url, req, &resp); err != nil {
+		return nil, err
+	}
+
+	// levels is [bids, asks]
+	if len(resp.Levels) < 2 {
+		return nil, fmt.Errorf("failed to parse order book levels")
+	}
+
+	book := &OrderBook{
+		Symbol: symbol,
+		Bids:   bookLevels(resp.Levels[0]),
+		Asks:   bookLevels(resp.Levels[1]),
+	}
+	if resp.Time > 0 {
+		book.Time = time.UnixMilli(resp.Time)
+	}
+
+	return book, nil
+}
+
+func bookLevels(wire []L2Level) []BookLevel {
+	levels := make([]BookLevel, 0, len(wire))
+	for _, level := range wire {
+		levels = append(levels, BookLevel{Price: level.Px.Float(), Size: level.Sz.Float(), Orders: level.N})
+	}
+	return levels
+}
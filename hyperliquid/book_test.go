@@ -0,0 +1,66 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetL2Book(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"coin":"ETH","time":1700000000000,"levels":[
+			[{"px":"1999.5","sz":"3.2","n":4},{"px":"1999.0","sz":"10","n":7}],
+			[{"px":"2000.5","sz":"1.1","n":2}]
+		]}`))
+	}))
+	defer server.Close()
+
+	book, err := NewClient(server.URL).GetL2Book("ETH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if book.BestBid() != 1999.5 || book.BestAsk() != 2000.5 || book.Mid() != 2000 {
+		t.Errorf("unexpected top of book: bid %v ask %v mid %v", book.BestBid(), book.BestAsk(), book.Mid())
+	}
+	if len(book.Bids) != 2 || book.Bids[1].Orders != 7 {
+		t.Errorf("unexpected bid levels: %+v", book.Bids)
+	}
+
+	empty := &OrderBook{Bids: book.Bids}
+	if empty.Mid() != 0 {
+		t.Error("a one-sided book should have no mid")
+	}
+}
+
+func TestParseOrderStatus(t *testing.T) {
+	parse := func(data string) *OrderResult {
+		var status ActionStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			t.Fatalf("failed to decode %s: %v", data, err)
+		}
+		result := &OrderResult{Success: true}
+		parseOrderStatus(status, result)
+		return result
+	}
+
+	filled := parse(`{"filled":{"totalSz":"0.5","avgPx":"2001.2","oid":77738308}}`)
+	if filled.OrderID != "77738308" || filled.FilledSize != 0.5 || filled.AvgPrice != 2001.2 {
+		t.Errorf("unexpected filled result: %+v", filled)
+	}
+
+	resting := parse(`{"resting":{"oid":42}}`)
+	if resting.OrderID != "42" || resting.FilledSize != 0 || !resting.Resting {
+		t.Errorf("unexpected resting result: %+v", resting)
+	}
+
+	rejected := parse(`{"error":"Order could not immediately match against any resting orders."}`)
+	if rejected.Success || rejected.Message == "" {
+		t.Errorf("an error status should fail the order: %+v", rejected)
+	}
+
+	var status ActionStatus
+	if err := json.Unmarshal([]byte(`{"filled":{"totalSz":"lots","avgPx":"2001.2","oid":1}}`), &status); err == nil {
+		t.Error("a malformed fill size should fail to decode")
+	}
+}
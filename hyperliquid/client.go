@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"aitrading/indicators"
@@ -15,6 +16,15 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	network    Network
+
+	metaMu      sync.Mutex
+	assets      map[string]AssetMeta // Cached by AssetMeta
+	metaFetched time.Time
+
+	spotMu      sync.Mutex
+	spotAssets  map[string]AssetMeta // Cached by spotAssetMeta, keyed by "BASE/QUOTE"
+	spotFetched time.Time
 }
 
 // NewClient creates a new Hyperliquid client
@@ -24,6 +34,7 @@ func NewClient(baseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		network: Mainnet,
 	}
 }
 
@@ -47,89 +58,70 @@ type Position struct {
 	PnLPercent  float64
 	OpenTime    time.Time
 	HoldingTime time.Duration
+
+	PositionValue    float64 // Notional value in USD
+	Leverage         int
+	MarginMode       string // "cross" or "isolated"
+	MarginUsed       float64
+	LiquidationPrice float64 // Zero when the exchange reports none
+	ReturnOnEquity   float64 // Unrealized PnL over the margin posted
+	Funding          float64 // Funding paid since open; negative when received
+}
+
+// MarginSummary contains account-wide margin figures
+type MarginSummary struct {
+	AccountValue  float64
+	TotalNotional float64
+	MarginUsed    float64
 }
 
 // GetMarketData fetches current market information
 func (c *Client) GetMarketData(symbol string) (*MarketInfo, error) {
 	url := fmt.Sprintf("%s/info", c.baseURL)
 
-	// Get current prices using allMids
-	req := map[string]interface{}{
-		"type": "allMids",
-	}
-
-	respData, err := c.doRequest("POST", url, req)
-	if err != nil {
+	// Get current prices using allMids: a map of symbol to price
+	var mids map[string]Decimal
+	if err := c.doRequest("POST", url, InfoRequest{Type: "allMids"}, &mids); err != nil {
 		return nil, err
 	}
 
+	coin := c.infoCoin(symbol)
 	marketInfo := &MarketInfo{
-		Symbol: symbol,
+		Symbol:       symbol,
+		CurrentPrice: mids[coin].Float(),
 	}
 
-	// Parse allMids response - it's a map of symbol to price
-	if priceMap, ok := respData.(map[string]interface{}); ok {
-		if priceStr, ok := priceMap[symbol].(string); ok {
-			fmt.Sscanf(priceStr, "%f", &marketInfo.CurrentPrice)
+	if IsSpot(symbol) {
+		if err := c.spotMarketContext(coin, marketInfo); err != nil {
+			return nil, err
 		}
+		return marketInfo, nil
 	}
 
 	// Get meta and asset contexts for volume data
-	req2 := map[string]interface{}{
-		"type": "metaAndAssetCtxs",
+	var metaAndCtxs MetaAndAssetCtxs
+	if err := c.doRequest("POST", url, InfoRequest{Type: "metaAndAssetCtxs"}, &metaAndCtxs); err != nil {
+		return nil, err
 	}
 
-	respData2, err := c.doRequest("POST", url, req2)
-	if err == nil {
-		// Parse response array: [meta, assetCtxs]
-		if respArray, ok := respData2.([]interface{}); ok && len(respArray) >= 2 {
-			// assetCtxs is the second element
-			if assetCtxs, ok := respArray[1].([]interface{}); ok {
-				// Get metadata to find symbol index
-				var symbolIndex int
-				if meta, ok := respArray[0].(map[string]interface{}); ok {
-					if universe, ok := meta["universe"].([]interface{}); ok {
-						for i, asset := range universe {
-							if assetMap, ok := asset.(map[string]interface{}); ok {
-								if name, ok := assetMap["name"].(string); ok && name == symbol {
-									symbolIndex = i
-									break
-								}
-							}
-						}
-					}
-				}
-
-				// Get data from assetCtxs using the index
-				if symbolIndex < len(assetCtxs) {
-					if ctxMap, ok := assetCtxs[symbolIndex].(map[string]interface{}); ok {
-						// Get 24h volume
-						if dayNtlVlm, ok := ctxMap["dayNtlVlm"].(string); ok {
-							var volume float64
-							if _, err := fmt.Sscanf(dayNtlVlm, "%f", &volume); err == nil {
-								marketInfo.Volume24h = volume
-							}
-						}
-						// Calculate 24h change from prevDayPx and markPx
-						var prevPrice, markPrice float64
-						if prevDayPx, ok := ctxMap["prevDayPx"].(string); ok {
-							fmt.Sscanf(prevDayPx, "%f", &prevPrice)
-						}
-						if markPx, ok := ctxMap["markPx"].(string); ok {
-							fmt.Sscanf(markPx, "%f", &markPrice)
-							// Use mark price if current price is still 0
-							if marketInfo.CurrentPrice == 0 {
-								marketInfo.CurrentPrice = markPrice
-							}
-						}
-						// Calculate percentage change
-						if prevPrice > 0 && markPrice > 0 {
-							marketInfo.PriceChange = ((markPrice - prevPrice) / prevPrice) * 100
-						}
-					}
-				}
-			}
+	// Asset contexts are in universe order
+	for i, asset := range metaAndCtxs.Meta.Universe {
+		if asset.Name != symbol || i >= len(metaAndCtxs.Ctxs) {
+			continue
 		}
+		ctx := metaAndCtxs.Ctxs[i]
+		marketInfo.Volume24h = ctx.DayNtlVlm.Float()
+
+		// Use mark price if current price is still 0
+		markPrice := ctx.MarkPx.Float()
+		if marketInfo.CurrentPrice == 0 {
+			marketInfo.CurrentPrice = markPrice
+		}
+		// Calculate 24h change from prevDayPx and markPx
+		if prevPrice := ctx.PrevDayPx.Float(); prevPrice > 0 && markPrice > 0 {
+			marketInfo.PriceChange = ((markPrice - prevPrice) / prevPrice) * 100
+		}
+		break
 	}
 
 	return marketInfo, nil
@@ -142,189 +134,87 @@ func (c *Client) GetCandlestickData(symbol, interval string, limit int) ([]indic
 	endTime := time.Now().Unix() * 1000
 	startTime := endTime - int64(limit*c.intervalToMilliseconds(interval))
 
-	req := map[string]interface{}{
-		"type": "candleSnapshot",
-		"req": map[string]interface{}{
-			"coin":      symbol,
-			"interval":  interval,
-			"startTime": startTime,
-			"endTime":   endTime,
+	req := InfoRequest{
+		Type: "candleSnapshot",
+		Req: &CandleRequest{
+			Coin:      c.infoCoin(symbol),
+			Interval:  interval,
+			StartTime: startTime,
+			EndTime:   endTime,
 		},
 	}
 
-	respData, err := c.doRequest("POST", url, req)
-	if err != nil {
+	var candleData []Candle
+	if err := c.doRequest("POST", url, req, &candleData); err != nil {
 		return nil, err
 	}
 
-	// Parse candlestick data
-	candles := []indicators.MarketData{}
-
-	if candleData, ok := respData.([]interface{}); ok {
-		for _, candle := range candleData {
-			if c, ok := candle.(map[string]interface{}); ok {
-				md := indicators.MarketData{}
-
-				if t, ok := c["t"].(float64); ok {
-					md.Timestamp = int64(t)
-				}
-				if o, ok := c["o"].(string); ok {
-					fmt.Sscanf(o, "%f", &md.Open)
-				}
-				if h, ok := c["h"].(string); ok {
-					fmt.Sscanf(h, "%f", &md.High)
-				}
-				if l, ok := c["l"].(string); ok {
-					fmt.Sscanf(l, "%f", &md.Low)
-				}
-				if cl, ok := c["c"].(string); ok {
-					fmt.Sscanf(cl, "%f", &md.Close)
-				}
-				if v, ok := c["v"].(string); ok {
-					fmt.Sscanf(v, "%f", &md.Volume)
-				}
-
-				candles = append(candles, md)
-			}
-		}
+	candles := make([]indicators.MarketData, 0, len(candleData))
+	for _, candle := range candleData {
+		candles = append(candles, indicators.MarketData{
+			Timestamp: candle.OpenTime,
+			Open:      candle.Open.Float(),
+			High:      candle.High.Float(),
+			Low:       candle.Low.Float(),
+			Close:     candle.Close.Float(),
+			Volume:    candle.Volume.Float(),
+		})
 	}
 
 	return candles, nil
 }
 
-// GetPosition fetches current position for a symbol
-func (c *Client) GetPosition(symbol, accountAddress string) (*Position, error) {
+// getClearinghouseState fetches the account's perp positions and margin
+func (c *Client) getClearinghouseState(accountAddress string) (*ClearinghouseState, error) {
 	url := fmt.Sprintf("%s/info", c.baseURL)
 
-	req := map[string]interface{}{
-		"type": "clearinghouseState",
-		"user": accountAddress,
-	}
-
-	respData, err := c.doRequest("POST", url, req)
-	if err != nil {
+	var state ClearinghouseState
+	req := InfoRequest{Type: "clearinghouseState", User: accountAddress}
+	if err := c.doRequest("POST", url, req, &state); err != nil {
 		return nil, err
 	}
-
-	// Parse position data
-	if stateMap, ok := respData.(map[string]interface{}); ok {
-		if assetPositions, ok := stateMap["assetPositions"].([]interface{}); ok {
-			for _, pos := range assetPositions {
-				if posMap, ok := pos.(map[string]interface{}); ok {
-					if positionMap, ok := posMap["position"].(map[string]interface{}); ok {
-						if coin, ok := positionMap["coin"].(string); ok && coin == symbol {
-							position := &Position{
-								Symbol:   symbol,
-								OpenTime: time.Now(), // Would need to track this separately
-							}
-
-							if szi, ok := positionMap["szi"].(string); ok {
-								var size float64
-								fmt.Sscanf(szi, "%f", &size)
-								position.Size = size
-								if size > 0 {
-									position.Side = "LONG"
-								} else if size < 0 {
-									position.Side = "SHORT"
-									position.Size = -size
-								}
-							}
-
-							if entryPx, ok := positionMap["entryPx"].(string); ok {
-								fmt.Sscanf(entryPx, "%f", &position.EntryPrice)
-							}
-
-							if unrealizedPnl, ok := positionMap["unrealizedPnl"].(string); ok {
-								fmt.Sscanf(unrealizedPnl, "%f", &position.CurrentPnL)
-							}
-
-							if position.EntryPrice > 0 {
-								position.PnLPercent = (position.CurrentPnL / position.EntryPrice) * 100
-							}
-
-							return position, nil
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// No position found
-	return &Position{
-		Symbol: symbol,
-		Side:   "NONE",
-		Size:   0,
-	}, nil
-}
-
-// GetAccountBalance fetches account balance
-func (c *Client) GetAccountBalance(accountAddress string) (float64, error) {
-	url := fmt.Sprintf("%s/info", c.baseURL)
-
-	req := map[string]interface{}{
-		"type": "clearinghouseState",
-		"user": accountAddress,
-	}
-
-	respData, err := c.doRequest("POST", url, req)
-	if err != nil {
-		return 0, err
-	}
-
-	// Parse balance
-	if stateMap, ok := respData.(map[string]interface{}); ok {
-		if marginSummary, ok := stateMap["marginSummary"].(map[string]interface{}); ok {
-			if accountValue, ok := marginSummary["accountValue"].(string); ok {
-				var balance float64
-				fmt.Sscanf(accountValue, "%f", &balance)
-				return balance, nil
-			}
-		}
-	}
-
-	return 0, fmt.Errorf("failed to parse account balance")
+	return &state, nil
 }
 
-// doRequest performs HTTP request to Hyperliquid API
-func (c *Client) doRequest(method, url string, payload interface{}) (interface{}, error) {
+// doRequest performs HTTP request to Hyperliquid API and decodes the JSON
+// response into out
+func (c *Client) doRequest(method, url string, payload, out interface{}) error {
 	var body io.Reader
 	if payload != nil {
 		jsonData, err := json.Marshal(payload)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request: %w", err)
+			return fmt.Errorf("failed to marshal request: %w", err)
 		}
 		body = bytes.NewBuffer(jsonData)
 	}
 
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
-	var result interface{}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return result, nil
+	return nil
 }
 
 // intervalToMilliseconds converts interval string to milliseconds
@@ -0,0 +1,44 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Decimal is a number the API sends as a decimal string, e.g. "2001.5".
+// Decoding fails on anything that is not a finite number, so a schema change
+// surfaces as an error instead of a silent zero. JSON null decodes as zero.
+type Decimal float64
+
+// UnmarshalJSON parses a quoted decimal string
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = 0
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("invalid decimal %s: expected a string", data)
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("invalid decimal %q", str)
+	}
+
+	*d = Decimal(value)
+	return nil
+}
+
+// MarshalJSON formats the decimal as the API expects: a string without
+// exponent or trailing zeros
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(float64(d), 'f', -1, 64))
+}
+
+// Float returns the decimal as a float64
+func (d Decimal) Float() float64 {
+	return float64(d)
+}
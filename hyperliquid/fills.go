@@ -0,0 +1,83 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// flatSize is the position size treated as flat, absorbing float rounding
+const flatSize = 1e-9
+
+// Fill is one execution of the account's orders
+type Fill struct {
+	Coin          string
+	Price         float64
+	Size          float64
+	Side          string // "B" for buys, "A" for sells
+	Time          time.Time
+	StartPosition float64 // Signed position size before the fill
+	Dir           string  // e.g. "Open Long", "Close Short"
+	ClosedPnL     float64
+}
+
+// EndPosition returns the signed position size after the fill
+func (f Fill) EndPosition() float64 {
+	if f.Side == "B" {
+		return f.StartPosition + f.Size
+	}
+	return f.StartPosition - f.Size
+}
+
+// GetUserFills fetches the account's most recent fills
+func (c *Client) GetUserFills(accountAddress string) ([]Fill, error) {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var wire []UserFill
+	req := InfoRequest{Type: "userFills", User: accountAddress}
+	if err := c.doRequest("POST", url, req, &wire); err != nil {
+		return nil, err
+	}
+
+	fills := make([]Fill, 0, len(wire))
+	for _, fill := range wire {
+		fills = append(fills, Fill{
+			Coin:          fill.Coin,
+			Price:         fill.Px.Float(),
+			Size:          fill.Sz.Float(),
+			Side:          fill.Side,
+			Time:          time.UnixMilli(fill.Time),
+			StartPosition: fill.StartPosition.Float(),
+			Dir:           fill.Dir,
+			ClosedPnL:     fill.ClosedPnl.Float(),
+		})
+	}
+
+	return fills, nil
+}
+
+// PositionOpenTime returns when the current position in coin was opened: the
+// last fill that started it from flat or flipped its direction. It returns the
+// zero time when the fills do not reach back to the opening fill.
+func PositionOpenTime(fills []Fill, coin string) time.Time {
+	var coinFills []Fill
+	for _, fill := range fills {
+		if fill.Coin == coin {
+			coinFills = append(coinFills, fill)
+		}
+	}
+	sort.SliceStable(coinFills, func(i, j int) bool { return coinFills[i].Time.Before(coinFills[j].Time) })
+
+	var openTime time.Time
+	for _, fill := range coinFills {
+		start, end := fill.StartPosition, fill.EndPosition()
+		switch {
+		case math.Abs(end) < flatSize:
+			openTime = time.Time{}
+		case math.Abs(start) < flatSize || (start > 0) != (end > 0):
+			openTime = fill.Time
+		}
+	}
+	return openTime
+}
@@ -0,0 +1,39 @@
+package hyperliquid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPositionOpenTime(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+
+	fills := []Fill{
+		{Coin: "ETH", Side: "B", Size: 1, StartPosition: 0, Time: at(0)},    // open long
+		{Coin: "ETH", Side: "B", Size: 1, StartPosition: 1, Time: at(1)},    // add
+		{Coin: "ETH", Side: "A", Size: 2, StartPosition: 2, Time: at(2)},    // close
+		{Coin: "ETH", Side: "A", Size: 1, StartPosition: 0, Time: at(3)},    // open short
+		{Coin: "ETH", Side: "B", Size: 0.5, StartPosition: -1, Time: at(4)}, // partial close
+		{Coin: "BTC", Side: "B", Size: 1, StartPosition: 0, Time: at(5)},
+	}
+	if got := PositionOpenTime(fills, "ETH"); !got.Equal(at(3)) {
+		t.Errorf("expected reopen time %v, got %v", at(3), got)
+	}
+
+	flip := append(fills, Fill{Coin: "ETH", Side: "B", Size: 1.5, StartPosition: -0.5, Time: at(6)})
+	if got := PositionOpenTime(flip, "ETH"); !got.Equal(at(6)) {
+		t.Errorf("a flip should restart the open time, got %v", got)
+	}
+
+	closed := append(fills, Fill{Coin: "ETH", Side: "B", Size: 0.5, StartPosition: -0.5, Time: at(6)})
+	if got := PositionOpenTime(closed, "ETH"); !got.IsZero() {
+		t.Errorf("a flat position should have no open time, got %v", got)
+	}
+
+	// Fills that start mid-position never reach the opening fill
+	partial := []Fill{{Coin: "ETH", Side: "B", Size: 1, StartPosition: 1, Time: at(0)}}
+	if got := PositionOpenTime(partial, "ETH"); !got.IsZero() {
+		t.Errorf("expected unknown open time, got %v", got)
+	}
+}
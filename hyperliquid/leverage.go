@@ -0,0 +1,33 @@
+package hyperliquid
+
+import "fmt"
+
+// UpdateLeverage sets the leverage, and cross or isolated margin, that the
+// symbol's position is opened or increased at. The exchange applies it to the
+// existing position too, so it must not exceed what that position can carry.
+func (t *Trader) UpdateLeverage(symbol string, leverage int, isolated bool) error {
+	meta, err := t.client.AssetMeta(symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get asset metadata: %w", err)
+	}
+	if meta.Spot {
+		return fmt.Errorf("%s is a spot market and has no leverage", symbol)
+	}
+	if leverage < 1 {
+		return fmt.Errorf("invalid leverage %d", leverage)
+	}
+
+	resp, err := t.sendAction(UpdateLeverageAction{
+		Type:     "updateLeverage",
+		Asset:    meta.Index,
+		IsCross:  !isolated,
+		Leverage: leverage,
+	})
+	if err != nil {
+		return err
+	}
+	if message := resp.Rejected(); message != "" {
+		return fmt.Errorf("leverage update rejected: %s", message)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MarginTier is one step of an asset's margin table. Notional above
+// LowerBound is limited to MaxLeverage.
+type MarginTier struct {
+	LowerBound  float64
+	MaxLeverage int
+}
+
+// GetMarginTiers fetches the margin tiers of every perpetual asset from meta.
+// Assets without a margin table get a single tier at their max leverage.
+func (c *Client) GetMarginTiers() (map[string][]MarginTier, error) {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var metaResp MetaResponse
+	if err := c.doRequest("POST", url, InfoRequest{Type: "meta"}, &metaResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch meta info: %w", err)
+	}
+
+	tables := make(map[int][]MarginTier)
+	for _, table := range metaResp.MarginTables {
+		for _, tier := range table.Tiers {
+			if tier.MaxLeverage > 0 {
+				tables[table.ID] = append(tables[table.ID], MarginTier{
+					LowerBound:  tier.LowerBound.Float(),
+					MaxLeverage: tier.MaxLeverage,
+				})
+			}
+		}
+	}
+
+	result := make(map[string][]MarginTier)
+	for _, asset := range metaResp.Universe {
+		if tiers := tables[asset.MarginTableID]; len(tiers) > 0 {
+			tiers = append([]MarginTier(nil), tiers...)
+			sort.Slice(tiers, func(i, j int) bool { return tiers[i].LowerBound < tiers[j].LowerBound })
+			result[asset.Name] = tiers
+			continue
+		}
+
+		if asset.MaxLeverage > 0 {
+			result[asset.Name] = []MarginTier{{LowerBound: 0, MaxLeverage: asset.MaxLeverage}}
+		}
+	}
+
+	return result, nil
+}
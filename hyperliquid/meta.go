@@ -0,0 +1,169 @@
+package hyperliquid
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinOrderValue is the smallest order notional in USD the exchange accepts
+	MinOrderValue = 10.0
+
+	// Prices have at most 5 significant figures and 6 - szDecimals decimals,
+	// or 8 - szDecimals for spot
+	priceSigFigs         = 5
+	maxPriceDecimals     = 6
+	maxSpotPriceDecimals = 8
+
+	// metaCacheTTL bounds how long asset metadata is reused before refetching
+	metaCacheTTL = time.Hour
+)
+
+// ErrOrderTooSmall is returned for orders below MinOrderValue
+var ErrOrderTooSmall = errors.New("order below minimum notional")
+
+// AssetMeta is the trading metadata of one perpetual or spot asset
+type AssetMeta struct {
+	Name         string
+	Index        int // Asset id used in orders
+	SzDecimals   int // Decimals allowed in order sizes
+	MaxLeverage  int
+	OnlyIsolated bool
+
+	Spot bool   // Spot pair rather than a perpetual
+	Coin string // Name in info queries when it differs from Name, e.g. "@107"
+}
+
+// GetMeta fetches the metadata of every perpetual asset keyed by name
+func (c *Client) GetMeta() (map[string]AssetMeta, error) {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var metaResp MetaResponse
+	if err := c.doRequest("POST", url, InfoRequest{Type: "meta"}, &metaResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch meta info: %w", err)
+	}
+
+	assets := make(map[string]AssetMeta, len(metaResp.Universe))
+	for i, asset := range metaResp.Universe {
+		if asset.Name == "" {
+			return nil, fmt.Errorf("failed to parse meta info: asset %d has no name", i)
+		}
+		assets[asset.Name] = AssetMeta{
+			Name:         asset.Name,
+			Index:        i,
+			SzDecimals:   asset.SzDecimals,
+			MaxLeverage:  asset.MaxLeverage,
+			OnlyIsolated: asset.OnlyIsolated,
+		}
+	}
+
+	return assets, nil
+}
+
+// AssetMeta returns the cached metadata for symbol, refetching meta when the
+// cache is stale or does not know the symbol yet. Spot symbols ("HYPE/USDC")
+// are looked up in the spot universe.
+func (c *Client) AssetMeta(symbol string) (*AssetMeta, error) {
+	if IsSpot(symbol) {
+		return c.spotAssetMeta(symbol)
+	}
+
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+
+	meta, ok := c.assets[symbol]
+	if ok && time.Since(c.metaFetched) < metaCacheTTL {
+		return &meta, nil
+	}
+
+	assets, err := c.GetMeta()
+	if err != nil {
+		if ok {
+			// Stale metadata beats failing the order
+			return &meta, nil
+		}
+		return nil, err
+	}
+	c.assets = assets
+	c.metaFetched = time.Now()
+
+	meta, ok = assets[symbol]
+	if !ok {
+		return nil, fmt.Errorf("symbol %s not found in universe", symbol)
+	}
+	return &meta, nil
+}
+
+// priceDecimals returns the most decimals a price may have
+func (m *AssetMeta) priceDecimals() int {
+	if m.Spot {
+		return maxSpotPriceDecimals
+	}
+	return maxPriceDecimals
+}
+
+// RoundPrice rounds price to 5 significant figures and the asset's price
+// decimals. Integer prices are always valid.
+func (m *AssetMeta) RoundPrice(price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	if price == math.Trunc(price) {
+		return price
+	}
+
+	// Decimals that keep 5 significant figures at this magnitude
+	decimals := priceSigFigs - 1 - int(math.Floor(math.Log10(price)))
+	if maxDecimals := m.priceDecimals() - m.SzDecimals; decimals > maxDecimals {
+		decimals = maxDecimals
+	}
+	if decimals < 0 {
+		// Above 5 integer digits only whole prices remain valid
+		return math.Round(price)
+	}
+
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(price*scale) / scale
+}
+
+// RoundSize rounds size down to the asset's size decimals so an order never
+// exceeds the requested quantity
+func (m *AssetMeta) RoundSize(size float64) float64 {
+	scale := math.Pow(10, float64(m.SzDecimals))
+	// The epsilon keeps sizes like 0.3 from flooring to 0.29999
+	return math.Floor(size*scale+1e-9) / scale
+}
+
+// NormalizeOrder rounds an order's price and size to what the exchange accepts
+// and formats them for the API. Orders below MinOrderValue are rejected unless
+// reduce-only, so small positions can always be closed.
+func (m *AssetMeta) NormalizeOrder(price, size float64, reduceOnly bool) (priceStr, sizeStr string, err error) {
+	price = m.RoundPrice(price)
+	size = m.RoundSize(size)
+
+	if price <= 0 {
+		return "", "", fmt.Errorf("invalid price for %s", m.Name)
+	}
+	if size <= 0 {
+		return "", "", fmt.Errorf("%w: %s size rounds to zero at %d decimals", ErrOrderTooSmall, m.Name, m.SzDecimals)
+	}
+	if notional := price * size; !reduceOnly && notional < MinOrderValue {
+		return "", "", fmt.Errorf("%w: %s notional $%.2f < $%.2f", ErrOrderTooSmall, m.Name, notional, MinOrderValue)
+	}
+
+	return formatDecimal(price, m.priceDecimals()), formatDecimal(size, m.SzDecimals), nil
+}
+
+// formatDecimal formats value with at most decimals places and no trailing zeros
+func formatDecimal(value float64, decimals int) string {
+	str := strconv.FormatFloat(value, 'f', decimals, 64)
+	if strings.Contains(str, ".") {
+		str = strings.TrimRight(str, "0")
+		str = strings.TrimSuffix(str, ".")
+	}
+	return str
+}
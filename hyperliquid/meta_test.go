@@ -0,0 +1,64 @@
+package hyperliquid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoundPrice(t *testing.T) {
+	cases := []struct {
+		szDecimals int
+		price      float64
+		want       float64
+	}{
+		{4, 3456.789, 3456.8},     // 5 significant figures
+		{5, 98765.4321, 98765},    // integer part already uses all figures
+		{5, 123456.7, 123457},     // whole prices stay valid beyond 5 figures
+		{5, 123456, 123456},       // integers are always valid
+		{0, 0.123456789, 0.12346}, // sub-unit prices keep 5 figures
+		{2, 0.00123456, 0.0012},   // capped at 6 - szDecimals decimals
+	}
+	for _, c := range cases {
+		meta := &AssetMeta{Name: "TEST", SzDecimals: c.szDecimals}
+		if got := meta.RoundPrice(c.price); got != c.want {
+			t.Errorf("RoundPrice(%v) at szDecimals %d = %v, want %v", c.price, c.szDecimals, got, c.want)
+		}
+	}
+}
+
+func TestRoundSize(t *testing.T) {
+	meta := &AssetMeta{Name: "ETH", SzDecimals: 4}
+	if got := meta.RoundSize(0.123456); got != 0.1234 {
+		t.Errorf("size should round down to 4 decimals, got %v", got)
+	}
+	if got := meta.RoundSize(0.3); got != 0.3 {
+		t.Errorf("representable sizes should be unchanged, got %v", got)
+	}
+
+	whole := &AssetMeta{Name: "DOGE", SzDecimals: 0}
+	if got := whole.RoundSize(152.9); got != 152 {
+		t.Errorf("szDecimals 0 should trade whole units, got %v", got)
+	}
+}
+
+func TestNormalizeOrder(t *testing.T) {
+	meta := &AssetMeta{Name: "ETH", Index: 1, SzDecimals: 4}
+
+	price, size, err := meta.NormalizeOrder(3456.789123, 0.123456789, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if price != "3456.8" || size != "0.1234" {
+		t.Errorf("expected 3456.8 / 0.1234, got %s / %s", price, size)
+	}
+
+	if _, _, err := meta.NormalizeOrder(3000, 0.003, false); !errors.Is(err, ErrOrderTooSmall) {
+		t.Errorf("$9 order should be rejected as too small, got %v", err)
+	}
+	if _, _, err := meta.NormalizeOrder(3000, 0.003, true); err != nil {
+		t.Errorf("reduce-only orders should be exempt from the minimum: %v", err)
+	}
+	if _, _, err := meta.NormalizeOrder(3000, 0.00001, true); !errors.Is(err, ErrOrderTooSmall) {
+		t.Errorf("size rounding to zero should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OrderModify changes one resting order. Zero fields keep the order's current
+// value; side, reduce-only and order type always come from the order itself.
+type OrderModify struct {
+	Symbol       string
+	OrderID      string // Exchange order id or 0x-prefixed cloid
+	Price        float64
+	Size         float64
+	TriggerPrice float64 // Only for stop loss and take profit orders
+}
+
+// apply returns spec with the modification's non-zero fields applied
+func (m OrderModify) apply(spec OrderSpec) OrderSpec {
+	if m.Price > 0 {
+		spec.Price = m.Price
+	}
+	if m.Size > 0 {
+		spec.Size = m.Size
+	}
+	if m.TriggerPrice > 0 && spec.Trigger != nil {
+		trigger := *spec.Trigger
+		trigger.Price = m.TriggerPrice
+		spec.Trigger = &trigger
+	}
+	return spec
+}
+
+// ModifyOrders atomically modifies resting orders in a single signed action
+func (t *Trader) ModifyOrders(mods []OrderModify) error {
+	if len(mods) == 0 {
+		return nil
+	}
+
+	specs := make([]OrderSpec, 0, len(mods))
+	wire := make([]ModifyWire, 0, len(mods))
+	for _, mod := range mods {
+		spec, err := t.trackedOrder(mod.Symbol, mod.OrderID)
+		if err != nil {
+			return err
+		}
+		spec = mod.apply(spec)
+
+		order, err := t.wireOrder(spec)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+		wire = append(wire, ModifyWire{Oid: wireOrderID(mod.OrderID), Order: order})
+	}
+
+	resp, err := t.sendAction(BatchModifyAction{Type: "batchModify", Modifies: wire})
+	if err != nil {
+		return err
+	}
+
+	if message := resp.Rejected(); message != "" {
+		return fmt.Errorf("modify rejected: %s", message)
+	}
+	data, err := resp.Data()
+	if err != nil {
+		return err
+	}
+	for i, mod := range mods {
+		result := &OrderResult{Success: true}
+		if i < len(data.Statuses) {
+			parseOrderStatus(data.Statuses[i], result)
+		}
+		if !result.Success {
+			return fmt.Errorf("modify of order %s failed: %s", mod.OrderID, result.Message)
+		}
+
+		// A modified order may be assigned a new oid
+		t.untrack(mod.OrderID)
+		if result.Resting {
+			t.track(result.OrderID, specs[i])
+		}
+		if specs[i].Cloid != "" {
+			t.track(specs[i].Cloid, specs[i])
+		}
+	}
+	return nil
+}
+
+// wireOrderID returns the form the exchange expects for an order reference:
+// a number for an oid, the string itself for a cloid
+func wireOrderID(orderID string) interface{} {
+	if oid, err := strconv.ParseInt(orderID, 10, 64); err == nil {
+		return oid
+	}
+	return orderID
+}
+
+// track remembers a resting order's spec by its oid and cloid
+func (t *Trader) track(orderID string, spec OrderSpec) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tracked == nil {
+		t.tracked = make(map[string]OrderSpec)
+	}
+	if orderID != "" {
+		t.tracked[orderID] = spec
+	}
+	if spec.Cloid != "" {
+		t.tracked[spec.Cloid] = spec
+	}
+}
+
+// untrack forgets an order, under both its oid and cloid
+func (t *Trader) untrack(orderID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spec, ok := t.tracked[orderID]
+	if !ok {
+		return
+	}
+	delete(t.tracked, orderID)
+	if spec.Cloid != "" {
+		delete(t.tracked, spec.Cloid)
+	}
+	for id, other := range t.tracked {
+		if other.Cloid != "" && other.Cloid == spec.Cloid {
+			delete(t.tracked, id)
+		}
+	}
+}
+
+// trackedOrder returns the spec of a resting order. Orders this trader did
+// not place, e.g. after a restart, are looked up on the exchange.
+func (t *Trader) trackedOrder(symbol, orderID string) (OrderSpec, error) {
+	t.mu.Lock()
+	spec, ok := t.tracked[orderID]
+	t.mu.Unlock()
+	if ok {
+		return spec, nil
+	}
+
+	status, err := t.GetOrderStatus(orderID)
+	if err != nil {
+		return OrderSpec{}, fmt.Errorf("failed to look up order %s: %w", orderID, err)
+	}
+	if status.Status != OrderStatusOpen {
+		return OrderSpec{}, fmt.Errorf("order %s is %s, not open", orderID, status.Status)
+	}
+	spec = status.Spec()
+	if spec.Symbol == "" {
+		spec.Symbol = symbol
+	}
+	// Spot orders report the pair's info coin, e.g. "@107"
+	if !strings.EqualFold(spec.Symbol, symbol) && spec.Symbol != t.client.infoCoin(symbol) {
+		return OrderSpec{}, fmt.Errorf("order %s is for %s, not %s", orderID, spec.Symbol, symbol)
+	}
+	spec.Symbol = symbol
+	return spec, nil
+}
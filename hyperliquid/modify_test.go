@@ -0,0 +1,87 @@
+package hyperliquid
+
+import "testing"
+
+func TestModifyOrderKeepsSideAndReduceOnly(t *testing.T) {
+	trader, actions := recordingExchange(t,
+		`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":12}}]}}}`,
+		`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":13}}]}}}`)
+
+	_, err := trader.PlaceOrders([]OrderSpec{{Symbol: "ETH", Size: 0.5, Price: 2100, ReduceOnly: true, Tif: TifAlo}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := trader.ModifyOrder("ETH", "12", 2050, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	action := (*actions)[1]
+	modify := action["modifies"].([]interface{})[0].(map[string]interface{})
+	order := modify["order"].(map[string]interface{})
+	if action["type"] != "batchModify" || modify["oid"] != float64(12) {
+		t.Fatalf("expected a modify of oid 12, got %v", action)
+	}
+	if order["b"] != false || order["r"] != true || order["p"] != "2050" || order["s"] != "0.5" {
+		t.Errorf("modify should keep side, reduce-only and size, got %v", order)
+	}
+	if tif := order["t"].(map[string]interface{})["limit"].(map[string]interface{})["tif"]; tif != TifAlo {
+		t.Errorf("modify should keep the time in force, got %v", tif)
+	}
+
+	// The order now rests as oid 13; 12 is gone
+	trader.mu.Lock()
+	_, old := trader.tracked["12"]
+	spec, ok := trader.tracked["13"]
+	trader.mu.Unlock()
+	if old || !ok || spec.Price != 2050 {
+		t.Errorf("tracking should follow the new oid, got %v", trader.tracked)
+	}
+}
+
+func TestBatchModifyTrigger(t *testing.T) {
+	trader, actions := recordingExchange(t, `{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":12}}]}}}`)
+
+	stop := OrderSpec{
+		Symbol:     "ETH",
+		Size:       0.5,
+		Price:      1900,
+		ReduceOnly: true,
+		Cloid:      "0x0000000000000000000000000000000b",
+		Trigger:    &Trigger{Price: 1900, IsMarket: true, Kind: "sl"},
+	}
+	if _, err := trader.PlaceOrders([]OrderSpec{stop}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := trader.ModifyOrders([]OrderModify{{Symbol: "ETH", OrderID: stop.Cloid, Price: 1950, TriggerPrice: 1950}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	action := (*actions)[1]
+	modify := action["modifies"].([]interface{})[0].(map[string]interface{})
+	order := modify["order"].(map[string]interface{})
+	trigger := order["t"].(map[string]interface{})["trigger"].(map[string]interface{})
+	if action["type"] != "batchModify" || modify["oid"] != stop.Cloid {
+		t.Fatalf("expected a batchModify by cloid, got %v", action)
+	}
+	if trigger["triggerPx"] != "1950" || trigger["tpsl"] != "sl" || trigger["isMarket"] != true || order["r"] != true {
+		t.Errorf("unexpected modified trigger order: %v", order)
+	}
+}
+
+func TestCancelOrderWireFormat(t *testing.T) {
+	trader, actions := recordingExchange(t, `{"status":"ok","response":{"type":"cancel","data":{"statuses":["success"]}}}`)
+
+	if err := trader.CancelOrder("ETH", "12"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	action := (*actions)[0]
+	cancel := action["cancels"].([]interface{})[0].(map[string]interface{})
+	if action["type"] != "cancel" || cancel["a"] != float64(1) || cancel["o"] != float64(12) {
+		t.Errorf("cancels should use the asset index and a numeric oid, got %v", action)
+	}
+
+	if err := trader.CancelOrder("ETH", "BTC-12"); err == nil {
+		t.Error("a malformed order id should be refused")
+	}
+}
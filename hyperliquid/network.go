@@ -0,0 +1,61 @@
+package hyperliquid
+
+import "strings"
+
+// Network is a Hyperliquid chain: where its API lives and what signatures on
+// it commit to, so an action signed for testnet is never valid on mainnet
+type Network struct {
+	Name             string // hyperliquidChain of user-signed actions: "Mainnet" or "Testnet"
+	APIURL           string
+	Source           string // Phantom agent source L1 actions are signed with
+	SignatureChainID string // Chain id user-signed actions are signed on
+}
+
+var (
+	Mainnet = Network{
+		Name:             "Mainnet",
+		APIURL:           "https://api.hyperliquid.xyz",
+		Source:           "a",
+		SignatureChainID: "0xa4b1",
+	}
+	Testnet = Network{
+		Name:             "Testnet",
+		APIURL:           "https://api.hyperliquid-testnet.xyz",
+		Source:           "b",
+		SignatureChainID: "0x66eee",
+	}
+)
+
+// NetworkFor returns Testnet when testnet is set, otherwise Mainnet
+func NetworkFor(testnet bool) Network {
+	if testnet {
+		return Testnet
+	}
+	return Mainnet
+}
+
+// IsTestnet reports whether n is the testnet
+func (n Network) IsTestnet() bool {
+	return n.Name == Testnet.Name
+}
+
+// Serves reports whether apiURL may be used with n: any URL but the other
+// network's official endpoint, so local and proxy endpoints keep working
+func (n Network) Serves(apiURL string) bool {
+	other := Testnet
+	if n.IsTestnet() {
+		other = Mainnet
+	}
+	return strings.TrimRight(apiURL, "/") != other.APIURL
+}
+
+// SetNetwork selects the chain the client's traders sign for. Clients start on
+// Mainnet.
+func (c *Client) SetNetwork(network Network) {
+	c.network = network
+}
+
+// Network returns the chain the client's traders sign for
+func (c *Client) Network() Network {
+	return c.network
+}
@@ -0,0 +1,86 @@
+package hyperliquid
+
+import "testing"
+
+func TestNetworkEndpoints(t *testing.T) {
+	if NetworkFor(false) != Mainnet || NetworkFor(true) != Testnet || !Testnet.IsTestnet() || Mainnet.IsTestnet() {
+		t.Error("testnet should select Testnet, otherwise Mainnet")
+	}
+	if NewClient("").Network() != Mainnet {
+		t.Error("clients should start on Mainnet")
+	}
+
+	cases := []struct {
+		network Network
+		apiURL  string
+		ok      bool
+	}{
+		{Mainnet, "https://api.hyperliquid.xyz", true},
+		{Mainnet, "https://api.hyperliquid-testnet.xyz/", false},
+		{Testnet, "https://api.hyperliquid-testnet.xyz", true},
+		{Testnet, "https://api.hyperliquid.xyz", false},
+		{Testnet, "http://127.0.0.1:8080", true},
+	}
+	for _, c := range cases {
+		if c.network.Serves(c.apiURL) != c.ok {
+			t.Errorf("%s serving %s: expected %v", c.network.Name, c.apiURL, c.ok)
+		}
+	}
+}
+
+func TestTestnetSigning(t *testing.T) {
+	url, requests := captureServer(t, exchangeReply(`{"status":"ok","response":{"type":"default"}}`))
+
+	master, _ := KeyAddress(testKey)
+	mainnet, err := NewTrader(NewClient(url), testKey, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testnetClient := NewClient(url)
+	testnetClient.SetNetwork(Testnet)
+	testnet, err := NewTrader(testnetClient, testKey, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same action signs differently per network, so it cannot be replayed
+	action := map[string]interface{}{"type": "cancel", "cancels": []interface{}{}}
+	mainnetSignature, err := mainnet.signAction(action, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testnetSignature, err := testnet.signAction(action, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mainnetSignature == testnetSignature {
+		t.Error("testnet and mainnet signatures of an action should differ")
+	}
+
+	// The SDK's testnet vector: source "b" in the phantom agent
+	sdkTrader, err := NewTrader(testnetClient, sdkKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := sdkTrader.signAction(dummyAction{Type: "dummy", Num: 100000000000}, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Signature{
+		R: "0x542af61ef1f429707e3c76c5293c80d01f74ef853e34b76efffcb57e574f9510",
+		S: "0x17b8b32f086e8cdede991f1e2c529f5dd5297cbe8128500e00cbaf766204a613",
+		V: 28,
+	}
+	if signature != expected {
+		t.Errorf("expected the SDK's testnet signature %+v, got %+v", expected, signature)
+	}
+
+	agent, _ := NewAgent()
+	if err := testnet.ApproveAgent(agent.Address, "bot"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	approve := (*requests)[0]["action"].(map[string]interface{})
+	if approve["hyperliquidChain"] != "Testnet" || approve["signatureChainId"] != "0x66eee" {
+		t.Errorf("approveAgent should be signed for the testnet chain, got %v", approve)
+	}
+}
@@ -0,0 +1,200 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Order statuses reported by orderStatus
+const (
+	OrderStatusOpen     = "open"
+	OrderStatusFilled   = "filled"
+	OrderStatusCanceled = "canceled"
+)
+
+// OrderStatus is the state of one order
+type OrderStatus struct {
+	OrderID      string
+	Status       string // "open", "filled", "canceled", "rejected", ...
+	Symbol       string
+	IsBuy        bool
+	LimitPrice   float64
+	OrigSize     float64
+	Remaining    float64
+	ReduceOnly   bool
+	Tif          string
+	Cloid        string
+	OrderType    string // "Limit", "Stop Market", "Take Profit Limit", ...
+	IsTrigger    bool
+	TriggerPrice float64
+}
+
+// FilledSize returns how much of the order has filled
+func (s *OrderStatus) FilledSize() float64 {
+	return s.OrigSize - s.Remaining
+}
+
+// Spec rebuilds the order's spec, sized to what is still resting
+func (s *OrderStatus) Spec() OrderSpec {
+	spec := OrderSpec{
+		Symbol:     s.Symbol,
+		IsBuy:      s.IsBuy,
+		Size:       s.Remaining,
+		Price:      s.LimitPrice,
+		ReduceOnly: s.ReduceOnly,
+		Tif:        s.Tif,
+		Cloid:      s.Cloid,
+	}
+	if s.IsTrigger {
+		spec.Trigger = &Trigger{
+			Price:    s.TriggerPrice,
+			IsMarket: strings.Contains(s.OrderType, "Market"),
+			Kind:     "sl",
+		}
+		if strings.HasPrefix(s.OrderType, "Take Profit") {
+			spec.Trigger.Kind = "tp"
+		}
+	}
+	return spec
+}
+
+// GetOrderStatus fetches the status of one of the account's orders by
+// exchange order id or 0x-prefixed cloid
+func (t *Trader) GetOrderStatus(orderID string) (*OrderStatus, error) {
+	oid := wireOrderID(orderID)
+	if _, ok := oid.(string); ok && !strings.HasPrefix(orderID, "0x") {
+		return nil, fmt.Errorf("invalid order id %q", orderID)
+	}
+
+	url := fmt.Sprintf("%s/info", t.client.baseURL)
+	req := InfoRequest{
+		Type: "orderStatus",
+		User: t.user(),
+		Oid:  oid,
+	}
+
+	var resp OrderStatusResponse
+	if err := t.client.doRequest("POST", url, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "order" {
+		return nil, fmt.Errorf("order %s not found: %s", orderID, resp.Status)
+	}
+	if resp.Order == nil {
+		return nil, fmt.Errorf("failed to parse order status")
+	}
+
+	order := resp.Order.Order
+	result := &OrderStatus{
+		OrderID:      orderID,
+		Status:       resp.Order.Status,
+		Symbol:       order.Coin,
+		IsBuy:        order.Side == "B",
+		LimitPrice:   order.LimitPx.Float(),
+		OrigSize:     order.OrigSz.Float(),
+		Remaining:    order.Sz.Float(),
+		ReduceOnly:   order.ReduceOnly,
+		Tif:          order.Tif,
+		Cloid:        order.Cloid,
+		OrderType:    order.OrderType,
+		IsTrigger:    order.IsTrigger,
+		TriggerPrice: order.TriggerPx.Float(),
+	}
+	if result.Status == OrderStatusFilled {
+		result.Remaining = 0
+	}
+	if result.Status != OrderStatusOpen {
+		t.untrack(orderID)
+	}
+
+	return result, nil
+}
+
+// PlaceTWAP starts the exchange's native TWAP, which slices size over minutes.
+// refPrice is only used to check the minimum notional. The returned OrderID is
+// the TWAP id.
+func (t *Trader) PlaceTWAP(symbol string, isBuy bool, size, refPrice float64, minutes int, randomize, reduceOnly bool) (*OrderResult, error) {
+	meta, err := t.client.AssetMeta(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset metadata: %w", err)
+	}
+
+	_, sizeStr, err := meta.NormalizeOrder(refPrice, size, reduceOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	action := TwapOrderAction{
+		Type: "twapOrder",
+		Twap: TwapWire{
+			Asset:      meta.Index,
+			IsBuy:      isBuy,
+			Size:       sizeStr,
+			ReduceOnly: reduceOnly,
+			Minutes:    minutes,
+			Randomize:  randomize,
+		},
+	}
+
+	resp, err := t.sendAction(action)
+	if err != nil {
+		return nil, err
+	}
+
+	// {"status": "ok", "response": {"data": {"status": {"running": {"twapId": 1}}}}}
+	if message := resp.Rejected(); message != "" {
+		return &OrderResult{Success: false, Message: message}, nil
+	}
+	data, err := resp.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OrderResult{Success: true, Message: resp.Status}
+	if data.Status != nil {
+		if data.Status.Error != "" {
+			result.Success = false
+			result.Message = data.Status.Error
+		}
+		if data.Status.Running != nil {
+			result.OrderID = strconv.FormatInt(data.Status.Running.TwapID, 10)
+		}
+	}
+
+	return result, nil
+}
+
+// sendAction signs an exchange action and sends it, for the vault if the
+// trader has one
+func (t *Trader) sendAction(action interface{}) (*ExchangeResponse, error) {
+	return t.send(action, time.Now().UnixMilli(), t.vaultAddress())
+}
+
+// send signs an L1 action and posts it with nonce
+func (t *Trader) send(action interface{}, nonce int64, vaultAddress string) (*ExchangeResponse, error) {
+	signature, err := t.signAction(action, nonce, vaultAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign action: %w", err)
+	}
+	return t.post(action, nonce, signature, vaultAddress)
+}
+
+// post sends a signed action to the exchange. User-signed actions such as
+// approveAgent carry the nonce in the action itself and no vaultAddress.
+func (t *Trader) post(action interface{}, nonce int64, signature Signature, vaultAddress string) (*ExchangeResponse, error) {
+	payload := ExchangeRequest{
+		Action:       action,
+		Nonce:        nonce,
+		Signature:    signature,
+		VaultAddress: vaultAddress,
+	}
+
+	url := fmt.Sprintf("%s/exchange", t.client.baseURL)
+	var resp ExchangeResponse
+	if err := t.client.doRequest("POST", url, payload, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,132 @@
+package hyperliquid
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// l1Domain is the EIP-712 domain L1 actions are signed in, on every network
+var l1Domain = apitypes.TypedDataDomain{
+	Name:              "Exchange",
+	Version:           "1",
+	ChainId:           math.NewHexOrDecimal256(1337),
+	VerifyingContract: "0x0000000000000000000000000000000000000000",
+}
+
+var eip712Domain = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// signAction signs an L1 action, such as an order or cancel, sent with nonce.
+// The key signs a phantom agent whose connection id is the action's hash and
+// whose source is the network's, so a signature is only valid for one action,
+// nonce, vault and network.
+func (t *Trader) signAction(action interface{}, nonce int64, vaultAddress string) (Signature, error) {
+	hash, err := actionHash(action, nonce, vaultAddress)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	return signTypedData(t.privateKey, apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712Domain,
+			"Agent": {
+				{Name: "source", Type: "string"},
+				{Name: "connectionId", Type: "bytes32"},
+			},
+		},
+		PrimaryType: "Agent",
+		Domain:      l1Domain,
+		Message: apitypes.TypedDataMessage{
+			"source":       t.client.Network().Source,
+			"connectionId": hash.Bytes(),
+		},
+	})
+}
+
+// signUserAction signs a user-signed action, such as approveAgent, directly
+// as EIP-712 typed data: message with fields as primaryType, in the
+// HyperliquidSignTransaction domain on the network's signature chain id.
+// Unlike L1 actions they are never signed by agents or for vaults.
+func (t *Trader) signUserAction(primaryType string, fields []apitypes.Type, message apitypes.TypedDataMessage) (Signature, error) {
+	var chainID math.HexOrDecimal256
+	if err := chainID.UnmarshalText([]byte(t.client.Network().SignatureChainID)); err != nil {
+		return Signature{}, fmt.Errorf("invalid signature chain id: %w", err)
+	}
+
+	return signTypedData(t.privateKey, apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712Domain,
+			primaryType:    fields,
+		},
+		PrimaryType: primaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:              "HyperliquidSignTransaction",
+			Version:           "1",
+			ChainId:           &chainID,
+			VerifyingContract: "0x0000000000000000000000000000000000000000",
+		},
+		Message: message,
+	})
+}
+
+// actionHash returns the keccak256 hash of the msgpack encoded action
+// followed by the nonce as 8 big-endian bytes and a vault flag byte, 1 with
+// the vault's address after it or 0 without one. Struct fields are encoded
+// in declaration order under their json names, which must therefore match
+// the key order of the exchange's own encoding.
+func actionHash(action interface{}, nonce int64, vaultAddress string) (common.Hash, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(action); err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode action: %w", err)
+	}
+
+	data := binary.BigEndian.AppendUint64(buf.Bytes(), uint64(nonce))
+	if vaultAddress == "" {
+		data = append(data, 0)
+	} else {
+		data = append(data, 1)
+		data = append(data, common.HexToAddress(vaultAddress).Bytes()...)
+	}
+	return crypto.Keccak256Hash(data), nil
+}
+
+// signTypedData signs the EIP-712 hash of typed with key
+func signTypedData(key *ecdsa.PrivateKey, typed apitypes.TypedData) (Signature, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typed)
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	return Signature{
+		R: hexInt(signature[:32]),
+		S: hexInt(signature[32:64]),
+		V: int(signature[64]) + 27,
+	}, nil
+}
+
+// hexInt formats big-endian bytes as a 0x-prefixed hex number without
+// leading zeros, as the exchange's own SDK does
+func hexInt(b []byte) string {
+	return "0x" + new(big.Int).SetBytes(b).Text(16)
+}
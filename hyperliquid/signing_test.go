@@ -0,0 +1,71 @@
+package hyperliquid
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// sdkKey and the signatures below are the L1 signing vectors of the
+// exchange's Python SDK
+const sdkKey = "0x0123456789012345678901234567890123456789012345678901234567890123"
+
+// dummyAction encodes as {"type": "dummy", "num": 100000000000}
+type dummyAction struct {
+	Type string `json:"type"`
+	Num  int64  `json:"num"`
+}
+
+func TestSignL1Action(t *testing.T) {
+	trader, err := NewTrader(NewClient(""), sdkKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := trader.signAction(dummyAction{Type: "dummy", Num: 100000000000}, 0, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Signature{
+		R: "0x53749d5b30552aeb2fca34b530185976545bb22d0b3ce6f62e31be961a59298",
+		S: "0x755c40ba9bf05223521753995abb2f73ab3229be8ec921f350cb447e384d8ed8",
+		V: 27,
+	}
+	if signature != expected {
+		t.Errorf("expected the SDK's mainnet signature %+v, got %+v", expected, signature)
+	}
+}
+
+func TestSignUserAction(t *testing.T) {
+	client := NewClient("")
+	client.SetNetwork(Testnet)
+	trader, err := NewTrader(client, sdkKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The SDK's usdSend vector exercises the same domain as approveAgent
+	signature, err := trader.signUserAction("HyperliquidTransaction:UsdSend", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "destination", Type: "string"},
+		{Name: "amount", Type: "string"},
+		{Name: "time", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"destination":      "0x5e9ee1089755c3435139848e47e6635505d5a13a",
+		"amount":           "1",
+		"time":             big.NewInt(1687816341423),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Signature{
+		R: "0x637b37dd731507cdd24f46532ca8ba6eec616952c56218baeff04144e4a77073",
+		S: "0x11a6a24900e6e314136d2592e2f8d502cd89b7c15b198e1bee043c9589f9fad7",
+		V: 27,
+	}
+	if signature != expected {
+		t.Errorf("expected the SDK's testnet signature %+v, got %+v", expected, signature)
+	}
+}
@@ -0,0 +1,239 @@
+package hyperliquid
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// spotAssetOffset is added to a spot pair's index to form its asset id
+const spotAssetOffset = 10000
+
+// IsSpot reports whether symbol names a spot pair, e.g. "HYPE/USDC", rather
+// than a perpetual
+func IsSpot(symbol string) bool {
+	return strings.Contains(symbol, "/")
+}
+
+// SpotBalance is the account's balance of one spot token
+type SpotBalance struct {
+	Coin          string
+	Total         float64
+	Hold          float64 // Reserved by resting orders
+	EntryNotional float64 // USDC cost of the balance
+}
+
+// Available returns the part of the balance not reserved by resting orders
+func (b SpotBalance) Available() float64 {
+	return b.Total - b.Hold
+}
+
+// GetSpotMeta fetches the metadata of every spot pair keyed by "BASE/QUOTE"
+// token names. Pairs without a canonical name are queried as "@<index>", which
+// is kept in AssetMeta.Coin.
+func (c *Client) GetSpotMeta() (map[string]AssetMeta, error) {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var metaResp SpotMetaResponse
+	if err := c.doRequest("POST", url, InfoRequest{Type: "spotMeta"}, &metaResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch spot meta info: %w", err)
+	}
+	return spotAssets(metaResp)
+}
+
+// spotAssets resolves each pair's token indices to names
+func spotAssets(metaResp SpotMetaResponse) (map[string]AssetMeta, error) {
+	tokens := make(map[int]SpotToken, len(metaResp.Tokens))
+	for _, token := range metaResp.Tokens {
+		tokens[token.Index] = token
+	}
+
+	assets := make(map[string]AssetMeta, len(metaResp.Universe))
+	for _, pair := range metaResp.Universe {
+		if len(pair.Tokens) != 2 {
+			return nil, fmt.Errorf("failed to parse spot meta info: pair %q has %d tokens", pair.Name, len(pair.Tokens))
+		}
+		base, okBase := tokens[pair.Tokens[0]]
+		quote, okQuote := tokens[pair.Tokens[1]]
+		if !okBase || !okQuote {
+			return nil, fmt.Errorf("failed to parse spot meta info: pair %q references an unknown token", pair.Name)
+		}
+
+		name := base.Name + "/" + quote.Name
+		assets[name] = AssetMeta{
+			Name:        name,
+			Index:       spotAssetOffset + pair.Index,
+			SzDecimals:  base.SzDecimals,
+			MaxLeverage: 1,
+			Spot:        true,
+			Coin:        pair.Name,
+		}
+	}
+	return assets, nil
+}
+
+// spotAssetMeta returns the cached metadata of a spot pair, refetching
+// spotMeta when the cache is stale or does not know the pair yet
+func (c *Client) spotAssetMeta(symbol string) (*AssetMeta, error) {
+	c.spotMu.Lock()
+	defer c.spotMu.Unlock()
+
+	meta, ok := c.spotAssets[symbol]
+	if ok && time.Since(c.spotFetched) < metaCacheTTL {
+		return &meta, nil
+	}
+
+	assets, err := c.GetSpotMeta()
+	if err != nil {
+		if ok {
+			return &meta, nil
+		}
+		return nil, err
+	}
+	c.spotAssets = assets
+	c.spotFetched = time.Now()
+
+	meta, ok = assets[symbol]
+	if !ok {
+		return nil, fmt.Errorf("spot pair %s not found in spot universe", symbol)
+	}
+	return &meta, nil
+}
+
+// infoCoin returns the coin info queries use for symbol. Perps are queried by
+// name, spot pairs by their universe name, e.g. "@107" for HYPE/USDC.
+func (c *Client) infoCoin(symbol string) string {
+	if !IsSpot(symbol) {
+		return symbol
+	}
+	meta, err := c.spotAssetMeta(symbol)
+	if err != nil || meta.Coin == "" {
+		return symbol
+	}
+	return meta.Coin
+}
+
+// spotMarketContext fills volume and 24h change of a spot pair from
+// spotMetaAndAssetCtxs
+func (c *Client) spotMarketContext(coin string, marketInfo *MarketInfo) error {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var metaAndCtxs SpotMetaAndAssetCtxs
+	if err := c.doRequest("POST", url, InfoRequest{Type: "spotMetaAndAssetCtxs"}, &metaAndCtxs); err != nil {
+		return err
+	}
+
+	for _, ctx := range metaAndCtxs.Ctxs {
+		if ctx.Coin != coin {
+			continue
+		}
+		marketInfo.Volume24h = ctx.DayNtlVlm.Float()
+
+		markPrice := ctx.MarkPx.Float()
+		if marketInfo.CurrentPrice == 0 {
+			marketInfo.CurrentPrice = markPrice
+		}
+		if prevPrice := ctx.PrevDayPx.Float(); prevPrice > 0 && markPrice > 0 {
+			marketInfo.PriceChange = ((markPrice - prevPrice) / prevPrice) * 100
+		}
+		break
+	}
+	return nil
+}
+
+// GetSpotBalances fetches the account's spot token balances, including USDC
+func (c *Client) GetSpotBalances(accountAddress string) ([]SpotBalance, error) {
+	url := fmt.Sprintf("%s/info", c.baseURL)
+
+	var state SpotClearinghouseState
+	req := InfoRequest{Type: "spotClearinghouseState", User: accountAddress}
+	if err := c.doRequest("POST", url, req, &state); err != nil {
+		return nil, err
+	}
+
+	balances := make([]SpotBalance, 0, len(state.Balances))
+	for _, balance := range state.Balances {
+		balances = append(balances, SpotBalance{
+			Coin:          balance.Coin,
+			Total:         balance.Total.Float(),
+			Hold:          balance.Hold.Float(),
+			EntryNotional: balance.EntryNtl.Float(),
+		})
+	}
+	return balances, nil
+}
+
+// AddSpotHoldings adds the account's spot balances to account. The base token
+// balance of each of symbols is reported as a long, unlevered position valued
+// at the pair's mid price; other tokens are only listed in account.Spot.
+func (c *Client) AddSpotHoldings(account *AccountState, accountAddress string, symbols []string) error {
+	balances, err := c.GetSpotBalances(accountAddress)
+	if err != nil {
+		return fmt.Errorf("failed to fetch spot balances: %w", err)
+	}
+	account.Spot = balances
+
+	held := make(map[string]SpotBalance, len(balances))
+	for _, balance := range balances {
+		if balance.Total > 0 {
+			held[balance.Coin] = balance
+		}
+	}
+
+	var mids map[string]Decimal
+	var fills []Fill
+	for _, symbol := range symbols {
+		if !IsSpot(symbol) {
+			continue
+		}
+		balance, ok := held[strings.SplitN(symbol, "/", 2)[0]]
+		if !ok {
+			continue
+		}
+		meta, err := c.spotAssetMeta(symbol)
+		if err != nil {
+			return err
+		}
+
+		if mids == nil {
+			url := fmt.Sprintf("%s/info", c.baseURL)
+			if err := c.doRequest("POST", url, InfoRequest{Type: "allMids"}, &mids); err != nil {
+				return err
+			}
+			fills, _ = c.GetUserFills(accountAddress)
+		}
+
+		position := spotPosition(symbol, balance, mids[meta.Coin].Float())
+		position.OpenTime = PositionOpenTime(fills, meta.Coin)
+		if !position.OpenTime.IsZero() {
+			position.HoldingTime = time.Since(position.OpenTime)
+		}
+		account.Positions[symbol] = position
+	}
+	return nil
+}
+
+// spotPosition reports a token balance as a long position at price
+func spotPosition(symbol string, balance SpotBalance, price float64) *Position {
+	position := &Position{
+		Symbol:        symbol,
+		Side:          "LONG",
+		Size:          balance.Total,
+		PositionValue: balance.Total * price,
+		Leverage:      1,
+		MarginMode:    "spot",
+	}
+	if balance.Total > 0 {
+		position.EntryPrice = balance.EntryNotional / balance.Total
+	}
+	if price > 0 {
+		position.CurrentPnL = position.PositionValue - balance.EntryNotional
+	}
+	if position.EntryPrice > 0 {
+		position.PnLPercent = (position.CurrentPnL / position.EntryPrice) * 100
+	}
+	if balance.EntryNotional > 0 {
+		position.ReturnOnEquity = position.CurrentPnL / balance.EntryNotional
+	}
+	return position
+}
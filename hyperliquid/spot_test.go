@@ -0,0 +1,120 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixtureSpotMeta(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+
+	hype, err := client.AssetMeta("HYPE/USDC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hype.Index != 10107 || hype.SzDecimals != 2 || !hype.Spot || hype.Coin != "@107" {
+		t.Errorf("unexpected HYPE/USDC meta: %+v", hype)
+	}
+	purr, err := client.AssetMeta("PURR/USDC")
+	if err != nil || purr.Index != 10000 || purr.Coin != "PURR/USDC" {
+		t.Errorf("unexpected PURR/USDC meta: %+v (%v)", purr, err)
+	}
+	if _, err := client.AssetMeta("FOO/USDC"); err == nil {
+		t.Error("an unknown spot pair should fail")
+	}
+
+	// Spot prices keep up to 8 - szDecimals decimals
+	if got := purr.RoundPrice(0.1940512); got != 0.19405 {
+		t.Errorf("expected 0.19405, got %v", got)
+	}
+	if got := (&AssetMeta{Spot: true, SzDecimals: 0}).RoundPrice(0.0000123456); got != 0.00001235 {
+		t.Errorf("expected 0.00001235, got %v", got)
+	}
+}
+
+func TestFixtureSpotMarketData(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+
+	market, err := client.GetMarketData("HYPE/USDC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if market.CurrentPrice != 25.5 || market.Volume24h != 152034987.5 || !near(market.PriceChange, 2) {
+		t.Errorf("unexpected HYPE/USDC market data: %+v", market)
+	}
+}
+
+func TestFixtureSpotHoldings(t *testing.T) {
+	client := fixtureServer(t, "", nil).client
+	const user = "0x0000000000000000000000000000000000000001"
+
+	account, err := client.GetAccountState(user)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.AddSpotHoldings(account, user, []string{"ETH", "HYPE/USDC"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(account.Spot) != 3 || account.Spot[1].Coin != "PURR" || account.Spot[1].Available() != 800 {
+		t.Errorf("unexpected spot balances: %+v", account.Spot)
+	}
+
+	if account.BalanceFor("HYPE/USDC") != 1520.5 || account.BalanceFor("ETH") != account.Balance() {
+		t.Errorf("spot should be sized from USDC, perps from the account value: %v, %v",
+			account.BalanceFor("HYPE/USDC"), account.BalanceFor("ETH"))
+	}
+
+	hype := account.Position("HYPE/USDC")
+	if hype.Side != "LONG" || hype.Size != 12.5 || hype.EntryPrice != 24 || hype.Leverage != 1 || hype.MarginMode != "spot" ||
+		!near(hype.PositionValue, 318.75) || !near(hype.CurrentPnL, 18.75) {
+		t.Errorf("unexpected HYPE/USDC position: %+v", hype)
+	}
+	// PURR is held but not traded
+	if account.Position("PURR/USDC").Side != "NONE" {
+		t.Error("balances of symbols not traded should not be positions")
+	}
+	if len(account.OpenPositions()) != 3 {
+		t.Errorf("expected ETH, SOL and HYPE/USDC, got %+v", account.OpenPositions())
+	}
+}
+
+func TestSpotOrderWireFormat(t *testing.T) {
+	var actions []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path == "/info" {
+			body, _ := os.ReadFile(filepath.Join("testdata", "spotMeta.json"))
+			w.Write(body)
+			return
+		}
+		actions = append(actions, req["action"].(map[string]interface{}))
+		w.Write([]byte(`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":5}}]}}}`))
+	}))
+	defer server.Close()
+
+	trader, err := NewTrader(NewClient(server.URL), testKey, "0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := trader.ClosePosition("HYPE/USDC", "LONG", 12.5, 25.123); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order := actions[0]["orders"].([]interface{})[0].(map[string]interface{})
+	if order["a"] != float64(10107) || order["b"] != false || order["r"] != false || order["p"] != "25.123" || order["s"] != "12.5" {
+		t.Errorf("unexpected spot order: %v", order)
+	}
+
+	if _, err := trader.OpenShortPosition("HYPE/USDC", 1, 25); err == nil {
+		t.Error("shorting a spot pair should fail")
+	}
+	if len(actions) != 1 {
+		t.Errorf("a spot short should not reach the exchange, got %d actions", len(actions))
+	}
+}
@@ -2,14 +2,12 @@ package hyperliquid
 
 import (
 	"crypto/ecdsa"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"math/big"
-	"time"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // Trader handles trade execution on Hyperliquid
@@ -17,18 +15,46 @@ type Trader struct {
 	client     *Client
 	privateKey *ecdsa.PrivateKey
 	address    common.Address
+
+	vault *common.Address // Vault or sub-account traded on behalf of; nil trades the key's own account
+
+	mu      sync.Mutex
+	tracked map[string]OrderSpec // Resting orders placed by this trader, by oid and cloid
 }
 
-// NewTrader creates a new trader instance
-func NewTrader(client *Client, privateKeyHex, accountAddress string) (*Trader, error) {
-	// Remove 0x prefix if present
-	if len(privateKeyHex) > 2 && privateKeyHex[:2] == "0x" {
-		privateKeyHex = privateKeyHex[2:]
+// SetVault makes the trader act for a vault or sub-account the key's account
+// controls: actions carry its vaultAddress and order queries use its address
+func (t *Trader) SetVault(vaultAddress string) error {
+	if !common.IsHexAddress(vaultAddress) {
+		return fmt.Errorf("invalid vault address %q", vaultAddress)
+	}
+	vault := common.HexToAddress(vaultAddress)
+	t.vault = &vault
+	return nil
+}
+
+// user returns the address whose orders and positions are traded
+func (t *Trader) user() string {
+	if t.vault != nil {
+		return t.vault.Hex()
 	}
+	return t.address.Hex()
+}
 
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+// vaultAddress returns the vaultAddress sent with actions, empty for none
+func (t *Trader) vaultAddress() string {
+	if t.vault == nil {
+		return ""
+	}
+	return strings.ToLower(t.vault.Hex())
+}
+
+// NewTrader creates a new trader instance. accountAddress is the master
+// account traded; privateKeyHex is its key or the key of an approved API agent.
+func NewTrader(client *Client, privateKeyHex, accountAddress string) (*Trader, error) {
+	privateKey, err := parsePrivateKey(privateKeyHex)
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key: %w", err)
+		return nil, err
 	}
 
 	address := common.HexToAddress(accountAddress)
@@ -50,266 +76,157 @@ const (
 
 // OrderType represents order type
 type OrderType struct {
-	Limit *LimitOrderType `json:"limit,omitempty"`
+	Limit   *LimitOrderType   `json:"limit,omitempty"`
+	Trigger *TriggerOrderType `json:"trigger,omitempty"`
 }
 
 type LimitOrderType struct {
 	Tif string `json:"tif"` // Time in force: "Gtc", "Ioc", "Alo"
 }
 
+// TriggerOrderType makes an order a stop loss or take profit that activates
+// when the mark price crosses TriggerPx. Fields are in the exchange's key
+// order, which the signature commits to.
+type TriggerOrderType struct {
+	IsMarket  bool   `json:"isMarket"`
+	TriggerPx string `json:"triggerPx"`
+	Tpsl      string `json:"tpsl"` // "tp" or "sl"
+}
+
+// Time in force values
+const (
+	TifGtc = "Gtc" // Rest on the book until filled or cancelled
+	TifIoc = "Ioc" // Fill what crosses immediately, cancel the rest
+	TifAlo = "Alo" // Add liquidity only; rejected if it would cross
+)
+
 // PlaceOrderRequest represents order placement request
 type PlaceOrderRequest struct {
-	Asset      int       `json:"a"`      // Asset index
-	IsBuy      bool      `json:"b"`      // Buy (true) or Sell (false)
-	Price      string    `json:"p"`      // Price
-	Size       string    `json:"s"`      // Size
-	ReduceOnly bool      `json:"r"`      // Reduce only
-	OrderType  OrderType `json:"t"`      // Order type
+	Asset      int       `json:"a"`           // Asset index
+	IsBuy      bool      `json:"b"`           // Buy (true) or Sell (false)
+	Price      string    `json:"p"`           // Price
+	Size       string    `json:"s"`           // Size
+	ReduceOnly bool      `json:"r"`           // Reduce only
+	OrderType  OrderType `json:"t"`           // Order type
+	Cloid      string    `json:"c,omitempty"` // Client order id
 }
 
 // OrderResult represents order execution result
 type OrderResult struct {
-	Success bool
-	OrderID string
-	Message string
+	Success    bool
+	OrderID    string
+	Message    string
+	FilledSize float64 // Size filled immediately
+	AvgPrice   float64 // Average fill price, zero when nothing filled
+	Cloid      string  // Client order id the order was sent with
+	Resting    bool    // The order rests on the book
 }
 
 // OpenLongPosition opens a long position
 func (t *Trader) OpenLongPosition(symbol string, size float64, price float64) (*OrderResult, error) {
-	return t.placeOrder(symbol, true, size, price, false)
+	return t.PlaceOrder(symbol, true, size, price, false, TifGtc)
 }
 
-// OpenShortPosition opens a short position
+// OpenShortPosition opens a short position. Spot symbols cannot be shorted.
 func (t *Trader) OpenShortPosition(symbol string, size float64, price float64) (*OrderResult, error) {
-	return t.placeOrder(symbol, false, size, price, false)
+	if IsSpot(symbol) {
+		return nil, fmt.Errorf("cannot open a short position on spot market %s", symbol)
+	}
+	return t.PlaceOrder(symbol, false, size, price, false, TifGtc)
 }
 
 // ClosePosition closes an existing position
 func (t *Trader) ClosePosition(symbol string, side string, size float64, price float64) (*OrderResult, error) {
 	// For closing, we do the opposite of the position side
 	isBuy := side == "SHORT" // If position is short, we buy to close
-	return t.placeOrder(symbol, isBuy, size, price, true)
+	return t.PlaceOrder(symbol, isBuy, size, price, true, TifGtc)
 }
 
-// placeOrder places an order on Hyperliquid
-func (t *Trader) placeOrder(symbol string, isBuy bool, size float64, price float64, reduceOnly bool) (*OrderResult, error) {
-	// Get asset index for the symbol
-	assetIndex, err := t.getAssetIndex(symbol)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get asset index: %w", err)
-	}
-
-	// Format price and size strings (remove trailing zeros as required by Hyperliquid)
-	priceStr := formatPriceForAPI(price)
-	sizeStr := formatPriceForAPI(size)
-
-	// Create order request
-	order := PlaceOrderRequest{
-		Asset:  assetIndex,
-		IsBuy:  isBuy,
-		Price:  priceStr,
-		Size:   sizeStr,
-		OrderType: OrderType{
-			Limit: &LimitOrderType{
-				Tif: "Gtc", // Good till cancel
-			},
-		},
+// PlaceOrder places a limit order on Hyperliquid with the given time in force
+func (t *Trader) PlaceOrder(symbol string, isBuy bool, size float64, price float64, reduceOnly bool, tif string) (*OrderResult, error) {
+	results, err := t.PlaceOrders([]OrderSpec{{
+		Symbol:     symbol,
+		IsBuy:      isBuy,
+		Size:       size,
+		Price:      price,
 		ReduceOnly: reduceOnly,
-	}
-
-	// Create action payload
-	action := map[string]interface{}{
-		"type":     "order",
-		"orders":   []PlaceOrderRequest{order},
-		"grouping": "na",
-	}
-
-	// Sign the action
-	signature, err := t.signAction(action)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign order: %w", err)
-	}
-
-	// Create exchange request
-	payload := map[string]interface{}{
-		"action":    action,
-		"nonce":     time.Now().UnixMilli(),
-		"signature": signature,
-	}
-
-	// Send request
-	url := fmt.Sprintf("%s/exchange", t.client.baseURL)
-	respData, err := t.client.doRequest("POST", url, payload)
+		Tif:        tif,
+	}})
 	if err != nil {
 		return nil, err
 	}
-
-	// Parse response
-	result := &OrderResult{
-		Success: true,
-	}
-
-	if respMap, ok := respData.(map[string]interface{}); ok {
-		if status, ok := respMap["status"].(string); ok {
-			result.Success = status == "ok"
-			result.Message = status
-		}
-		if response, ok := respMap["response"].(map[string]interface{}); ok {
-			if data, ok := response["data"].(map[string]interface{}); ok {
-				if statuses, ok := data["statuses"].([]interface{}); ok && len(statuses) > 0 {
-					if statusMap, ok := statuses[0].(map[string]interface{}); ok {
-						if filled, ok := statusMap["filled"].(map[string]interface{}); ok {
-							if oid, ok := filled["oid"].(string); ok {
-								result.OrderID = oid
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	return result, nil
+	return results[0], nil
 }
 
-// getAssetIndex returns the asset index for a symbol
-func (t *Trader) getAssetIndex(symbol string) (int, error) {
-	// Fetch meta info to get asset indices
-	url := fmt.Sprintf("%s/info", t.client.baseURL)
-	req := map[string]interface{}{
-		"type": "meta",
+// parseOrderStatus fills result from one entry of an order response's
+// statuses: {"filled": {...}}, {"resting": {...}} or {"error": "..."}
+func parseOrderStatus(status ActionStatus, result *OrderResult) {
+	if status.Error != "" {
+		result.Success = false
+		result.Message = status.Error
+		return
 	}
 
-	respData, err := t.client.doRequest("POST", url, req)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch meta info: %w", err)
+	if status.Filled != nil {
+		result.OrderID = strconv.FormatInt(status.Filled.Oid, 10)
+		result.FilledSize = status.Filled.TotalSz.Float()
+		result.AvgPrice = status.Filled.AvgPx.Float()
+		return
 	}
 
-	// Parse meta response to find asset index
-	if metaMap, ok := respData.(map[string]interface{}); ok {
-		if universe, ok := metaMap["universe"].([]interface{}); ok {
-			for i, asset := range universe {
-				if assetMap, ok := asset.(map[string]interface{}); ok {
-					if name, ok := assetMap["name"].(string); ok && name == symbol {
-						return i, nil
-					}
-				}
-			}
-		}
-	}
-
-	return 0, fmt.Errorf("symbol %s not found in universe", symbol)
-}
-
-// formatPriceForAPI formats a float64 to string removing trailing zeros
-func formatPriceForAPI(value float64) string {
-	// Format with high precision
-	str := fmt.Sprintf("%.12f", value)
-	// Remove trailing zeros
-	for len(str) > 0 && str[len(str)-1] == '0' {
-		str = str[:len(str)-1]
-	}
-	// Remove trailing decimal point
-	if len(str) > 0 && str[len(str)-1] == '.' {
-		str = str[:len(str)-1]
+	if status.Resting != nil {
+		result.OrderID = strconv.FormatInt(status.Resting.Oid, 10)
+		result.Resting = true
 	}
-	return str
 }
 
-// CancelOrder cancels an existing order
+// CancelOrder cancels an existing order by exchange order id, or by client
+// order id when orderID is a 0x-prefixed cloid
 func (t *Trader) CancelOrder(symbol string, orderID string) error {
-	action := map[string]interface{}{
-		"type": "cancel",
-		"cancels": []map[string]interface{}{
-			{
-				"asset": symbol,
-				"oid":   orderID,
-			},
-		},
+	var err error
+	if strings.HasPrefix(orderID, "0x") {
+		err = t.CancelByCloid([]CloidCancel{{Symbol: symbol, Cloid: orderID}})
+	} else {
+		err = t.CancelOrders([]OrderCancel{{Symbol: symbol, OrderID: orderID}})
 	}
-
-	signature, err := t.signAction(action)
-	if err != nil {
-		return fmt.Errorf("failed to sign cancel: %w", err)
+	if err == nil {
+		t.untrack(orderID)
 	}
-
-	payload := map[string]interface{}{
-		"action":    action,
-		"nonce":     time.Now().UnixMilli(),
-		"signature": signature,
-	}
-
-	url := fmt.Sprintf("%s/exchange", t.client.baseURL)
-	_, err = t.client.doRequest("POST", url, payload)
 	return err
 }
 
-// signAction signs an action using the private key
-func (t *Trader) signAction(action interface{}) (map[string]interface{}, error) {
-	// Convert action to canonical JSON
-	actionBytes, err := json.Marshal(action)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create message hash
-	// The actual Hyperliquid signing mechanism would be more complex
-	// This is a simplified version
-	hash := crypto.Keccak256Hash(actionBytes)
-
-	// Sign the hash
-	signature, err := crypto.Sign(hash.Bytes(), t.privateKey)
-	if err != nil {
-		return nil, err
-	}
-
-	// Adjust V value (Ethereum compatibility)
-	if signature[64] < 27 {
-		signature[64] += 27
-	}
-
-	// Split signature into r, s, v
-	r := new(big.Int).SetBytes(signature[:32])
-	s := new(big.Int).SetBytes(signature[32:64])
-	v := signature[64]
-
-	return map[string]interface{}{
-		"r": fmt.Sprintf("0x%s", hex.EncodeToString(r.Bytes())),
-		"s": fmt.Sprintf("0x%s", hex.EncodeToString(s.Bytes())),
-		"v": int(v),
-	}, nil
-}
-
-// GetOpenOrders fetches open orders for a symbol
-func (t *Trader) GetOpenOrders(symbol string) ([]interface{}, error) {
+// GetOpenOrders fetches open orders for a symbol, or for every symbol when
+// symbol is empty
+func (t *Trader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
 	url := fmt.Sprintf("%s/info", t.client.baseURL)
 
-	req := map[string]interface{}{
-		"type": "openOrders",
-		"user": t.address.Hex(),
+	req := InfoRequest{
+		Type: "openOrders",
+		User: t.user(),
 	}
 
-	respData, err := t.client.doRequest("POST", url, req)
-	if err != nil {
+	var orders []OpenOrder
+	if err := t.client.doRequest("POST", url, req, &orders); err != nil {
 		return nil, err
 	}
 
-	if orders, ok := respData.([]interface{}); ok {
+	if symbol == "" {
 		return orders, nil
 	}
-
-	return []interface{}{}, nil
+	coin := t.client.infoCoin(symbol)
+	filtered := make([]OpenOrder, 0, len(orders))
+	for _, order := range orders {
+		if order.Coin == coin {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered, nil
 }
 
-// ModifyOrder modifies an existing order
+// ModifyOrder atomically changes the price and size of a resting order,
+// keeping its side, reduce-only flag and order type. A zero size keeps the
+// current size. It is sent as a batch of one, whose reply carries the oid the
+// modified order now rests under.
 func (t *Trader) ModifyOrder(symbol string, orderID string, newPrice float64, newSize float64) error {
-	// Cancel old order
-	if err := t.CancelOrder(symbol, orderID); err != nil {
-		return err
-	}
-
-	// Place new order
-	// Note: You'd need to track whether the original order was buy or sell
-	// This is simplified
-	return nil
+	return t.ModifyOrders([]OrderModify{{Symbol: symbol, OrderID: orderID, Price: newPrice, Size: newSize}})
 }
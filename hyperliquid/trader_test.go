@@ -0,0 +1,68 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVaultTrading(t *testing.T) {
+	const vault = "0x00000000000000000000000000000000000000AA"
+
+	url, requests := captureServer(t, func(path string, req map[string]interface{}) string {
+		switch {
+		case path == "/exchange":
+			return `{"status":"ok","response":{"type":"cancel","data":{"statuses":["success"]}}}`
+		case req["type"] == "openOrders":
+			return `[]`
+		default:
+			return metaReply
+		}
+	})
+
+	newTrader := func() *Trader {
+		trader, err := NewTrader(NewClient(url), testKey, "0x0000000000000000000000000000000000000001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trader
+	}
+
+	own := newTrader()
+	if err := own.CancelOrder("ETH", "12"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := (*requests)[len(*requests)-1]["vaultAddress"]; ok {
+		t.Error("actions for the key's own account should not carry a vaultAddress")
+	}
+	ownSignature := (*requests)[len(*requests)-1]["signature"]
+
+	trader := newTrader()
+	if err := trader.SetVault("not an address"); err == nil {
+		t.Error("an invalid vault address should be rejected")
+	}
+	if err := trader.SetVault(vault); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := trader.CancelOrder("ETH", "12"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last := (*requests)[len(*requests)-1]
+	if last["vaultAddress"] != "0x00000000000000000000000000000000000000aa" {
+		t.Errorf("vault actions should carry the lowercase vaultAddress, got %v", last["vaultAddress"])
+	}
+	if signature := last["signature"]; signature == nil || jsonString(signature) == jsonString(ownSignature) {
+		t.Error("the signature should commit to the vault address")
+	}
+
+	if _, err := trader.GetOpenOrders("ETH"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user := (*requests)[len(*requests)-1]["user"]; user != vault {
+		t.Errorf("order queries should use the vault address, got %v", user)
+	}
+}
+
+func jsonString(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -35,6 +35,7 @@ type TechnicalIndicators struct {
 	BBMiddle float64
 	BBLower  float64
 	BBWidth  float64
+	ATR14    float64
 
 	// Volume Indicators
 	VMA20         float64
@@ -97,6 +98,9 @@ func (c *Calculator) Calculate(data []MarketData) *TechnicalIndicators {
 	indicators.BBUpper, indicators.BBMiddle, indicators.BBLower = c.BollingerBands(closes, 20, 2)
 	indicators.BBWidth = (indicators.BBUpper - indicators.BBLower) / indicators.BBMiddle
 
+	// Calculate ATR
+	indicators.ATR14 = c.ATR(data, 14)
+
 	// Calculate Volume MA
 	indicators.VMA20 = c.SMA(volumes, 20)
 
@@ -249,6 +253,27 @@ func (c *Calculator) BollingerBands(data []float64, period int, stdDev float64)
 	return upper, middle, lower
 }
 
+// ATR calculates Average True Range with Wilder's smoothing
+func (c *Calculator) ATR(data []MarketData, period int) float64 {
+	if len(data) < period+1 {
+		return 0
+	}
+
+	trueRanges := make([]float64, len(data)-1)
+	for i := 1; i < len(data); i++ {
+		prevClose := data[i-1].Close
+		trueRanges[i-1] = math.Max(data[i].High-data[i].Low,
+			math.Max(math.Abs(data[i].High-prevClose), math.Abs(data[i].Low-prevClose)))
+	}
+
+	atr := c.SMA(trueRanges[:period], period)
+	for i := period; i < len(trueRanges); i++ {
+		atr = (atr*float64(period-1) + trueRanges[i]) / float64(period)
+	}
+
+	return atr
+}
+
 // analyzeTrend determines trend strength
 func (c *Calculator) analyzeTrend(ind *TechnicalIndicators, currentPrice float64) string {
 	bullishSignals := 0
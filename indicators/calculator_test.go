@@ -67,6 +67,23 @@ func TestBollingerBands(t *testing.T) {
 	}
 }
 
+func TestATR(t *testing.T) {
+	calc := NewCalculator()
+	data := make([]MarketData, 30)
+	for i := range data {
+		data[i] = MarketData{High: 102, Low: 98, Close: 100}
+	}
+
+	result := calc.ATR(data, 14)
+	if math.Abs(result-4) > 0.0001 {
+		t.Errorf("ATR calculation failed: got %f, expected 4", result)
+	}
+
+	if calc.ATR(data[:10], 14) != 0 {
+		t.Error("ATR should be 0 with insufficient data")
+	}
+}
+
 func TestCalculateWithInsufficientData(t *testing.T) {
 	calc := NewCalculator()
 	// Only 50 candles, need 120
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"strings"
@@ -14,29 +16,120 @@ import (
 	"aitrading/hyperliquid"
 	"aitrading/indicators"
 	"aitrading/risk"
+	"aitrading/strategy"
 
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
+// stopLossReason marks closes triggered by the stop loss, which start the
+// longer stop-out cooldown
+const stopLossReason = "Stop loss triggered"
+
 // TradingBot represents the main trading bot
 type TradingBot struct {
-	config         *config.Config
-	logger         *logrus.Logger
-	hlClient       *hyperliquid.Client
-	hlTrader       *hyperliquid.Trader
-	aiDecision     *ai.DecisionMaker
-	riskControl    *risk.Controller
-	executor       *executor.Executor
-	calculator     *indicators.Calculator
-	scheduler      *cron.Cron
-	lastStopLoss   float64
-	lastTakeProfit float64
-}
-
-// NewTradingBot creates a new trading bot instance
+	config       *config.Config
+	logger       *logrus.Logger
+	hlClient     *hyperliquid.Client
+	hlTrader     *hyperliquid.Trader
+	aiDecision   *ai.DecisionMaker
+	riskControl  *risk.Controller
+	executor     *executor.Executor
+	calculator   *indicators.Calculator
+	strategy     *strategy.Engine
+	scheduler    *cron.Cron
+	protective   map[string]protectiveLevels      // Stop loss and take profit of each symbol's open position
+	pendingOpens map[string]*hyperliquid.Position // Opens approved earlier in a portfolio cycle
+	account      *hyperliquid.AccountState        // Latest account snapshot, refreshed each cycle and after trades
+
+	name     string        // Account name when several accounts are traded
+	accounts []*TradingBot // One bot per hyperliquid.accounts entry, each on its own schedule
+}
+
+// protectiveLevels are the stop loss and take profit set when a position was
+// opened, or moved since; zero leaves the risk controller's default
+type protectiveLevels struct {
+	StopLoss   float64
+	TakeProfit float64
+	Orders     executor.ProtectiveOrders // Exchange trigger orders at the levels, when placed
+}
+
+// NewTradingBot creates a new trading bot instance. With hyperliquid.accounts
+// configured it creates one bot per account; they share the AI budget and
+// run concurrently.
 func NewTradingBot(cfg *config.Config) (*TradingBot, error) {
-	// Setup logger
+	logger := newLogger(cfg)
+	if len(cfg.Hyperliquid.Accounts) == 0 {
+		return newAccountBot(cfg, logger, nil)
+	}
+
+	accounts, err := cfg.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	costTracker := newCostTracker(&cfg.AI)
+
+	bot := &TradingBot{config: cfg, logger: logger}
+	for _, accountCfg := range accounts {
+		accountBot, err := newAccountBot(accountCfg, accountLogger(logger, accountCfg.Account), costTracker)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", accountCfg.Account, err)
+		}
+		bot.accounts = append(bot.accounts, accountBot)
+	}
+	return bot, nil
+}
+
+// checkSigningKey refuses to trade with the master key, which can also
+// withdraw funds, unless hyperliquid.allow_master_key is set. It runs before
+// any key is resolved, so a refused master key is never decrypted or
+// prompted for.
+func checkSigningKey(cfg *config.HyperliquidConfig) error {
+	if cfg.AllowMasterKey || cfg.AgentKey != "" {
+		return nil
+	}
+	return fmt.Errorf("refusing to trade with the master private key: approve an API agent with './aitrading approve-agent' and set hyperliquid.agent_key, or set hyperliquid.allow_master_key")
+}
+
+// checkAgentKey rejects an agent_key that resolved to the master key
+func checkAgentKey(cfg *config.HyperliquidConfig, trader *hyperliquid.Trader) error {
+	if cfg.AllowMasterKey || cfg.AgentKey == "" {
+		return nil
+	}
+	if strings.EqualFold(trader.SignerAddress(), cfg.AccountAddress) {
+		return fmt.Errorf("hyperliquid.agent_key is the master key of %s, not an API agent key", cfg.AccountAddress)
+	}
+	return nil
+}
+
+// newHyperliquidClient connects to the network hyperliquid.testnet selects,
+// at hyperliquid.api_url when set
+func newHyperliquidClient(cfg *config.HyperliquidConfig) (*hyperliquid.Client, error) {
+	network := hyperliquid.NetworkFor(cfg.Testnet)
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = network.APIURL
+	} else if !network.Serves(apiURL) {
+		return nil, fmt.Errorf("hyperliquid.api_url %s is not a %s endpoint: fix api_url or hyperliquid.testnet", apiURL, network.Name)
+	}
+
+	client := hyperliquid.NewClient(apiURL)
+	client.SetNetwork(network)
+	return client, nil
+}
+
+// checkNetwork refuses to trade real funds on mainnet unless
+// hyperliquid.confirm_mainnet acknowledges it. Simulated trading and testnet
+// need no confirmation.
+func checkNetwork(cfg *config.Config) error {
+	if !cfg.Trading.TradingEnabled || cfg.Hyperliquid.Testnet || cfg.Hyperliquid.ConfirmMainnet {
+		return nil
+	}
+	return fmt.Errorf("refusing to trade on mainnet: set hyperliquid.testnet to trial on testnet, or hyperliquid.confirm_mainnet to trade real funds")
+}
+
+// newLogger creates the logger configured under monitoring
+func newLogger(cfg *config.Config) *logrus.Logger {
 	logger := logrus.New()
 	logger.SetLevel(getLogLevel(cfg.Monitoring.LogLevel))
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -52,29 +145,109 @@ func NewTradingBot(cfg *config.Config) (*TradingBot, error) {
 			logger.Warnf("Failed to open log file: %v", err)
 		}
 	}
+	logger.AddHook(redactSecrets{cfg.Redactor()})
+	return logger
+}
+
+// redactSecrets masks every key the config holds, inline or once resolved,
+// in log messages and fields
+type redactSecrets struct {
+	redactor *config.Redactor
+}
+
+func (r redactSecrets) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (r redactSecrets) Fire(entry *logrus.Entry) error {
+	secrets := r.redactor.Secrets()
+	if len(secrets) == 0 {
+		return nil
+	}
+	entry.Message = config.Redact(entry.Message, secrets)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = config.Redact(v, secrets)
+		case error:
+			if redacted := config.Redact(v.Error(), secrets); redacted != v.Error() {
+				entry.Data[key] = redacted
+			}
+		}
+	}
+	return nil
+}
+
+// accountField tags every entry of an account's logger with the account name
+type accountField string
 
-	// Initialize Hyperliquid client
-	hlClient := hyperliquid.NewClient(cfg.Hyperliquid.APIURL)
+func (a accountField) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (a accountField) Fire(entry *logrus.Entry) error {
+	entry.Data["account"] = string(a)
+	return nil
+}
+
+// accountLogger returns a logger writing where base does, with its hooks,
+// tagged with name
+func accountLogger(base *logrus.Logger, name string) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(base.Out)
+	logger.SetFormatter(base.Formatter)
+	logger.SetLevel(base.GetLevel())
+	for level, hooks := range base.Hooks {
+		logger.Hooks[level] = append([]logrus.Hook(nil), hooks...)
+	}
+	logger.AddHook(accountField(name))
+	return logger
+}
+
+// newCostTracker tracks token spend against the daily AI budget
+func newCostTracker(cfg *config.AIConfig) *ai.CostTracker {
+	pricing := make(map[string]ai.ModelPrice)
+	for model, price := range cfg.Budget.Pricing {
+		pricing[model] = ai.ModelPrice{Input: price.Input, Output: price.Output}
+	}
+	return ai.NewCostTracker(pricing, cfg.Budget.DailyLimit, cfg.Budget.HardLimit, cfg.Budget.StateFile)
+}
 
-	// Initialize Hyperliquid trader
-	hlTrader, err := hyperliquid.NewTrader(hlClient, cfg.Hyperliquid.PrivateKey, cfg.Hyperliquid.AccountAddress)
+// newAccountBot creates the bot trading one account. costTracker is shared
+// between accounts; nil creates one.
+func newAccountBot(cfg *config.Config, logger *logrus.Logger, costTracker *ai.CostTracker) (*TradingBot, error) {
+	if err := checkNetwork(cfg); err != nil {
+		return nil, err
+	}
+
+	// Initialize Hyperliquid client on the configured network
+	hlClient, err := newHyperliquidClient(&cfg.Hyperliquid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize Hyperliquid trader, acting for the vault or sub-account if any
+	if err := checkSigningKey(&cfg.Hyperliquid); err != nil {
+		return nil, err
+	}
+	signingKey, err := cfg.Hyperliquid.SigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing key: %w", err)
+	}
+	hlTrader, err := hyperliquid.NewTrader(hlClient, signingKey, cfg.Hyperliquid.AccountAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create trader: %w", err)
 	}
+	if err := checkAgentKey(&cfg.Hyperliquid, hlTrader); err != nil {
+		return nil, err
+	}
+	if cfg.Hyperliquid.VaultAddress != "" {
+		if err := hlTrader.SetVault(cfg.Hyperliquid.VaultAddress); err != nil {
+			return nil, fmt.Errorf("failed to create trader: %w", err)
+		}
+	}
 
 	// Initialize AI decision maker
-	var aiAPIKey, aiBaseURL, aiModel string
-	switch cfg.AI.Provider {
-	case "qwen":
-		aiAPIKey = cfg.AI.Qwen.APIKey
-		aiBaseURL = cfg.AI.Qwen.BaseURL
-		aiModel = cfg.AI.Qwen.Model
-	default: // "deepseek" or any other
-		aiAPIKey = cfg.AI.APIKey
-		aiBaseURL = cfg.AI.BaseURL
-		aiModel = cfg.AI.Model
+	aiAPIKey, aiBaseURL, aiModel, err := aiProviderSettings(&cfg.AI, cfg.AI.Provider)
+	if err != nil {
+		return nil, err
 	}
-
 	aiDecision := ai.NewDecisionMaker(
 		cfg.AI.Provider,
 		aiAPIKey,
@@ -85,15 +258,53 @@ func NewTradingBot(cfg *config.Config) (*TradingBot, error) {
 		cfg.AI.Timeout,
 	)
 
-	// Initialize risk controller
+	// Fail over to a second provider when the primary is down
+	if cfg.AI.FallbackProvider != "" && cfg.AI.FallbackProvider != cfg.AI.Provider {
+		fbAPIKey, fbBaseURL, fbModel, err := aiProviderSettings(&cfg.AI, cfg.AI.FallbackProvider)
+		if err != nil {
+			return nil, err
+		}
+		aiDecision.AddFallback(cfg.AI.FallbackProvider, fbAPIKey, fbBaseURL, fbModel)
+	}
+	aiDecision.SetRetryPolicy(
+		cfg.System.MaxRetries,
+		time.Duration(cfg.System.RetryDelay)*time.Second,
+		time.Duration(cfg.System.MaxRetryDelay)*time.Second,
+	)
+	aiDecision.SetCircuitBreaker(
+		cfg.AI.CircuitBreaker.FailureThreshold,
+		time.Duration(cfg.AI.CircuitBreaker.Cooldown)*time.Second,
+	)
+
+	// Track token spend and enforce the daily AI budget
+	if costTracker == nil {
+		costTracker = newCostTracker(&cfg.AI)
+	}
+	aiDecision.SetBudget(costTracker, cfg.AI.Budget.OnExceed, cfg.AI.Budget.DowngradeModel)
+
+	// Attach rolling decision memory so the model sees its recent calls
+	if cfg.AI.Memory.Enabled {
+		memory := ai.NewMemory(cfg.AI.Memory.Window, cfg.AI.Memory.MaxPromptChars)
+		aiDecision.SetMemory(memory, cfg.AI.Memory.AsChatTurns)
+	}
+
+	// Initialize risk controller and the kill switch it shares with the executor
 	riskControl := risk.NewController(&cfg.Risk, &cfg.Trading, logger)
+	killSwitch := risk.NewKillSwitch(cfg.Risk.KillSwitch, logger)
+	riskControl.SetKillSwitch(killSwitch)
 
 	// Initialize executor
-	exec := executor.NewExecutor(hlTrader, hlClient, cfg.Hyperliquid.AccountAddress, logger)
+	exec := executor.NewExecutor(hlTrader, hlClient, cfg.Hyperliquid.TradingAddress(), logger)
+	exec.SetKillSwitch(killSwitch)
+	exec.SetExecutionConfig(cfg.Execution)
+	exec.SetMarginMode(cfg.Risk.MarginMode)
 
 	// Initialize indicator calculator
 	calc := indicators.NewCalculator()
 
+	// Initialize rule-based strategy engine
+	strategyEngine := strategy.NewEngine(&cfg.Strategy)
+
 	// Initialize scheduler
 	scheduler := cron.New()
 
@@ -106,30 +317,74 @@ func NewTradingBot(cfg *config.Config) (*TradingBot, error) {
 		riskControl: riskControl,
 		executor:    exec,
 		calculator:  calc,
+		strategy:    strategyEngine,
 		scheduler:   scheduler,
+		protective:  make(map[string]protectiveLevels),
+		name:        cfg.Account,
 	}
 
 	return bot, nil
 }
 
-// Start starts the trading bot
+// aiProviderSettings returns the API key, base URL and model configured for a provider
+func aiProviderSettings(cfg *config.AIConfig, provider string) (apiKey, baseURL, model string, err error) {
+	secret, baseURL, model := cfg.APIKey, cfg.BaseURL, cfg.Model // "deepseek" or any other
+	if provider == "qwen" {
+		secret, baseURL, model = cfg.Qwen.APIKey, cfg.Qwen.BaseURL, cfg.Qwen.Model
+	}
+	if apiKey, err = secret.Resolve(); err != nil {
+		return "", "", "", fmt.Errorf("failed to load %s API key: %w", provider, err)
+	}
+	return apiKey, baseURL, model, nil
+}
+
+// Start starts the trading bot, or the bot of every account
 func (bot *TradingBot) Start() error {
+	if len(bot.accounts) > 0 {
+		for _, account := range bot.accounts {
+			if err := account.Start(); err != nil {
+				return fmt.Errorf("account %s: %w", account.name, err)
+			}
+		}
+		return nil
+	}
+
 	bot.logger.Info("Starting AI Trading Bot...")
 
+	// Load exchange margin tables for liquidation estimates
+	if tiers, err := bot.hlClient.GetMarginTiers(); err != nil {
+		bot.logger.WithError(err).Warn("Failed to load margin tiers, using conservative defaults")
+	} else {
+		bot.riskControl.SetMarginTiers(tiers)
+	}
+
 	// Add scheduled job based on interval
 	cronExpr := bot.intervalToCron(bot.config.Trading.Interval)
 	bot.logger.Infof("Scheduling trading cycle at: %s", cronExpr)
 
-	_, err := bot.scheduler.AddFunc(cronExpr, func() {
+	// Execution algos can outlast an interval, so a cycle still running skips
+	// the next one instead of overlapping it. The first cycle runs through the
+	// same job for the same reason.
+	cycle := cron.SkipIfStillRunning(cron.DefaultLogger)(cron.FuncJob(func() {
 		if err := bot.runTradingCycle(); err != nil {
 			bot.logger.WithError(err).Error("Trading cycle failed")
 		}
-	})
+	}))
+	_, err := bot.scheduler.AddJob(cronExpr, cycle)
 
 	if err != nil {
 		return fmt.Errorf("failed to schedule trading cycle: %w", err)
 	}
 
+	// Keep the dead man's switch armed while the bot is alive
+	if seconds := bot.config.Execution.DeadMansSwitch; seconds > 0 && bot.config.Trading.TradingEnabled {
+		bot.armDeadMansSwitch()
+		refresh := time.Duration(seconds) * time.Second / 3
+		if _, err := bot.scheduler.AddFunc(fmt.Sprintf("@every %s", refresh), bot.armDeadMansSwitch); err != nil {
+			return fmt.Errorf("failed to schedule dead man's switch: %w", err)
+		}
+	}
+
 	// Start scheduler
 	bot.scheduler.Start()
 
@@ -138,43 +393,388 @@ func (bot *TradingBot) Start() error {
 	// Run first cycle immediately
 	go func() {
 		time.Sleep(2 * time.Second)
-		if err := bot.runTradingCycle(); err != nil {
-			bot.logger.WithError(err).Error("Initial trading cycle failed")
-		}
+		cycle.Run()
 	}()
 
 	return nil
 }
 
+// armDeadMansSwitch pushes the exchange's scheduled cancel of all open orders,
+// stop loss and take profit triggers too, dead_mans_switch seconds into the
+// future
+func (bot *TradingBot) armDeadMansSwitch() {
+	at := time.Now().Add(time.Duration(bot.config.Execution.DeadMansSwitch) * time.Second)
+	if err := bot.hlTrader.ScheduleCancel(at); err != nil {
+		bot.logger.WithError(err).Warn("Failed to arm dead man's switch")
+	}
+}
+
 // runTradingCycle executes one complete trading cycle
 func (bot *TradingBot) runTradingCycle() error {
 	bot.logger.Info("========== Starting Trading Cycle ==========")
 	startTime := time.Now()
 
-	// Iterate through all configured symbols
-	for _, symbol := range bot.config.Trading.Symbols {
-		if err := bot.runTradingCycleForSymbol(symbol); err != nil {
-			bot.logger.WithError(err).WithField("symbol", symbol).Error("Trading cycle failed for symbol")
-			// Continue with other symbols even if one fails
-			continue
+	if !bot.checkTradingState() {
+		return nil
+	}
+	killSwitch := bot.riskControl.KillSwitch()
+
+	if bot.config.Trading.PortfolioMode {
+		if err := bot.runPortfolioCycle(); err != nil {
+			bot.logger.WithError(err).Error("Portfolio trading cycle failed")
+			killSwitch.RecordError(err)
+		}
+	} else {
+		// Iterate through all configured symbols
+		for _, symbol := range bot.config.Trading.Symbols {
+			if err := bot.runTradingCycleForSymbol(symbol); err != nil {
+				bot.logger.WithError(err).WithField("symbol", symbol).Error("Trading cycle failed for symbol")
+				killSwitch.RecordError(err)
+				// Continue with other symbols even if one fails
+				continue
+			}
 		}
 	}
 
+	bot.logAICost()
+
 	elapsed := time.Since(startTime)
 	bot.logger.WithField("elapsed", elapsed).Info("========== Trading Cycle Completed ==========")
 
 	return nil
 }
 
+// checkTradingState syncs the kill switch, trips it on account-level limits and
+// reports whether the cycle should run. HALTED skips the cycle and FLATTEN
+// closes every position instead.
+func (bot *TradingBot) checkTradingState() bool {
+	killSwitch := bot.riskControl.KillSwitch()
+	killSwitch.Sync()
+
+	if account, err := bot.refreshAccount(); err != nil {
+		bot.logger.WithError(err).Warn("Failed to fetch account state")
+	} else {
+		bot.syncFills(account)
+		bot.riskControl.UpdateAccount(account.Balance())
+	}
+
+	if err := killSwitch.SaveError(); err != nil {
+		bot.logger.WithError(err).Warn("Failed to persist trading state")
+	}
+
+	halt := killSwitch.State()
+	switch halt.State {
+	case risk.StateHalted:
+		bot.logger.WithFields(logrus.Fields{
+			"reason": halt.Reason,
+			"since":  halt.Since,
+		}).Warn("Trading halted, skipping cycle. Run './aitrading resume' to clear")
+		return false
+	case risk.StateFlatten:
+		bot.flattenPositions(halt.Reason)
+		return false
+	case risk.StateReduceOnly:
+		bot.logger.WithField("reason", halt.Reason).Warn("Trading is reduce-only, new entries will be rejected")
+	}
+	return true
+}
+
+// syncFills feeds the account's fills to the risk controller, however the
+// positions were closed: the daily PnL becomes today's realized PnL plus the
+// unrealized PnL of open positions, and closes start their symbol's cooldown
+func (bot *TradingBot) syncFills(account *hyperliquid.AccountState) {
+	fills, err := bot.hlClient.GetUserFills(bot.config.Hyperliquid.TradingAddress())
+	if err != nil {
+		bot.logger.WithError(err).Warn("Failed to fetch fills")
+		return
+	}
+	pnl := dailyPnL(fills, account.OpenPositions(), startOfDay(time.Now()))
+	bot.riskControl.UpdatePnL(pnl - bot.riskControl.GetDailyPnL())
+
+	for _, symbol := range bot.config.Trading.Symbols {
+		coin := symbol
+		if hyperliquid.IsSpot(symbol) {
+			meta, err := bot.hlClient.AssetMeta(symbol)
+			if err != nil {
+				bot.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to resolve spot coin for fills")
+				continue
+			}
+			if meta.Coin != "" {
+				coin = meta.Coin
+			}
+		}
+		bot.riskControl.RecordFills(symbol, coin, fills)
+	}
+}
+
+// dailyPnL sums the PnL realized by fills since start and the unrealized PnL
+// of positions
+func dailyPnL(fills []hyperliquid.Fill, positions []*hyperliquid.Position, start time.Time) float64 {
+	pnl := 0.0
+	for _, fill := range fills {
+		if !fill.Time.Before(start) {
+			pnl += fill.ClosedPnL
+		}
+	}
+	for _, position := range positions {
+		pnl += position.CurrentPnL
+	}
+	return pnl
+}
+
+// startOfDay returns local midnight of t's day, when the daily PnL resets
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// flattenPositions closes every open position and halts once the exchange
+// shows all of them flat
+func (bot *TradingBot) flattenPositions(reason string) {
+	bot.logger.WithField("reason", reason).Warn("Flattening all positions")
+
+	account, err := bot.accountState()
+	if err != nil {
+		bot.logger.WithError(err).Error("Failed to get account state for flatten")
+		return
+	}
+	balance := account.Balance()
+
+	flat := true
+	for _, position := range account.OpenPositions() {
+		symbol := position.Symbol
+
+		if !bot.config.Trading.TradingEnabled {
+			bot.logger.WithField("symbol", symbol).Warn("Simulation mode - would close position to flatten")
+			continue
+		}
+
+		marketInfo, err := bot.hlClient.GetMarketData(symbol)
+		if err != nil {
+			bot.logger.WithError(err).WithField("symbol", symbol).Error("Failed to fetch market data for flatten")
+			flat = false
+			continue
+		}
+
+		// Crossing the book, so a close never rests unfilled at the mid
+		decision := &ai.Decision{
+			Action:        "CLOSE_POSITION",
+			Reason:        "kill switch flatten: " + reason,
+			ExecutionAlgo: executor.AlgoMarket,
+		}
+		result, err := bot.executor.Execute(symbol, decision, marketInfo.CurrentPrice, balance)
+		if err != nil || !result.Success {
+			bot.logger.WithError(err).WithField("symbol", symbol).Error("Failed to close position for flatten")
+			flat = false
+			continue
+		}
+		bot.rememberExecution(symbol, true, "强制平仓: "+reason)
+	}
+
+	if !flat {
+		return
+	}
+	// An IOC may fill only in part; halt only once the exchange shows every
+	// position closed, otherwise the next cycle flattens again
+	if bot.config.Trading.TradingEnabled {
+		account, err := bot.refreshAccount()
+		if err != nil {
+			bot.logger.WithError(err).Error("Failed to confirm positions are flat")
+			return
+		}
+		if open := account.OpenPositions(); len(open) > 0 {
+			bot.logger.WithField("open_positions", len(open)).Warn("Positions still open after flatten, retrying next cycle")
+			return
+		}
+	}
+	bot.riskControl.KillSwitch().Set(risk.StateHalted, "flatten", "all positions closed after: "+reason)
+}
+
+// refreshAccount fetches positions and margin in one round trip and keeps the
+// snapshot for the rest of the cycle. Spot balances are added when spot
+// symbols are traded.
+func (bot *TradingBot) refreshAccount() (*hyperliquid.AccountState, error) {
+	address := bot.config.Hyperliquid.TradingAddress()
+	account, err := bot.hlClient.GetAccountState(address)
+	if err != nil {
+		bot.account = nil
+		return nil, err
+	}
+	if bot.config.Trading.HasSpot() {
+		if err := bot.hlClient.AddSpotHoldings(account, address, bot.config.Trading.Symbols); err != nil {
+			bot.account = nil
+			return nil, err
+		}
+	}
+	bot.account = account
+	return account, nil
+}
+
+// accountState returns the current account snapshot, fetching one when there
+// is none
+func (bot *TradingBot) accountState() (*hyperliquid.AccountState, error) {
+	if bot.account != nil {
+		return bot.account, nil
+	}
+	return bot.refreshAccount()
+}
+
+// logAICost logs today's AI token spend in total and per symbol
+func (bot *TradingBot) logAICost() {
+	budget := bot.aiDecision.Budget()
+	if budget == nil {
+		return
+	}
+
+	report := budget.Report()
+	fields := logrus.Fields{
+		"date":       report.Date,
+		"calls":      report.Total.Calls,
+		"tokens":     report.Total.PromptTokens + report.Total.CompletionTokens,
+		"daily_cost": fmt.Sprintf("%.4f", report.Total.Cost),
+	}
+	for symbol, totals := range report.BySymbol {
+		fields["cost_"+symbol] = fmt.Sprintf("%.4f", totals.Cost)
+	}
+	entry := bot.logger.WithFields(fields)
+
+	if err := budget.SaveError(); err != nil {
+		bot.logger.WithError(err).Warn("Failed to persist AI cost totals")
+	}
+
+	if budget.Exceeded() {
+		entry.Warnf("AI daily budget exceeded, degrading to %s", bot.config.AI.Budget.OnExceed)
+	} else {
+		entry.Info("AI cost")
+	}
+}
+
 // runTradingCycleForSymbol executes trading cycle for a specific symbol
 func (bot *TradingBot) runTradingCycleForSymbol(symbol string) error {
 	bot.logger.WithField("symbol", symbol).Info("Processing symbol...")
 
+	analysis, err := bot.prepareAnalysis(symbol)
+	if err != nil {
+		return err
+	}
+	marketInfo, indicators, position := analysis.Market, analysis.Indicators, analysis.Position
+
+	// Step 5: Check stop loss and take profit
+	if handled, err := bot.checkProtectiveExits(symbol, marketInfo, position); handled {
+		return err
+	}
+
+	// Step 6: AI analysis
+	bot.logger.Info("Step 6: Requesting AI decision...")
+	decision, err := bot.decide(analysis)
+	if err != nil {
+		return err
+	}
+
+	bot.logger.WithFields(logrus.Fields{
+		"action":     decision.Action,
+		"confidence": decision.Confidence,
+		"reason":     decision.Reason,
+		"provider":   bot.aiDecision.LastProvider(),
+	}).Info("AI decision received")
+
+	// Print decision report to console
+	bot.printDecisionReport(symbol, marketInfo, indicators, position, decision)
+
+	// Step 7: Execute decision
+	if err := bot.executeDecision(decision, marketInfo, position, symbol); err != nil {
+		return fmt.Errorf("execution failed: %w", err)
+	}
+
+	return nil
+}
+
+// runPortfolioCycle asks the AI once for all symbols and applies the ranked decisions in order
+func (bot *TradingBot) runPortfolioCycle() error {
+	portfolio := &ai.PortfolioAnalysis{
+		Timestamp:        time.Now(),
+		MaxOpenPositions: bot.config.Trading.MaxOpenPositions,
+	}
+
+	for _, symbol := range bot.config.Trading.Symbols {
+		bot.logger.WithField("symbol", symbol).Info("Processing symbol...")
+
+		analysis, err := bot.prepareAnalysis(symbol)
+		if err != nil {
+			bot.logger.WithError(err).WithField("symbol", symbol).Error("Failed to prepare symbol for portfolio analysis")
+			continue
+		}
+
+		if analysis.Position.Size > 0 {
+			portfolio.OpenPositions++
+			portfolio.TotalExposure += analysis.Position.Size * analysis.Market.CurrentPrice
+		}
+
+		// Protective exits bypass the AI and free the symbol for this cycle
+		if handled, err := bot.checkProtectiveExits(symbol, analysis.Market, analysis.Position); handled {
+			if err != nil {
+				bot.logger.WithError(err).WithField("symbol", symbol).Error("Protective exit failed")
+			}
+			continue
+		}
+
+		portfolio.Symbols = append(portfolio.Symbols, analysis)
+	}
+
+	if len(portfolio.Symbols) == 0 {
+		return nil
+	}
+
+	// Protective exits above may have traded
+	account, err := bot.refreshAccount()
+	if err != nil {
+		return fmt.Errorf("failed to get account state: %w", err)
+	}
+	portfolio.AccountBalance = account.Balance()
+
+	bot.logger.WithField("symbols", len(portfolio.Symbols)).Info("Requesting AI portfolio decision...")
+	decisions, err := bot.decidePortfolio(portfolio)
+	if err != nil {
+		return err
+	}
+
+	analyses := make(map[string]*ai.MarketAnalysis)
+	for _, analysis := range portfolio.Symbols {
+		analyses[analysis.Symbol] = analysis
+	}
+
+	// Apply in rank order so earlier opens consume the position budget first
+	bot.pendingOpens = make(map[string]*hyperliquid.Position)
+	defer func() { bot.pendingOpens = nil }()
+
+	for _, d := range decisions {
+		analysis := analyses[d.Symbol]
+		decision := d.Decision
+
+		bot.logger.WithFields(logrus.Fields{
+			"symbol":     d.Symbol,
+			"rank":       d.Rank,
+			"action":     decision.Action,
+			"confidence": decision.Confidence,
+		}).Info("AI portfolio decision received")
+
+		bot.printDecisionReport(d.Symbol, analysis.Market, analysis.Indicators, analysis.Position, &decision)
+
+		if err := bot.executeDecision(&decision, analysis.Market, analysis.Position, d.Symbol); err != nil {
+			bot.logger.WithError(err).WithField("symbol", d.Symbol).Error("Execution failed")
+		}
+	}
+
+	return nil
+}
+
+// prepareAnalysis fetches market data, candles, indicators and position for a symbol
+func (bot *TradingBot) prepareAnalysis(symbol string) (*ai.MarketAnalysis, error) {
 	// Step 1: Fetch market data
 	bot.logger.Info("Step 1: Fetching market data...")
 	marketInfo, err := bot.hlClient.GetMarketData(symbol)
 	if err != nil {
-		return fmt.Errorf("failed to fetch market data: %w", err)
+		return nil, fmt.Errorf("failed to fetch market data: %w", err)
 	}
 
 	bot.logger.WithFields(logrus.Fields{
@@ -187,20 +787,21 @@ func (bot *TradingBot) runTradingCycleForSymbol(symbol string) error {
 	bot.logger.Info("Step 2: Fetching candlestick data...")
 	candles, err := bot.hlClient.GetCandlestickData(symbol, bot.config.Trading.Timeframe, 150)
 	if err != nil {
-		return fmt.Errorf("failed to fetch candlestick data: %w", err)
+		return nil, fmt.Errorf("failed to fetch candlestick data: %w", err)
 	}
 
 	if len(candles) < 120 {
-		return fmt.Errorf("insufficient candle data: got %d, need at least 120", len(candles))
+		return nil, fmt.Errorf("insufficient candle data: got %d, need at least 120", len(candles))
 	}
 
 	bot.logger.Infof("Fetched %d candles", len(candles))
+	bot.riskControl.RecordCandles(symbol, candles)
 
 	// Step 3: Calculate technical indicators
 	bot.logger.Info("Step 3: Calculating technical indicators...")
 	indicators := bot.calculator.Calculate(candles)
 	if indicators == nil {
-		return fmt.Errorf("failed to calculate indicators")
+		return nil, fmt.Errorf("failed to calculate indicators")
 	}
 
 	bot.logger.WithFields(logrus.Fields{
@@ -211,10 +812,11 @@ func (bot *TradingBot) runTradingCycleForSymbol(symbol string) error {
 
 	// Step 4: Get current position
 	bot.logger.Info("Step 4: Fetching current position...")
-	position, err := bot.hlClient.GetPosition(symbol, bot.config.Hyperliquid.AccountAddress)
+	account, err := bot.accountState()
 	if err != nil {
-		return fmt.Errorf("failed to fetch position: %w", err)
+		return nil, fmt.Errorf("failed to fetch position: %w", err)
 	}
+	position := account.Position(symbol)
 
 	bot.logger.WithFields(logrus.Fields{
 		"side": position.Side,
@@ -222,95 +824,214 @@ func (bot *TradingBot) runTradingCycleForSymbol(symbol string) error {
 		"pnl":  position.PnLPercent,
 	}).Info("Position fetched")
 
-	// Step 5: Check stop loss and take profit
-	if position.Size > 0 {
-		if bot.riskControl.CheckStopLoss(position, marketInfo.CurrentPrice, bot.lastStopLoss) {
-			bot.logger.Warn("Stop loss triggered, closing position")
-			decision := &ai.Decision{
-				Action:     "CLOSE_POSITION",
-				Confidence: 1.0,
-				Reason:     "Stop loss triggered",
-			}
-			return bot.executeDecision(decision, marketInfo, position, symbol)
-		}
-
-		if bot.riskControl.CheckTakeProfit(position, marketInfo.CurrentPrice, bot.lastTakeProfit) {
-			bot.logger.Info("Take profit triggered, closing position")
-			decision := &ai.Decision{
-				Action:     "CLOSE_POSITION",
-				Confidence: 1.0,
-				Reason:     "Take profit triggered",
-			}
-			return bot.executeDecision(decision, marketInfo, position, symbol)
-		}
-	}
-
-	// Step 6: AI analysis
-	bot.logger.Info("Step 6: Requesting AI decision...")
-	analysis := &ai.MarketAnalysis{
+	return &ai.MarketAnalysis{
 		Symbol:     symbol,
 		Timestamp:  time.Now(),
 		Market:     marketInfo,
 		Indicators: indicators,
 		Position:   position,
+		Candles:    candles,
+	}, nil
+}
+
+// decide obtains a symbol's decision from the AI and/or the strategy engine
+// according to strategy.mode
+func (bot *TradingBot) decide(analysis *ai.MarketAnalysis) (*ai.Decision, error) {
+	mode := bot.config.Strategy.Mode
+	if mode == strategy.ModeStandalone {
+		return bot.strategyDecision(analysis), nil
 	}
 
 	decision, err := bot.aiDecision.Analyze(analysis)
-	if err != nil {
-		return fmt.Errorf("AI analysis failed: %w", err)
+	switch {
+	case err == nil:
+	case mode == strategy.ModeFallback:
+		bot.logger.WithError(err).WithField("symbol", analysis.Symbol).Warn("AI analysis failed, falling back to strategy engine")
+		return bot.strategyDecision(analysis), nil
+	case errors.Is(err, ai.ErrBudgetExceeded):
+		bot.logger.WithField("symbol", analysis.Symbol).Warn("AI budget exceeded, holding")
+		return ai.BudgetHold(), nil
+	default:
+		return nil, fmt.Errorf("AI analysis failed: %w", err)
 	}
 
-	bot.logger.WithFields(logrus.Fields{
-		"action":     decision.Action,
-		"confidence": decision.Confidence,
-		"reason":     decision.Reason,
-	}).Info("AI decision received")
+	return bot.applyVeto(analysis, decision), nil
+}
+
+// decidePortfolio obtains ranked decisions for all symbols from the AI and/or
+// the strategy engine according to strategy.mode
+func (bot *TradingBot) decidePortfolio(portfolio *ai.PortfolioAnalysis) ([]*ai.SymbolDecision, error) {
+	mode := bot.config.Strategy.Mode
+
+	var decisions []*ai.SymbolDecision
+	var err error
+	if mode != strategy.ModeStandalone {
+		decisions, err = bot.aiDecision.AnalyzePortfolio(portfolio)
+		switch {
+		case err == nil:
+		case mode == strategy.ModeFallback:
+			bot.logger.WithError(err).Warn("AI portfolio analysis failed, falling back to strategy engine")
+		case errors.Is(err, ai.ErrBudgetExceeded):
+			bot.logger.Warn("AI budget exceeded, holding")
+			decisions = nil
+			for _, analysis := range portfolio.Symbols {
+				decisions = append(decisions, &ai.SymbolDecision{Symbol: analysis.Symbol, Decision: *ai.BudgetHold()})
+			}
+			return decisions, nil
+		default:
+			return nil, fmt.Errorf("AI portfolio analysis failed: %w", err)
+		}
+	}
+
+	if mode == strategy.ModeStandalone || err != nil {
+		decisions = nil
+		for _, analysis := range portfolio.Symbols {
+			decisions = append(decisions, &ai.SymbolDecision{
+				Symbol:   analysis.Symbol,
+				Decision: *bot.strategyDecision(analysis),
+			})
+		}
+		return decisions, nil
+	}
+
+	for _, d := range decisions {
+		for _, analysis := range portfolio.Symbols {
+			if analysis.Symbol == d.Symbol {
+				d.Decision = *bot.applyVeto(analysis, &d.Decision)
+			}
+		}
+	}
+	return decisions, nil
+}
+
+// strategyDecision returns the rule-based decision and remembers it like an AI decision
+func (bot *TradingBot) strategyDecision(analysis *ai.MarketAnalysis) *ai.Decision {
+	decision := bot.strategy.Decide(analysis)
+	if memory := bot.aiDecision.Memory(); memory != nil {
+		memory.RecordDecision(analysis.Symbol, decision, analysis.Market.CurrentPrice)
+	}
+	return decision
+}
 
-	// Print decision report to console
-	bot.printDecisionReport(symbol, marketInfo, indicators, position, decision)
+// applyVeto filters AI entries through the strategy engine in veto mode
+func (bot *TradingBot) applyVeto(analysis *ai.MarketAnalysis, decision *ai.Decision) *ai.Decision {
+	if bot.config.Strategy.Mode != strategy.ModeVeto {
+		return decision
+	}
 
-	// Step 7: Execute decision
-	if err := bot.executeDecision(decision, marketInfo, position, symbol); err != nil {
-		return fmt.Errorf("execution failed: %w", err)
+	filtered, vetoed := bot.strategy.Veto(decision, analysis)
+	if vetoed {
+		bot.logger.WithFields(logrus.Fields{
+			"symbol": analysis.Symbol,
+			"action": decision.Action,
+			"reason": filtered.Reason,
+		}).Warn("AI decision vetoed by strategy engine")
+	}
+	return filtered
+}
+
+// checkProtectiveExits closes the position when stop loss or take profit is hit
+// or it is held past its max holding time.
+// It reports whether an exit was handled so the caller can skip the AI.
+func (bot *TradingBot) checkProtectiveExits(symbol string, marketInfo *hyperliquid.MarketInfo, position *hyperliquid.Position) (bool, error) {
+	if position.Size == 0 {
+		// Closed on the exchange, e.g. by one of its trigger orders
+		if !bot.executor.Working(symbol) {
+			bot.clearProtective(symbol)
+		}
+		return false, nil
+	}
+
+	levels := bot.protective[symbol]
+	reason := ""
+	if bot.riskControl.CheckStopLoss(position, marketInfo.CurrentPrice, levels.StopLoss) {
+		bot.logger.Warn("Stop loss triggered, closing position")
+		reason = stopLossReason
+	} else if bot.riskControl.CheckTakeProfit(position, marketInfo.CurrentPrice, levels.TakeProfit) {
+		bot.logger.Info("Take profit triggered, closing position")
+		reason = "Take profit triggered"
+	} else if bot.riskControl.CheckHoldingTime(symbol, position) {
+		bot.logger.Warn("Max holding time exceeded, closing position")
+		reason = "Max holding time exceeded"
+	} else {
+		return false, nil
 	}
 
-	return nil
+	decision := &ai.Decision{
+		Action:        "CLOSE_POSITION",
+		Confidence:    1.0,
+		Reason:        reason,
+		ExecutionAlgo: executor.AlgoMarket, // Exits cross the book rather than rest while price runs
+	}
+	if memory := bot.aiDecision.Memory(); memory != nil {
+		memory.RecordDecision(symbol, decision, marketInfo.CurrentPrice)
+	}
+	return true, bot.executeDecision(decision, marketInfo, position, symbol)
 }
 
 // executeDecision executes a trading decision with risk checks
 func (bot *TradingBot) executeDecision(decision *ai.Decision, marketInfo *hyperliquid.MarketInfo, position *hyperliquid.Position, symbol string) error {
-	// Get account balance
-	balance, err := bot.hlClient.GetAccountBalance(bot.config.Hyperliquid.AccountAddress)
+	// Positions and balance in one round trip; earlier trades this cycle may
+	// have changed them
+	account, err := bot.refreshAccount()
 	if err != nil {
-		return fmt.Errorf("failed to get account balance: %w", err)
+		return fmt.Errorf("failed to get account state: %w", err)
 	}
+	// Spot orders are paid for from the quote balance, not perp margin
+	balance := account.BalanceFor(symbol)
 
 	bot.logger.WithField("balance", balance).Info("Account balance fetched")
 
-	// Count open positions across all symbols
-	openPositionCount := 0
-	for _, sym := range bot.config.Trading.Symbols {
-		pos, err := bot.hlClient.GetPosition(sym, bot.config.Hyperliquid.AccountAddress)
-		if err == nil && pos.Size > 0 {
-			openPositionCount++
-		}
-	}
+	portfolio := bot.portfolioSnapshot(account)
+	openPositionCount := len(portfolio.Positions)
 
 	// Risk check
 	bot.logger.Info("Performing risk checks...")
-	riskCheck, err := bot.riskControl.CheckDecision(decision, marketInfo.CurrentPrice, balance, position, openPositionCount)
+	riskCheck, err := bot.riskControl.CheckDecision(&risk.DecisionContext{
+		Symbol:            symbol,
+		Decision:          decision,
+		CurrentPrice:      marketInfo.CurrentPrice,
+		AccountBalance:    balance,
+		AccountValue:      account.Balance(),
+		Position:          position,
+		OpenPositionCount: openPositionCount,
+		Portfolio:         portfolio,
+	})
 	if err != nil {
 		return fmt.Errorf("risk check failed: %w", err)
 	}
 
 	if !riskCheck.Approved {
 		bot.logger.WithField("reason", riskCheck.Reason).Warn("Decision rejected by risk control")
+		bot.rememberExecution(symbol, false, "风控拒绝: "+riskCheck.Reason)
 		return nil
 	}
 
 	// Adjust decision based on risk check
 	decision.Size = riskCheck.AdjustedSize
 	decision.Leverage = riskCheck.AdjustedLeverage
+	decision.Quantity = riskCheck.Quantity
+
+	if bot.pendingOpens != nil && (decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT") {
+		side := "LONG"
+		if decision.Action == "OPEN_SHORT" {
+			side = "SHORT"
+		}
+		leverage := decision.Leverage
+		if leverage < 1 {
+			leverage = 1
+		}
+		notional := balance * decision.Size
+		bot.pendingOpens[symbol] = &hyperliquid.Position{
+			Symbol:        symbol,
+			Side:          side,
+			Size:          notional / marketInfo.CurrentPrice,
+			EntryPrice:    marketInfo.CurrentPrice,
+			PositionValue: notional,
+			Leverage:      leverage,
+			MarginUsed:    notional / float64(leverage),
+		}
+	}
 
 	// Check if trading is enabled
 	if !bot.config.Trading.TradingEnabled {
@@ -325,20 +1046,57 @@ func (bot *TradingBot) executeDecision(decision *ai.Decision, marketInfo *hyperl
 			"leverage": decision.Leverage,
 			"price":    marketInfo.CurrentPrice,
 		}).Info("Simulated trade")
+		bot.rememberExecution(symbol, false, "模拟模式")
 		return nil
 	}
 
 	// Execute trade
+	if decision.ExecutionAlgo == "" {
+		decision.ExecutionAlgo = bot.config.Trading.Override(symbol).ExecutionAlgo
+	}
 	result, err := bot.executor.Execute(symbol, decision, marketInfo.CurrentPrice, balance)
+	if errors.Is(err, executor.ErrPriceMoved) {
+		// Stale signals are expected in fast markets and are not failures
+		bot.logger.WithError(err).Warn("Order skipped, price moved since analysis")
+		bot.rememberExecution(symbol, false, err.Error())
+		return nil
+	}
 	if err != nil {
 		bot.logger.WithError(err).Error("Trade execution failed")
+		bot.rememberExecution(symbol, false, err.Error())
 		return err
 	}
 
+	bot.rememberExecution(symbol, result.Success, result.Message)
+	if result.Success {
+		bot.riskControl.RecordTrade(symbol, decision.Action, decision.Reason == stopLossReason)
+	}
+	if result.Success && (result.Size > 0 || result.Pending) &&
+		(decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT") {
+		// Only an entry that filled, or an algo still working it, holds a position
+		bot.riskControl.RecordEntry(symbol, decision.ExpectedHoldingPeriod)
+	}
+	if memory := bot.aiDecision.Memory(); memory != nil && result.Success &&
+		decision.Action == "CLOSE_POSITION" && position.Size > 0 {
+		memory.RecordOutcome(symbol, position.CurrentPnL, position.PnLPercent)
+	}
+
 	// Update stop loss and take profit tracking
-	if decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT" {
-		bot.lastStopLoss = decision.StopLoss
-		bot.lastTakeProfit = decision.TakeProfit
+	filled := result.Size
+	if result.Pending || filled <= 0 {
+		filled = decision.Quantity
+	}
+	switch {
+	case !result.Success:
+	case decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT":
+		levels := protectiveLevels{StopLoss: decision.StopLoss, TakeProfit: decision.TakeProfit}
+		bot.protective[symbol] = bot.placeProtective(symbol, result.Side, filled, levels)
+	case decision.Action == "ADD_POSITION":
+		bot.moveProtective(symbol, position, marketInfo.CurrentPrice, decision, position.Size+filled)
+	case decision.Action == "HOLD" && position.Size > 0:
+		bot.moveProtective(symbol, position, marketInfo.CurrentPrice, decision, 0)
+	case decision.Action == "CLOSE_POSITION":
+		bot.clearProtective(symbol)
 	}
 
 	bot.logger.WithFields(logrus.Fields{
@@ -348,6 +1106,7 @@ func (bot *TradingBot) executeDecision(decision *ai.Decision, marketInfo *hyperl
 		"size":     result.Size,
 		"price":    result.Price,
 		"order_id": result.OrderID,
+		"pending":  result.Pending,
 		"message":  result.Message,
 	}).Info("Trade executed")
 
@@ -357,13 +1116,135 @@ func (bot *TradingBot) executeDecision(decision *ai.Decision, marketInfo *hyperl
 	return nil
 }
 
+// placeProtective places exchange trigger orders at levels for a position of
+// size on side, so it stays protected between cycles and if the bot stops.
+// The cycle's own stop loss and take profit checks remain as a fallback.
+func (bot *TradingBot) placeProtective(symbol, side string, size float64, levels protectiveLevels) protectiveLevels {
+	if !bot.config.Execution.ProtectiveOrders || hyperliquid.IsSpot(symbol) || size <= 0 {
+		return levels
+	}
+	orders, err := bot.executor.Protect(symbol, side, size, levels.StopLoss, levels.TakeProfit)
+	if err != nil {
+		bot.logger.WithError(err).WithField("symbol", symbol).Error("Failed to place protective orders")
+	}
+	levels.Orders = orders
+	return levels
+}
+
+// moveProtective moves the stop loss and take profit to those of decision
+// when they are set and on the right side of price, amending the exchange
+// trigger orders in place. A non-zero size resizes them to a position that
+// grew.
+func (bot *TradingBot) moveProtective(symbol string, position *hyperliquid.Position, price float64, decision *ai.Decision, size float64) {
+	levels, ok := bot.protective[symbol]
+	if !ok {
+		levels = protectiveLevels{}
+	}
+	long := position.Side == "LONG"
+	if stop := decision.StopLoss; stop > 0 && (long && stop < price || !long && stop > price) {
+		levels.StopLoss = stop
+	}
+	if target := decision.TakeProfit; target > 0 && (long && target > price || !long && target < price) {
+		levels.TakeProfit = target
+	}
+
+	if levels.Orders == (executor.ProtectiveOrders{}) {
+		if size <= 0 {
+			size = position.Size
+		}
+		bot.protective[symbol] = bot.placeProtective(symbol, position.Side, size, levels)
+		return
+	}
+
+	amend := func(orderID string, trigger float64) {
+		if orderID == "" || trigger <= 0 {
+			return
+		}
+		if err := bot.executor.AmendProtective(symbol, orderID, trigger, size); err != nil {
+			bot.logger.WithError(err).WithField("symbol", symbol).Error("Failed to move protective order")
+		}
+	}
+	old := bot.protective[symbol]
+	if levels.StopLoss != old.StopLoss || size > 0 {
+		amend(levels.Orders.StopLoss, levels.StopLoss)
+	}
+	if levels.TakeProfit != old.TakeProfit || size > 0 {
+		amend(levels.Orders.TakeProfit, levels.TakeProfit)
+	}
+	bot.protective[symbol] = levels
+}
+
+// clearProtective forgets a symbol's levels once its position is closed and
+// cancels trigger orders still resting
+func (bot *TradingBot) clearProtective(symbol string) {
+	levels, ok := bot.protective[symbol]
+	if !ok {
+		return
+	}
+	delete(bot.protective, symbol)
+	if levels.Orders != (executor.ProtectiveOrders{}) {
+		if err := bot.executor.CancelProtective(symbol, levels.Orders); err != nil {
+			bot.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to cancel protective orders")
+		}
+	}
+}
+
+// portfolioSnapshot collects open positions and margin across the account,
+// including opens approved earlier in this cycle
+func (bot *TradingBot) portfolioSnapshot(account *hyperliquid.AccountState) *risk.PortfolioSnapshot {
+	snapshot := risk.NewPortfolioSnapshot(account, bot.pendingOpens)
+	for _, pos := range account.OpenPositions() {
+		bot.ensureCandleHistory(pos.Symbol)
+	}
+	return snapshot
+}
+
+// ensureCandleHistory loads candles for a held symbol the risk controller has
+// not seen yet, so correlation checks cover it
+func (bot *TradingBot) ensureCandleHistory(symbol string) {
+	if bot.riskControl.HasCandles(symbol) {
+		return
+	}
+	candles, err := bot.hlClient.GetCandlestickData(symbol, bot.config.Trading.Timeframe, 150)
+	if err != nil {
+		bot.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to fetch candles for correlation check")
+		return
+	}
+	bot.riskControl.RecordCandles(symbol, candles)
+}
+
+// rememberExecution records the execution outcome of the latest decision in the AI memory
+func (bot *TradingBot) rememberExecution(symbol string, executed bool, note string) {
+	if memory := bot.aiDecision.Memory(); memory != nil {
+		memory.RecordExecution(symbol, executed, note)
+	}
+}
+
 // Stop stops the trading bot
 func (bot *TradingBot) Stop() {
+	for _, account := range bot.accounts {
+		account.Stop()
+	}
+	if len(bot.accounts) > 0 {
+		return
+	}
+
 	bot.logger.Info("Stopping trading bot...")
 	bot.scheduler.Stop()
+	bot.executor.Stop()
 	bot.logger.Info("Trading bot stopped")
 }
 
+// escalate moves the kill switch of every account to at least state
+func (bot *TradingBot) escalate(state risk.TradingState, source, reason string) {
+	for _, account := range bot.accounts {
+		account.escalate(state, source, reason)
+	}
+	if bot.riskControl != nil {
+		bot.riskControl.KillSwitch().Escalate(state, source, reason)
+	}
+}
+
 // intervalToCron converts interval string to cron expression
 func (bot *TradingBot) intervalToCron(interval string) string {
 	switch interval {
@@ -445,6 +1326,11 @@ func (bot *TradingBot) printDecisionReport(symbol string, market *hyperliquid.Ma
 		fmt.Println("\n💼 Position: None")
 	}
 
+	// Anti-churn limits blocking new entries
+	if cooldown := bot.riskControl.Cooldown(symbol); cooldown.Active() {
+		fmt.Printf("%s⏳ Cooldown: %s%s\n", colorYellow, formatCooldown(cooldown), colorReset)
+	}
+
 	// AI Decision - Compact to one line for basic info
 	actionStr := bot.formatAction(decision.Action)
 	if decision.Action != "HOLD" && decision.Action != "CLOSE_POSITION" {
@@ -476,6 +1362,25 @@ func (bot *TradingBot) printDecisionReport(symbol string, market *hyperliquid.Ma
 	fmt.Println(strings.Repeat("=", 80) + "\n")
 }
 
+// formatCooldown describes the limits in a cooldown status
+func formatCooldown(status risk.CooldownStatus) string {
+	var parts []string
+	if status.Reentry > 0 {
+		cause := "close"
+		if status.StoppedOut {
+			cause = "stop-out"
+		}
+		parts = append(parts, fmt.Sprintf("after %s %s", cause, status.Reentry.Round(time.Second)))
+	}
+	if status.Flip > 0 {
+		parts = append(parts, fmt.Sprintf("flip from %s %s", status.LastSide, status.Flip.Round(time.Second)))
+	}
+	if status.MaxTradesPerDay > 0 {
+		parts = append(parts, fmt.Sprintf("trades today %d/%d", status.TradesToday, status.MaxTradesPerDay))
+	}
+	return strings.Join(parts, " | ")
+}
+
 // formatAction formats the action with emoji
 func (bot *TradingBot) formatAction(action string) string {
 	switch action {
@@ -723,6 +1628,10 @@ func (bot *TradingBot) printSimulatedOrder(decision *ai.Decision, market *hyperl
 	// Calculate position details
 	positionValue := balance * decision.Size
 	coinAmount := positionValue / market.CurrentPrice
+	if decision.Quantity > 0 {
+		coinAmount = decision.Quantity
+		positionValue = coinAmount * market.CurrentPrice
+	}
 
 	fmt.Printf("\n📊 币种: %s\n", symbol)
 	fmt.Printf("   方向: %s %s\n", sideEmoji, sideText)
@@ -813,19 +1722,52 @@ func showPositions() {
 		os.Exit(1)
 	}
 
-	hlClient := hyperliquid.NewClient(cfg.Hyperliquid.APIURL)
+	hlClient, err := newHyperliquidClient(&cfg.Hyperliquid)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	accounts, err := cfg.Accounts()
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Println("\n" + strings.Repeat("=", 80))
 	fmt.Printf("  💼 Current Positions - %s\n", time.Now().Format("2006-01-02 15:04:05"))
 	fmt.Println(strings.Repeat("=", 80))
 
-	// Get account balance first
-	balance, err := hlClient.GetAccountBalance(cfg.Hyperliquid.AccountAddress)
+	// Margin tables back the liquidation estimate when the exchange reports none
+	marginTiers, _ := hlClient.GetMarginTiers()
+
+	for _, accountCfg := range accounts {
+		showAccountPositions(hlClient, accountCfg, marginTiers)
+	}
+
+	fmt.Println(strings.Repeat("=", 80) + "\n")
+}
+
+// showAccountPositions prints the positions and summary of one account
+func showAccountPositions(hlClient *hyperliquid.Client, cfg *config.Config, marginTiers map[string][]hyperliquid.MarginTier) {
+	if cfg.Account != "" {
+		fmt.Printf("\n🏷️  Account: %s (%s)\n", cfg.Account, cfg.Hyperliquid.TradingAddress())
+	}
+
+	// Positions and balance in one round trip
+	address := cfg.Hyperliquid.TradingAddress()
+	account, err := hlClient.GetAccountState(address)
 	if err != nil {
-		fmt.Printf("\n❌ Failed to get account balance: %v\n", err)
-	} else {
-		fmt.Printf("\n💰 Account Balance: $%.2f\n", balance)
+		fmt.Printf("\n❌ Failed to get account state: %v\n\n", err)
+		os.Exit(1)
+	}
+	if cfg.Trading.HasSpot() {
+		if err := hlClient.AddSpotHoldings(account, address, cfg.Trading.Symbols); err != nil {
+			fmt.Printf("\n❌ Failed to get spot balances: %v\n\n", err)
+			os.Exit(1)
+		}
 	}
+	balance := account.Balance()
+	fmt.Printf("\n💰 Account Balance: $%.2f\n", balance)
 
 	hasOpenPosition := false
 	totalPnL := 0.0
@@ -833,64 +1775,75 @@ func showPositions() {
 
 	fmt.Println("\n" + strings.Repeat("-", 80))
 
-	// Check positions for all configured symbols
-	for _, symbol := range cfg.Trading.Symbols {
-		position, err := hlClient.GetPosition(symbol, cfg.Hyperliquid.AccountAddress)
-		if err != nil {
-			fmt.Printf("\n❌ %s: Failed to fetch position - %v\n", symbol, err)
-			continue
-		}
+	// Every open position, including symbols not configured for trading
+	for _, position := range account.OpenPositions() {
+		symbol := position.Symbol
+		hasOpenPosition = true
 
-		if position.Size > 0 {
-			hasOpenPosition = true
+		// Get current market price
+		marketInfo, err := hlClient.GetMarketData(symbol)
+		currentPrice := 0.0
+		if err == nil {
+			currentPrice = marketInfo.CurrentPrice
+		}
 
-			// Get current market price
-			marketInfo, err := hlClient.GetMarketData(symbol)
-			currentPrice := 0.0
-			if err == nil {
-				currentPrice = marketInfo.CurrentPrice
-			}
+		// Calculate exposure
+		exposure := position.Size * position.EntryPrice
+		totalExposure += exposure
+		totalPnL += position.CurrentPnL
 
-			// Calculate exposure
-			exposure := position.Size * position.EntryPrice
-			totalExposure += exposure
-			totalPnL += position.CurrentPnL
+		// Format side with emoji
+		sideEmoji := "🟢"
+		if position.Side == "SHORT" {
+			sideEmoji = "🔴"
+		}
 
-			// Format side with emoji
-			sideEmoji := "🟢"
-			if position.Side == "SHORT" {
-				sideEmoji = "🔴"
-			}
+		// Format PnL with emoji
+		pnlEmoji := "🟢"
+		if position.PnLPercent < 0 {
+			pnlEmoji = "🔴"
+		}
 
-			// Format PnL with emoji
-			pnlEmoji := "🟢"
-			if position.PnLPercent < 0 {
-				pnlEmoji = "🔴"
+		fmt.Printf("\n📊 %s Position:\n", symbol)
+		fmt.Printf("  Side:         %s %s\n", sideEmoji, position.Side)
+		fmt.Printf("  Size:         %.4f\n", position.Size)
+		fmt.Printf("  Entry Price:  $%s\n", formatPriceGlobal(position.EntryPrice))
+		if currentPrice > 0 {
+			fmt.Printf("  Current Price: $%s\n", formatPriceGlobal(currentPrice))
+			priceChange := ((currentPrice - position.EntryPrice) / position.EntryPrice) * 100
+			priceChangeEmoji := "🟢"
+			if priceChange < 0 {
+				priceChangeEmoji = "🔴"
 			}
-
-			fmt.Printf("\n📊 %s Position:\n", symbol)
-			fmt.Printf("  Side:         %s %s\n", sideEmoji, position.Side)
-			fmt.Printf("  Size:         %.4f\n", position.Size)
-			fmt.Printf("  Entry Price:  $%s\n", formatPriceGlobal(position.EntryPrice))
-			if currentPrice > 0 {
-				fmt.Printf("  Current Price: $%s\n", formatPriceGlobal(currentPrice))
-				priceChange := ((currentPrice - position.EntryPrice) / position.EntryPrice) * 100
-				priceChangeEmoji := "🟢"
-				if priceChange < 0 {
-					priceChangeEmoji = "🔴"
-				}
-				fmt.Printf("  Price Change: %s %.2f%%\n", priceChangeEmoji, priceChange)
+			fmt.Printf("  Price Change: %s %.2f%%\n", priceChangeEmoji, priceChange)
+		}
+		fmt.Printf("  Unrealized P&L: %s $%.2f (%.2f%%)\n", pnlEmoji, position.CurrentPnL, position.PnLPercent)
+		fmt.Printf("  Exposure:     $%.2f\n", exposure)
+		fmt.Printf("  Leverage:     %dx %s\n", position.Leverage, position.MarginMode)
+		fmt.Printf("  Margin Used:  $%.2f (ROE %.2f%%)\n", position.MarginUsed, position.ReturnOnEquity*100)
+		fmt.Printf("  Funding:      $%.2f since open\n", position.Funding)
+
+		liquidation := position.LiquidationPrice
+		if liquidation <= 0 {
+			margin := position.MarginUsed
+			if position.MarginMode != "isolated" {
+				margin = balance
 			}
-			fmt.Printf("  Unrealized P&L: %s $%.2f (%.2f%%)\n", pnlEmoji, position.CurrentPnL, position.PnLPercent)
-			fmt.Printf("  Exposure:     $%.2f\n", exposure)
-			fmt.Printf("  Holding Time: %s\n", position.HoldingTime.String())
-
-			fmt.Println(strings.Repeat("-", 80))
+			liquidation = risk.LiquidationPrice(position.Side, position.EntryPrice, position.Size, margin, marginTiers[symbol])
+		}
+		if liquidation > 0 && currentPrice > 0 {
+			distance := math.Abs(currentPrice-liquidation) / currentPrice * 100
+			fmt.Printf("  Liquidation:  $%s (%.2f%% away)\n", formatPriceGlobal(liquidation), distance)
+		} else {
+			fmt.Printf("  Liquidation:  none\n")
 		}
+		fmt.Printf("  Holding Time: %s\n", position.HoldingTime.String())
+
+		fmt.Println(strings.Repeat("-", 80))
 	}
 
 	if !hasOpenPosition {
-		fmt.Println("\n📭 No open positions\n")
+		fmt.Print("\n📭 No open positions\n\n")
 	} else {
 		fmt.Println("\n📈 Summary:")
 		fmt.Printf("  Total Exposure:  $%.2f\n", totalExposure)
@@ -905,8 +1858,6 @@ func showPositions() {
 		}
 		fmt.Println()
 	}
-
-	fmt.Println(strings.Repeat("=", 80) + "\n")
 }
 
 // showBalance displays account balance
@@ -917,38 +1868,252 @@ func showBalance() {
 		os.Exit(1)
 	}
 
-	hlClient := hyperliquid.NewClient(cfg.Hyperliquid.APIURL)
+	hlClient, err := newHyperliquidClient(&cfg.Hyperliquid)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	accounts, err := cfg.Accounts()
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Println("\n" + strings.Repeat("=", 80))
 	fmt.Printf("  💰 Account Balance - %s\n", time.Now().Format("2006-01-02 15:04:05"))
 	fmt.Println(strings.Repeat("=", 80))
 
-	balance, err := hlClient.GetAccountBalance(cfg.Hyperliquid.AccountAddress)
+	for _, accountCfg := range accounts {
+		if accountCfg.Account != "" {
+			fmt.Printf("\n🏷️  Account: %s (%s)\n", accountCfg.Account, accountCfg.Hyperliquid.TradingAddress())
+		}
+
+		account, err := hlClient.GetAccountState(accountCfg.Hyperliquid.TradingAddress())
+		if err != nil {
+			fmt.Printf("\n❌ Failed to get account balance: %v\n\n", err)
+			os.Exit(1)
+		}
+		balance := account.Balance()
+
+		fmt.Printf("\n💵 Available Balance: $%.2f\n", balance)
+		fmt.Printf("🏦 Withdrawable:      $%.2f\n", account.Withdrawable)
+		fmt.Printf("🔒 Margin Used:       $%.2f\n", account.Margin.MarginUsed)
+
+		totalExposure := account.Exposure()
+
+		if totalExposure > 0 {
+			fmt.Printf("💼 Total Exposure:    $%.2f\n", totalExposure)
+			fmt.Printf("📊 Exposure Ratio:    %.2f%%\n", (totalExposure/balance)*100)
+			fmt.Printf("💵 Free Balance:      $%.2f\n", balance-totalExposure)
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
+}
+
+// showCost displays today's AI token usage and spend
+func showCost() {
+	cfg, err := config.Load("config.yaml")
 	if err != nil {
-		fmt.Printf("\n❌ Failed to get account balance: %v\n\n", err)
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("\n💵 Available Balance: $%.2f\n", balance)
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Printf("  🧾 AI Cost - %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Println(strings.Repeat("=", 80))
+
+	if cfg.AI.Budget.StateFile == "" {
+		fmt.Print("\n❌ ai.budget.state_file is not configured\n\n")
+		os.Exit(1)
+	}
 
-	// Calculate total exposure
-	totalExposure := 0.0
-	for _, symbol := range cfg.Trading.Symbols {
-		position, err := hlClient.GetPosition(symbol, cfg.Hyperliquid.AccountAddress)
-		if err == nil && position.Size > 0 {
-			totalExposure += position.Size * position.EntryPrice
-		}
+	report, err := ai.LoadCostReport(cfg.AI.Budget.StateFile)
+	if err != nil || report.Date != time.Now().Format("2006-01-02") {
+		fmt.Print("\n📭 No AI calls recorded today\n\n")
+		fmt.Println(strings.Repeat("=", 80) + "\n")
+		return
+	}
+
+	fmt.Printf("\n📅 Date:          %s\n", report.Date)
+	fmt.Printf("📞 Calls:         %d\n", report.Total.Calls)
+	fmt.Printf("🔤 Tokens:        %d in / %d out\n", report.Total.PromptTokens, report.Total.CompletionTokens)
+	fmt.Printf("💵 Spend:         $%.4f", report.Total.Cost)
+	if cfg.AI.Budget.DailyLimit > 0 {
+		fmt.Printf(" / $%.2f (%.1f%%)", cfg.AI.Budget.DailyLimit, report.Total.Cost/cfg.AI.Budget.DailyLimit*100)
+	}
+	fmt.Println()
+
+	fmt.Println("\n" + strings.Repeat("-", 80))
+	fmt.Println("📊 By Symbol:")
+	for symbol, totals := range report.BySymbol {
+		fmt.Printf("  %-10s calls:%-5d tokens:%-8d $%.4f\n", symbol, totals.Calls, totals.PromptTokens+totals.CompletionTokens, totals.Cost)
+	}
+	fmt.Println("\n🤖 By Model:")
+	for model, totals := range report.ByModel {
+		fmt.Printf("  %-16s calls:%-5d tokens:%-8d $%.4f\n", model, totals.Calls, totals.PromptTokens+totals.CompletionTokens, totals.Cost)
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
+}
+
+// showTradingState displays the persisted kill switch state
+func showTradingState() {
+	cfg, err := config.Load("config.yaml")
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	accounts, err := cfg.Accounts()
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
 	}
 
-	if totalExposure > 0 {
-		fmt.Printf("💼 Total Exposure:    $%.2f\n", totalExposure)
-		fmt.Printf("📊 Exposure Ratio:    %.2f%%\n", (totalExposure/balance)*100)
-		fmt.Printf("💵 Free Balance:      $%.2f\n", balance-totalExposure)
+	fmt.Println("\n" + strings.Repeat("=", 80))
+	fmt.Printf("  🚦 Trading State - %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Println(strings.Repeat("=", 80))
+
+	for _, accountCfg := range accounts {
+		halt := &risk.Halt{State: risk.StateActive}
+		if accountCfg.Risk.KillSwitch.StateFile != "" {
+			if loaded, err := risk.LoadTradingState(accountCfg.Risk.KillSwitch.StateFile); err == nil {
+				halt = loaded
+			}
+		}
+
+		stateEmoji := "🟢"
+		switch halt.State {
+		case risk.StateReduceOnly:
+			stateEmoji = "🟡"
+		case risk.StateHalted, risk.StateFlatten:
+			stateEmoji = "🔴"
+		}
+
+		if accountCfg.Account != "" {
+			fmt.Printf("\n🏷️  Account: %s", accountCfg.Account)
+		}
+		fmt.Printf("\n%s State:  %s\n", stateEmoji, halt.State)
+		if halt.State != risk.StateActive {
+			fmt.Printf("   Reason: %s\n", halt.Reason)
+			fmt.Printf("   Source: %s\n", halt.Source)
+			fmt.Printf("   Since:  %s\n", halt.Since.Format("2006-01-02 15:04:05"))
+		}
 	}
 
 	fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
 }
 
+// setTradingState persists a manual kill switch state; the running bot
+// picks it up at the start of its next cycle
+func setTradingState(args []string) {
+	cfg, err := config.Load("config.yaml")
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.Risk.KillSwitch.StateFile == "" {
+		fmt.Print("\n❌ risk.kill_switch.state_file is not configured\n\n")
+		os.Exit(1)
+	}
+	accounts, err := cfg.Accounts()
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	state := risk.StateHalted
+	if len(args) > 0 {
+		if state, err = risk.ParseTradingState(args[0]); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		args = args[1:]
+	}
+
+	reason := strings.Join(args, " ")
+	if reason == "" {
+		reason = "manual"
+	}
+
+	// Every account is switched; each persists its own state file
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	for _, accountCfg := range accounts {
+		killSwitch := risk.NewKillSwitch(accountCfg.Risk.KillSwitch, logger)
+		if state == risk.StateActive {
+			killSwitch.Clear("cli")
+		} else {
+			killSwitch.Set(state, "cli", reason)
+		}
+
+		if err := killSwitch.SaveError(); err != nil {
+			fmt.Printf("❌ Failed to save trading state: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("✅ Trading state set to %s\n", state)
+}
+
+// approveAgent runs "approve-agent [0xAGENT] [name]": it authorizes an API
+// agent wallet to trade for the master account, signing the approval with
+// hyperliquid.private_key. Without an agent address a new agent key is
+// generated and printed once. The name defaults to "aitrading"; approving
+// under a name already in use replaces that agent.
+func approveAgent(args []string) {
+	cfg, err := config.Load("config.yaml")
+	if err != nil {
+		fmt.Printf("❌ Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	var agent *hyperliquid.Agent
+	var agentAddress string
+	if len(args) > 0 && strings.HasPrefix(args[0], "0x") {
+		agentAddress, args = args[0], args[1:]
+	} else {
+		if agent, err = hyperliquid.NewAgent(); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		agentAddress = agent.Address
+	}
+	name := "aitrading"
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	masterKey, err := cfg.Hyperliquid.PrivateKey.Resolve()
+	if err != nil {
+		fmt.Printf("❌ Failed to load hyperliquid.private_key: %v\n", err)
+		os.Exit(1)
+	}
+	hlClient, err := newHyperliquidClient(&cfg.Hyperliquid)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	hlTrader, err := hyperliquid.NewTrader(hlClient, masterKey, cfg.Hyperliquid.AccountAddress)
+	if err != nil {
+		fmt.Printf("❌ hyperliquid.private_key must hold the master key: %v\n", err)
+		os.Exit(1)
+	}
+	if err := hlTrader.ApproveAgent(agentAddress, name); err != nil {
+		fmt.Printf("❌ Failed to approve agent: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Agent %s approved as %q for %s on %s\n", agentAddress, name, cfg.Hyperliquid.AccountAddress, hlClient.Network().Name)
+	if agent != nil {
+		fmt.Println("\nAgent private key (shown once, store it securely):")
+		fmt.Printf("  %s\n", agent.PrivateKey)
+	}
+	fmt.Println("\nSet hyperliquid.agent_key to the agent key, e.g. agent_key: \"${HL_AGENT_KEY}\",")
+	fmt.Println("and remove the master private_key from config.yaml.")
+}
+
 // showHelp displays usage information
 func showHelp() {
 	fmt.Println("\n" + strings.Repeat("=", 80))
@@ -961,6 +2126,13 @@ func showHelp() {
 	fmt.Println("  ./aitrading position     Show current positions (alias)")
 	fmt.Println("  ./aitrading positions    Show current positions (alias)")
 	fmt.Println("  ./aitrading balance      Show account balance")
+	fmt.Println("  ./aitrading cost         Show today's AI token usage and spend")
+	fmt.Println("  ./aitrading state        Show the trading state (kill switch)")
+	fmt.Println("  ./aitrading halt [STATE] [reason]")
+	fmt.Println("                           Set REDUCE_ONLY, HALTED (default) or FLATTEN")
+	fmt.Println("  ./aitrading resume       Clear the kill switch and resume trading")
+	fmt.Println("  ./aitrading approve-agent [address] [name]")
+	fmt.Println("                           Approve an API agent wallet (new key if no address)")
 	fmt.Println("  ./aitrading help         Show this help message")
 	fmt.Println("\nExamples:")
 	fmt.Println("  # Start trading bot")
@@ -1006,7 +2178,11 @@ func (bot *TradingBot) printExecutionResult(result *executor.ExecutionResult, sy
 
 	// Determine color and emoji based on success
 	var statusColor, statusEmoji, statusText string
-	if result.Success {
+	if result.Success && result.Pending {
+		statusColor = colorYellow
+		statusEmoji = "⏳"
+		statusText = "WORKING"
+	} else if result.Success {
 		statusColor = colorGreen
 		statusEmoji = "✅"
 		statusText = "SUCCESS"
@@ -1077,6 +2253,21 @@ func main() {
 		case "balance":
 			showBalance()
 			return
+		case "cost":
+			showCost()
+			return
+		case "state":
+			showTradingState()
+			return
+		case "halt":
+			setTradingState(os.Args[2:])
+			return
+		case "resume":
+			setTradingState([]string{string(risk.StateActive)})
+			return
+		case "approve-agent":
+			approveAgent(os.Args[2:])
+			return
 		case "help", "-h", "--help":
 			showHelp()
 			return
@@ -1107,12 +2298,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal; SIGUSR1 halts trading and SIGUSR2 flattens
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
 
 	fmt.Println("AI Trading Bot is running. Press Ctrl+C to stop.")
-	<-sigChan
+	for sig := range sigChan {
+		if sig == syscall.SIGUSR1 {
+			bot.escalate(risk.StateHalted, "signal", "SIGUSR1")
+			continue
+		}
+		if sig == syscall.SIGUSR2 {
+			bot.escalate(risk.StateFlatten, "signal", "SIGUSR2")
+			continue
+		}
+		break
+	}
 
 	// Graceful shutdown
 	bot.Stop()
@@ -1,30 +1,52 @@
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"aitrading/ai"
 	"aitrading/config"
+	"aitrading/hyperliquid"
 	"aitrading/indicators"
+
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
-func TestMainConfigLoad(t *testing.T) {
+// loadTestConfig loads config.yaml with a throwaway agent key and no log file
+func loadTestConfig(t *testing.T) *config.Config {
 	cfg, err := config.Load("config.yaml")
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
 
-	if cfg.Trading.Symbol == "" {
-		t.Error("Trading symbol should not be empty")
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
 	}
+	cfg.Hyperliquid.AgentKey = config.Secret(hex.EncodeToString(crypto.FromECDSA(key)))
+	cfg.Monitoring.LogFile = ""
+
+	return cfg
 }
 
-func TestTradingBotCreation(t *testing.T) {
+func TestMainConfigLoad(t *testing.T) {
 	cfg, err := config.Load("config.yaml")
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
 
+	if len(cfg.Trading.Symbols) == 0 {
+		t.Error("Trading symbols should not be empty")
+	}
+}
+
+func TestTradingBotCreation(t *testing.T) {
+	cfg := loadTestConfig(t)
+
 	bot, err := NewTradingBot(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create trading bot: %v", err)
@@ -75,7 +97,7 @@ func TestIndicatorCalculationPipeline(t *testing.T) {
 
 	for i := range data {
 		// Simulate price movement with some volatility
-		variation := float64(i%10 - 5) * 2
+		variation := float64(i%10-5) * 2
 		data[i] = indicators.MarketData{
 			Timestamp: time.Now().Add(-time.Duration(150-i) * 5 * time.Minute).Unix(),
 			Open:      basePrice + variation,
@@ -161,3 +183,199 @@ func TestGetLogLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestTradingBotAccounts(t *testing.T) {
+	cfg := loadTestConfig(t)
+	cfg.Hyperliquid.Accounts = []config.AccountConfig{
+		{Name: "vault", VaultAddress: "0x00000000000000000000000000000000000000aa", Symbols: []string{"ETH"}},
+		{Name: "sub", AccountAddress: "0x0000000000000000000000000000000000000002"},
+	}
+
+	bot, err := NewTradingBot(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create trading bot: %v", err)
+	}
+	if len(bot.accounts) != 2 {
+		t.Fatalf("expected a bot per account, got %d", len(bot.accounts))
+	}
+
+	vault, sub := bot.accounts[0], bot.accounts[1]
+	if vault.name != "vault" || vault.config.Hyperliquid.TradingAddress() != "0x00000000000000000000000000000000000000aa" {
+		t.Errorf("unexpected vault bot: %s %s", vault.name, vault.config.Hyperliquid.TradingAddress())
+	}
+	if len(vault.config.Trading.Symbols) != 1 || len(sub.config.Trading.Symbols) != len(cfg.Trading.Symbols) {
+		t.Errorf("each account should trade its own symbols: %v / %v", vault.config.Trading.Symbols, sub.config.Trading.Symbols)
+	}
+	if vault.riskControl == sub.riskControl || vault.riskControl.KillSwitch() == sub.riskControl.KillSwitch() {
+		t.Error("accounts should have independent risk controls")
+	}
+	if vault.aiDecision.Budget() != sub.aiDecision.Budget() {
+		t.Error("accounts should share the AI budget")
+	}
+
+	cfg.Hyperliquid.Accounts[0].VaultAddress = "not an address"
+	if _, err := NewTradingBot(cfg); err == nil {
+		t.Error("an invalid vault address should fail")
+	}
+}
+
+func TestCheckSigningKey(t *testing.T) {
+	master, _ := crypto.GenerateKey()
+	masterKey := config.Secret(hex.EncodeToString(crypto.FromECDSA(master)))
+	masterAddress := crypto.PubkeyToAddress(master.PublicKey).Hex()
+	agent, _ := crypto.GenerateKey()
+	agentKey := config.Secret(hex.EncodeToString(crypto.FromECDSA(agent)))
+
+	cases := []struct {
+		name string
+		cfg  config.HyperliquidConfig
+		ok   bool
+	}{
+		{"master key", config.HyperliquidConfig{PrivateKey: masterKey, AccountAddress: masterAddress}, false},
+		{"master key allowed", config.HyperliquidConfig{PrivateKey: masterKey, AccountAddress: masterAddress, AllowMasterKey: true}, true},
+		{"agent key", config.HyperliquidConfig{PrivateKey: masterKey, AgentKey: agentKey, AccountAddress: masterAddress}, true},
+		{"master key as agent key", config.HyperliquidConfig{AgentKey: masterKey, AccountAddress: masterAddress}, false},
+	}
+	for _, c := range cases {
+		err := checkSigningKey(&c.cfg)
+		if err == nil {
+			signingKey, resolveErr := c.cfg.SigningKey()
+			if resolveErr != nil {
+				t.Fatal(resolveErr)
+			}
+			trader, resolveErr := hyperliquid.NewTrader(hyperliquid.NewClient(""), signingKey, c.cfg.AccountAddress)
+			if resolveErr != nil {
+				t.Fatal(resolveErr)
+			}
+			err = checkAgentKey(&c.cfg, trader)
+		}
+		if (err == nil) != c.ok {
+			t.Errorf("%s: unexpected result %v", c.name, err)
+		}
+	}
+
+	// The master key is refused before it is resolved, so never prompted for
+	refused := config.HyperliquidConfig{PrivateKey: "keystore:/nonexistent/key.json", AccountAddress: masterAddress}
+	if err := checkSigningKey(&refused); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("expected the master key refused unresolved, got %v", err)
+	}
+}
+
+func TestLogRedactsSecrets(t *testing.T) {
+	cfg := loadTestConfig(t)
+	key, _ := cfg.Hyperliquid.AgentKey.Resolve()
+
+	var out bytes.Buffer
+	logger := newLogger(cfg)
+	logger.SetOutput(&out)
+	logger.WithField("key", key).WithError(errors.New("bad key "+key)).Errorf("signing with %s", key)
+	accountLogger(logger, "vault").Infof("account key %s", key)
+
+	if strings.Contains(out.String(), key) {
+		t.Errorf("log leaks the agent key:\n%s", out.String())
+	}
+	if strings.Count(out.String(), "[REDACTED]") != 4 {
+		t.Errorf("expected 4 redactions, got:\n%s", out.String())
+	}
+}
+
+func TestNetworkSelection(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     config.HyperliquidConfig
+		network hyperliquid.Network
+		ok      bool
+	}{
+		{"mainnet by default", config.HyperliquidConfig{}, hyperliquid.Mainnet, true},
+		{"testnet endpoint by default", config.HyperliquidConfig{Testnet: true}, hyperliquid.Testnet, true},
+		{"custom testnet endpoint", config.HyperliquidConfig{Testnet: true, APIURL: "http://127.0.0.1:3001"}, hyperliquid.Testnet, true},
+		{"testnet with mainnet endpoint", config.HyperliquidConfig{Testnet: true, APIURL: hyperliquid.Mainnet.APIURL}, hyperliquid.Testnet, false},
+	}
+	for _, c := range cases {
+		client, err := newHyperliquidClient(&c.cfg)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: unexpected result %v", c.name, err)
+			continue
+		}
+		if err == nil && client.Network() != c.network {
+			t.Errorf("%s: expected %s, got %s", c.name, c.network.Name, client.Network().Name)
+		}
+	}
+
+	// Trading real funds on mainnet needs confirm_mainnet
+	cfg := loadTestConfig(t)
+	cfg.Hyperliquid.Testnet = false
+	cfg.Hyperliquid.ConfirmMainnet = false
+	cfg.Trading.TradingEnabled = true
+	if _, err := NewTradingBot(cfg); err == nil {
+		t.Error("mainnet trading without confirm_mainnet should be refused")
+	}
+	for _, allow := range []func(){
+		func() { cfg.Hyperliquid.ConfirmMainnet = true },
+		func() {
+			cfg.Hyperliquid.ConfirmMainnet, cfg.Hyperliquid.Testnet, cfg.Hyperliquid.APIURL = false, true, ""
+		},
+		func() { cfg.Hyperliquid.Testnet, cfg.Trading.TradingEnabled = false, false },
+	} {
+		allow()
+		if err := checkNetwork(cfg); err != nil {
+			t.Errorf("unexpected refusal: %v", err)
+		}
+	}
+}
+
+func TestDecideBudgetExceeded(t *testing.T) {
+	cfg := loadTestConfig(t)
+	bot, err := NewTradingBot(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create bot: %v", err)
+	}
+	budget := ai.NewCostTracker(map[string]ai.ModelPrice{"model": {Input: 1e6}}, 1, 1, "")
+	budget.Record("ETH", "model", ai.Usage{PromptTokens: 2})
+	bot.aiDecision.SetBudget(budget, "hold", "")
+
+	candles := make([]indicators.MarketData, 150)
+	for i := range candles {
+		price := 2000.0 + float64(i)
+		candles[i] = indicators.MarketData{Open: price, High: price, Low: price, Close: price, Volume: 100}
+	}
+	analysis := &ai.MarketAnalysis{
+		Symbol:     "ETH",
+		Market:     &hyperliquid.MarketInfo{Symbol: "ETH", CurrentPrice: 2149},
+		Indicators: indicators.NewCalculator().Calculate(candles),
+		Position:   &hyperliquid.Position{Side: "NONE"},
+		Candles:    candles,
+	}
+
+	// Budget exhaustion goes through the strategy fallback like any AI failure
+	cfg.Strategy.Mode = "fallback"
+	decision, err := bot.decide(analysis)
+	if err != nil || decision.Reason == ai.BudgetHold().Reason {
+		t.Errorf("expected the strategy engine's decision, got %+v (%v)", decision, err)
+	}
+
+	cfg.Strategy.Mode = "off"
+	decision, err = bot.decide(analysis)
+	if err != nil || decision.Action != "HOLD" || decision.Reason != ai.BudgetHold().Reason {
+		t.Errorf("expected a budget hold without a fallback, got %+v (%v)", decision, err)
+	}
+}
+
+func TestDailyPnL(t *testing.T) {
+	now := time.Date(2024, 3, 5, 15, 0, 0, 0, time.Local)
+	start := startOfDay(now)
+	if start != time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local) {
+		t.Fatalf("unexpected start of day %v", start)
+	}
+
+	fills := []hyperliquid.Fill{
+		{Coin: "ETH", Time: start.Add(-time.Hour), ClosedPnL: -500}, // Yesterday's
+		{Coin: "ETH", Time: start.Add(2 * time.Hour), ClosedPnL: -120},
+		{Coin: "BTC", Time: start.Add(3 * time.Hour), ClosedPnL: 20}, // e.g. a take profit filled on the exchange
+	}
+	positions := []*hyperliquid.Position{{Symbol: "SOL", Side: "LONG", Size: 10, CurrentPnL: -30}}
+
+	if pnl := dailyPnL(fills, positions, start); pnl != -130 {
+		t.Errorf("expected -130 realized and unrealized, got %v", pnl)
+	}
+}
@@ -2,53 +2,78 @@ package risk
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"aitrading/ai"
 	"aitrading/config"
 	"aitrading/hyperliquid"
+	"aitrading/indicators"
 	"github.com/sirupsen/logrus"
 )
 
 // Controller handles risk management
 type Controller struct {
-	config        *config.RiskConfig
-	tradingConfig *config.TradingConfig
-	logger        *logrus.Logger
-	dailyPnL      float64
-	dailyPnLReset time.Time
-	maxDrawdown   float64
-	peakBalance   float64
+	config         *config.RiskConfig
+	tradingConfig  *config.TradingConfig
+	logger         *logrus.Logger
+	dailyPnL       float64
+	dailyPnLReset  time.Time
+	maxDrawdown    float64
+	peakBalance    float64
+	rules          []rule
+	candlesMu      sync.RWMutex
+	candles        map[string][]indicators.MarketData // Latest candle history per symbol, guarded by candlesMu
+	marginTiers    map[string][]hyperliquid.MarginTier
+	killSwitch     *KillSwitch
+	holdingPeriods map[string]string         // Expected holding period of each symbol's entry
+	activity       map[string]*tradeActivity // Recent trades per symbol for the cooldown rule
 }
 
 // NewController creates a new risk controller
 func NewController(riskCfg *config.RiskConfig, tradingCfg *config.TradingConfig, logger *logrus.Logger) *Controller {
-	return &Controller{
+	rc := &Controller{
 		config:        riskCfg,
 		tradingConfig: tradingCfg,
 		logger:        logger,
 		dailyPnLReset: time.Now(),
+		rules:         defaultRules(),
 	}
+	rc.loadActivity()
+	return rc
 }
 
 // RiskCheckResult represents the result of risk check
 type RiskCheckResult struct {
-	Approved       bool
-	Reason         string
-	AdjustedSize   float64
+	Approved         bool
+	Reason           string
+	AdjustedSize     float64
 	AdjustedLeverage int
-	StopLoss       float64
-	TakeProfit     float64
+	Quantity         float64 // Coin quantity for orders that add exposure
+	StopLoss         float64
+	TakeProfit       float64
+	Verdicts         []Verdict
 }
 
-// CheckDecision validates a trading decision against risk rules
-func (rc *Controller) CheckDecision(
-	decision *ai.Decision,
-	currentPrice float64,
-	accountBalance float64,
-	position *hyperliquid.Position,
-	openPositionCount int,
-) (*RiskCheckResult, error) {
+// DecisionContext carries everything the rules need to judge a decision
+type DecisionContext struct {
+	Symbol            string
+	Decision          *ai.Decision
+	CurrentPrice      float64
+	AccountBalance    float64 // Balance the order is sized from; the quote balance for spot
+	AccountValue      float64 // Perp account value for the account-wide limits; zero uses AccountBalance
+	Position          *hyperliquid.Position
+	OpenPositionCount int
+	Portfolio         *PortfolioSnapshot // Account-wide positions and margin; nil judges Position alone
+}
+
+// CheckDecision validates a trading decision against the risk rules in order.
+// Rules may adjust size or leverage; the first rejection stops the pipeline.
+func (rc *Controller) CheckDecision(ctx *DecisionContext) (*RiskCheckResult, error) {
+	decision := ctx.Decision
+	if ctx.Position == nil {
+		ctx.Position = &hyperliquid.Position{Symbol: ctx.Symbol, Side: "NONE"}
+	}
 
 	result := &RiskCheckResult{
 		Approved:         true,
@@ -61,164 +86,95 @@ func (rc *Controller) CheckDecision(
 	// Reset daily PnL if needed
 	rc.resetDailyPnLIfNeeded()
 
-	// Check 1: Maximum open positions
-	if decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT" {
-		if rc.tradingConfig.MaxOpenPositions > 0 && openPositionCount >= rc.tradingConfig.MaxOpenPositions {
+	limits := rc.LimitsFor(ctx.Symbol)
+	for _, r := range rc.rules {
+		verdict := r.check(rc, ctx, limits, result)
+		verdict.Rule = r.name
+		result.Verdicts = append(result.Verdicts, verdict)
+
+		if verdict.Status == VerdictReject {
 			result.Approved = false
-			result.Reason = fmt.Sprintf("Maximum open positions reached: %d >= %d",
-				openPositionCount, rc.tradingConfig.MaxOpenPositions)
-			rc.logger.Warn(result.Reason)
+			result.Reason = verdict.Message
+			rc.logger.WithFields(logrus.Fields{
+				"symbol": ctx.Symbol,
+				"rule":   r.name,
+			}).Warn(verdict.Message)
 			return result, nil
 		}
 	}
 
-	// Check 2: Leverage limit
-	if decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT" || decision.Action == "ADD_POSITION" {
-		if rc.tradingConfig.MaxLeverage > 0 && decision.Leverage > rc.tradingConfig.MaxLeverage {
-			result.AdjustedLeverage = rc.tradingConfig.MaxLeverage
-			rc.logger.WithFields(logrus.Fields{
-				"original_leverage": decision.Leverage,
-				"adjusted_leverage": result.AdjustedLeverage,
-			}).Warn("Leverage adjusted down to max limit")
-		}
+	if increasesExposure(decision.Action) && ctx.CurrentPrice > 0 {
+		result.Quantity = result.AdjustedSize * ctx.AccountBalance / ctx.CurrentPrice
 	}
 
-	// Check 3: Minimum confidence
-	if decision.Confidence < 0.6 {
-		result.Approved = false
-		result.Reason = fmt.Sprintf("Confidence too low: %.2f < 0.60", decision.Confidence)
-		rc.logger.Warn(result.Reason)
-		return result, nil
-	}
+	rc.logger.WithFields(logrus.Fields{
+		"symbol":        ctx.Symbol,
+		"approved":      result.Approved,
+		"adjusted_size": result.AdjustedSize,
+		"quantity":      result.Quantity,
+		"reason":        result.Reason,
+	}).Info("Risk check completed")
+
+	return result, nil
+}
+
+// SetKillSwitch attaches the trading state machine. Drawdown and daily loss
+// breaches escalate it, and decisions it does not allow are rejected.
+func (rc *Controller) SetKillSwitch(ks *KillSwitch) {
+	rc.killSwitch = ks
+}
+
+// KillSwitch returns the attached trading state machine, or nil
+func (rc *Controller) KillSwitch() *KillSwitch {
+	return rc.killSwitch
+}
 
-	// Check 4: Daily loss limit
-	if rc.dailyPnL < 0 && -rc.dailyPnL/accountBalance > rc.config.DailyLossLimit {
-		result.Approved = false
-		result.Reason = fmt.Sprintf("Daily loss limit exceeded: %.2f%% > %.2f%%",
-			-rc.dailyPnL/accountBalance*100, rc.config.DailyLossLimit*100)
-		rc.logger.Warn(result.Reason)
-		return result, nil
+// UpdateAccount checks account-level limits outside of any decision so the
+// kill switch trips even when no trade is proposed
+func (rc *Controller) UpdateAccount(accountBalance float64) {
+	rc.resetDailyPnLIfNeeded()
+	if accountBalance <= 0 {
+		return
 	}
 
-	// Check 5: Maximum drawdown
-	if rc.peakBalance > 0 {
-		currentDrawdown := (rc.peakBalance - accountBalance) / rc.peakBalance
-		if currentDrawdown > rc.config.MaxDrawdown {
-			result.Approved = false
-			result.Reason = fmt.Sprintf("Max drawdown exceeded: %.2f%% > %.2f%%",
-				currentDrawdown*100, rc.config.MaxDrawdown*100)
-			rc.logger.Warn(result.Reason)
-			return result, nil
-		}
+	if loss := rc.dailyLoss(accountBalance); rc.config.DailyLossLimit > 0 && loss > rc.config.DailyLossLimit {
+		rc.tripDailyLoss(loss)
+	}
+	if drawdown := rc.drawdown(accountBalance); rc.config.MaxDrawdown > 0 && drawdown > rc.config.MaxDrawdown {
+		rc.tripDrawdown(drawdown)
 	}
 
-	// Update peak balance
 	if accountBalance > rc.peakBalance {
 		rc.peakBalance = accountBalance
 	}
+}
 
-	// Check 6: Position size limits
-	if decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT" || decision.Action == "ADD_POSITION" {
-		// Check against max position size
-		if decision.Size > rc.config.PositionRiskPerTrade*10 { // Max 10x the per-trade risk
-			result.AdjustedSize = rc.config.PositionRiskPerTrade * 10
-			rc.logger.WithFields(logrus.Fields{
-				"original_size": decision.Size,
-				"adjusted_size": result.AdjustedSize,
-			}).Warn("Position size adjusted down")
-		}
-
-		// Check total exposure
-		currentExposure := 0.0
-		if position.Size > 0 {
-			currentExposure = (position.Size * currentPrice) / accountBalance
-		}
-
-		newExposure := currentExposure + result.AdjustedSize
-		if newExposure > rc.config.MaxTotalExposure {
-			result.Approved = false
-			result.Reason = fmt.Sprintf("Total exposure would exceed limit: %.2f%% > %.2f%%",
-				newExposure*100, rc.config.MaxTotalExposure*100)
-			rc.logger.Warn(result.Reason)
-			return result, nil
-		}
+// dailyLoss returns today's realized loss as a fraction of balance
+func (rc *Controller) dailyLoss(accountBalance float64) float64 {
+	if rc.dailyPnL >= 0 {
+		return 0
 	}
+	return -rc.dailyPnL / accountBalance
+}
 
-	// Check 7: Risk-reward ratio
-	if decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT" {
-		if decision.StopLoss > 0 && decision.TakeProfit > 0 {
-			var riskReward float64
-
-			if decision.Action == "OPEN_LONG" {
-				risk := currentPrice - decision.StopLoss
-				reward := decision.TakeProfit - currentPrice
-				if risk > 0 {
-					riskReward = reward / risk
-				}
-			} else {
-				risk := decision.StopLoss - currentPrice
-				reward := currentPrice - decision.TakeProfit
-				if risk > 0 {
-					riskReward = reward / risk
-				}
-			}
-
-			if riskReward < rc.config.MinRiskRewardRatio {
-				result.Approved = false
-				result.Reason = fmt.Sprintf("Risk-reward ratio too low: %.2f < %.2f",
-					riskReward, rc.config.MinRiskRewardRatio)
-				rc.logger.Warn(result.Reason)
-				return result, nil
-			}
-		}
+// drawdown returns the decline of balance from its peak
+func (rc *Controller) drawdown(accountBalance float64) float64 {
+	if rc.peakBalance <= 0 {
+		return 0
 	}
+	return (rc.peakBalance - accountBalance) / rc.peakBalance
+}
 
-	// Check 8: Stop loss validation
-	if decision.Action == "OPEN_LONG" || decision.Action == "OPEN_SHORT" {
-		if decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
-			result.Approved = false
-			result.Reason = "Stop loss and take profit must be set"
-			rc.logger.Warn(result.Reason)
-			return result, nil
-		}
-
-		// Validate stop loss is on correct side
-		if decision.Action == "OPEN_LONG" && decision.StopLoss >= currentPrice {
-			result.Approved = false
-			result.Reason = "Stop loss for long position must be below current price"
-			rc.logger.Warn(result.Reason)
-			return result, nil
-		}
-
-		if decision.Action == "OPEN_SHORT" && decision.StopLoss <= currentPrice {
-			result.Approved = false
-			result.Reason = "Stop loss for short position must be above current price"
-			rc.logger.Warn(result.Reason)
-			return result, nil
-		}
+func (rc *Controller) tripDailyLoss(loss float64) {
+	if rc.killSwitch != nil {
+		rc.killSwitch.OnDailyLoss(fmt.Sprintf("daily loss %.2f%% > %.2f%%", loss*100, rc.config.DailyLossLimit*100))
 	}
+}
 
-	// Check 9: Position holding time limits
-	if position != nil && position.Size > 0 {
-		maxHoldingTime := rc.getMaxHoldingTime(decision.ExpectedHoldingPeriod)
-		if position.HoldingTime > maxHoldingTime {
-			// Force close if holding too long
-			if decision.Action != "CLOSE_POSITION" {
-				rc.logger.WithFields(logrus.Fields{
-					"holding_time": position.HoldingTime,
-					"max_time":     maxHoldingTime,
-				}).Warn("Position held too long, should consider closing")
-			}
-		}
+func (rc *Controller) tripDrawdown(drawdown float64) {
+	if rc.killSwitch != nil {
+		rc.killSwitch.OnDrawdown(fmt.Sprintf("drawdown %.2f%% > %.2f%%", drawdown*100, rc.config.MaxDrawdown*100))
 	}
-
-	rc.logger.WithFields(logrus.Fields{
-		"approved":      result.Approved,
-		"adjusted_size": result.AdjustedSize,
-		"reason":        result.Reason,
-	}).Info("Risk check completed")
-
-	return result, nil
 }
 
 // UpdatePnL updates the daily PnL tracking
@@ -248,18 +204,78 @@ func (rc *Controller) resetDailyPnLIfNeeded() {
 	}
 }
 
-// getMaxHoldingTime returns maximum holding time based on expected period
-func (rc *Controller) getMaxHoldingTime(expectedPeriod string) time.Duration {
-	switch expectedPeriod {
-	case "SHORT":
-		return 4 * time.Hour
-	case "MEDIUM":
-		return 24 * time.Hour
-	case "LONG":
-		return 7 * 24 * time.Hour
-	default:
-		return 24 * time.Hour
+// defaultHoldingTimes are the limits per expected holding period when unconfigured
+var defaultHoldingTimes = map[string]time.Duration{
+	"SHORT":  4 * time.Hour,
+	"MEDIUM": 24 * time.Hour,
+	"LONG":   7 * 24 * time.Hour,
+}
+
+// MaxHoldingTime returns the holding time limit for symbol and an expected
+// holding period, applying the symbol override. Unknown periods use MEDIUM.
+func (rc *Controller) MaxHoldingTime(symbol, expectedPeriod string) time.Duration {
+	if _, ok := defaultHoldingTimes[expectedPeriod]; !ok {
+		expectedPeriod = "MEDIUM"
+	}
+
+	override := rc.tradingConfig.Override(symbol).HoldingTime
+	for _, configured := range []config.HoldingTimeConfig{override, rc.config.HoldingTime} {
+		value := map[string]string{
+			"SHORT":  configured.Short,
+			"MEDIUM": configured.Medium,
+			"LONG":   configured.Long,
+		}[expectedPeriod]
+		if value == "" {
+			continue
+		}
+		limit, err := time.ParseDuration(value)
+		if err != nil || limit <= 0 {
+			rc.logger.WithField("holding_time", value).Warn("Invalid holding time, ignoring")
+			continue
+		}
+		return limit
+	}
+
+	return defaultHoldingTimes[expectedPeriod]
+}
+
+// RecordEntry remembers the expected holding period of a symbol's entry once
+// it has executed
+func (rc *Controller) RecordEntry(symbol, expectedPeriod string) {
+	if rc.holdingPeriods == nil {
+		rc.holdingPeriods = make(map[string]string)
+	}
+	rc.holdingPeriods[symbol] = expectedPeriod
+}
+
+// HoldingLimit returns the holding time limit of the symbol's open position,
+// using the entry's expected period or fallback when the entry is unknown
+func (rc *Controller) HoldingLimit(symbol, fallback string) time.Duration {
+	period, ok := rc.holdingPeriods[symbol]
+	if !ok {
+		period = fallback
+	}
+	return rc.MaxHoldingTime(symbol, period)
+}
+
+// CheckHoldingTime reports whether a position must be closed for exceeding
+// its holding time limit. It is false unless holding_time.enforce is set.
+func (rc *Controller) CheckHoldingTime(symbol string, position *hyperliquid.Position) bool {
+	if !rc.config.HoldingTime.Enforce || position.Size == 0 || position.OpenTime.IsZero() {
+		return false
+	}
+
+	limit := rc.HoldingLimit(symbol, "")
+	if position.HoldingTime <= limit {
+		return false
 	}
+
+	rc.logger.WithFields(logrus.Fields{
+		"symbol":       symbol,
+		"holding_time": position.HoldingTime,
+		"max_time":     limit,
+	}).Warn("Max holding time exceeded")
+	return true
 }
 
 // CheckStopLoss checks if position should be closed due to stop loss
@@ -12,17 +12,17 @@ import (
 
 func TestNewController(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel) // Quiet for tests
 
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 	if rc == nil {
 		t.Fatal("NewController should not return nil")
 	}
@@ -30,16 +30,16 @@ func TestNewController(t *testing.T) {
 
 func TestCheckDecision_LowConfidence(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{MinConfidence: 0.6}, logger)
 
 	decision := &ai.Decision{
 		Action:     "OPEN_LONG",
@@ -55,7 +55,13 @@ func TestCheckDecision_LowConfidence(t *testing.T) {
 		Size:   0,
 	}
 
-	result, err := rc.CheckDecision(decision, 2000.0, 10000.0, position)
+	result, err := rc.CheckDecision(&DecisionContext{
+		Symbol:         "ETH",
+		Decision:       decision,
+		CurrentPrice:   2000.0,
+		AccountBalance: 10000.0,
+		Position:       position,
+	})
 	if err != nil {
 		t.Fatalf("CheckDecision should not error: %v", err)
 	}
@@ -67,16 +73,16 @@ func TestCheckDecision_LowConfidence(t *testing.T) {
 
 func TestCheckDecision_ValidLongPosition(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 
 	decision := &ai.Decision{
 		Action:     "OPEN_LONG",
@@ -92,7 +98,13 @@ func TestCheckDecision_ValidLongPosition(t *testing.T) {
 		Size:   0,
 	}
 
-	result, err := rc.CheckDecision(decision, 2000.0, 10000.0, position)
+	result, err := rc.CheckDecision(&DecisionContext{
+		Symbol:         "ETH",
+		Decision:       decision,
+		CurrentPrice:   2000.0,
+		AccountBalance: 10000.0,
+		Position:       position,
+	})
 	if err != nil {
 		t.Fatalf("CheckDecision should not error: %v", err)
 	}
@@ -104,16 +116,16 @@ func TestCheckDecision_ValidLongPosition(t *testing.T) {
 
 func TestCheckDecision_InvalidStopLoss(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 
 	decision := &ai.Decision{
 		Action:     "OPEN_LONG",
@@ -129,7 +141,13 @@ func TestCheckDecision_InvalidStopLoss(t *testing.T) {
 		Size:   0,
 	}
 
-	result, err := rc.CheckDecision(decision, 2000.0, 10000.0, position)
+	result, err := rc.CheckDecision(&DecisionContext{
+		Symbol:         "ETH",
+		Decision:       decision,
+		CurrentPrice:   2000.0,
+		AccountBalance: 10000.0,
+		Position:       position,
+	})
 	if err != nil {
 		t.Fatalf("CheckDecision should not error: %v", err)
 	}
@@ -141,16 +159,16 @@ func TestCheckDecision_InvalidStopLoss(t *testing.T) {
 
 func TestCheckStopLoss(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 
 	// Test long position stop loss
 	position := &hyperliquid.Position{
@@ -185,16 +203,16 @@ func TestCheckStopLoss(t *testing.T) {
 
 func TestCheckTakeProfit(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 
 	// Test long position take profit
 	position := &hyperliquid.Position{
@@ -229,16 +247,16 @@ func TestCheckTakeProfit(t *testing.T) {
 
 func TestUpdatePnL(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 
 	// Update with profit
 	rc.UpdatePnL(100)
@@ -255,16 +273,16 @@ func TestUpdatePnL(t *testing.T) {
 
 func TestDailyPnLReset(t *testing.T) {
 	cfg := &config.RiskConfig{
-		MaxDrawdown:         0.05,
-		DailyLossLimit:      0.02,
+		MaxDrawdown:          0.05,
+		DailyLossLimit:       0.02,
 		PositionRiskPerTrade: 0.01,
-		MaxTotalExposure:    0.25,
-		MinRiskRewardRatio:  2.0,
+		MaxTotalExposure:     0.25,
+		MinRiskRewardRatio:   2.0,
 	}
 
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
-	rc := NewController(cfg, logger)
+	rc := NewController(cfg, &config.TradingConfig{}, logger)
 
 	// Set some PnL
 	rc.UpdatePnL(100)
@@ -278,3 +296,131 @@ func TestDailyPnLReset(t *testing.T) {
 		t.Errorf("Daily PnL should be reset to 0, got %f", pnl)
 	}
 }
+
+// openLong returns a valid 2:1 long at price 2000
+func openLong(confidence, size float64) *ai.Decision {
+	return &ai.Decision{
+		Action:     "OPEN_LONG",
+		Confidence: confidence,
+		Size:       size,
+		Leverage:   3,
+		StopLoss:   1900,
+		TakeProfit: 2200,
+	}
+}
+
+func checkOpenLong(rc *Controller, symbol string, decision *ai.Decision) *RiskCheckResult {
+	result, _ := rc.CheckDecision(&DecisionContext{
+		Symbol:         symbol,
+		Decision:       decision,
+		CurrentPrice:   2000.0,
+		AccountBalance: 10000.0,
+	})
+	return result
+}
+
+func TestCheckDecision_MinConfidenceFromConfig(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2}
+
+	strict := NewController(riskCfg, &config.TradingConfig{MinConfidence: 0.8}, logger)
+	if result := checkOpenLong(strict, "ETH", openLong(0.7, 0.05)); result.Approved {
+		t.Error("confidence 0.7 should be rejected with min_confidence 0.8")
+	}
+
+	relaxed := NewController(riskCfg, &config.TradingConfig{MinConfidence: 0.5}, logger)
+	if result := checkOpenLong(relaxed, "ETH", openLong(0.7, 0.05)); !result.Approved {
+		t.Errorf("confidence 0.7 should pass with min_confidence 0.5: %s", result.Reason)
+	}
+}
+
+func TestCheckDecision_MaxPositionSizeFromConfig(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2, PositionRiskPerTrade: 0.01}
+
+	rc := NewController(riskCfg, &config.TradingConfig{MaxPositionSize: 0.2}, logger)
+	result := checkOpenLong(rc, "ETH", openLong(0.8, 0.15))
+	if !result.Approved || result.AdjustedSize != 0.15 {
+		t.Errorf("size 0.15 should pass unchanged under max_position_size 0.2, got %v %.2f", result.Approved, result.AdjustedSize)
+	}
+
+	rc = NewController(riskCfg, &config.TradingConfig{MaxPositionSize: 0.1}, logger)
+	result = checkOpenLong(rc, "ETH", openLong(0.8, 0.15))
+	if !result.Approved || result.AdjustedSize != 0.1 {
+		t.Errorf("size should be capped to 0.1, got %v %.2f", result.Approved, result.AdjustedSize)
+	}
+}
+
+func TestCheckDecision_SymbolOverride(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2}
+	tradingCfg := &config.TradingConfig{
+		MinConfidence:   0.6,
+		MaxPositionSize: 0.2,
+		MaxLeverage:     10,
+		SymbolOverrides: map[string]config.SymbolOverride{
+			"DOGE": {MinConfidence: 0.8, MaxPositionSize: 0.05, MaxLeverage: 2},
+		},
+	}
+	rc := NewController(riskCfg, tradingCfg, logger)
+
+	if result := checkOpenLong(rc, "ETH", openLong(0.7, 0.1)); !result.Approved || result.AdjustedSize != 0.1 {
+		t.Errorf("ETH should use global limits: %v %s", result.Approved, result.Reason)
+	}
+	if result := checkOpenLong(rc, "DOGE", openLong(0.7, 0.1)); result.Approved {
+		t.Error("DOGE should be rejected by its min_confidence override")
+	}
+
+	result := checkOpenLong(rc, "DOGE", openLong(0.9, 0.1))
+	if !result.Approved || result.AdjustedSize != 0.05 || result.AdjustedLeverage != 2 {
+		t.Errorf("DOGE should be capped by its overrides, got size %.2f leverage %d", result.AdjustedSize, result.AdjustedLeverage)
+	}
+}
+
+func TestCheckDecision_Verdicts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2}
+	rc := NewController(riskCfg, &config.TradingConfig{MinConfidence: 0.6, MaxPositionSize: 0.1}, logger)
+
+	result := checkOpenLong(rc, "ETH", openLong(0.8, 0.15))
+	if len(result.Verdicts) != len(rc.rules) {
+		t.Fatalf("expected a verdict per rule, got %d", len(result.Verdicts))
+	}
+	statuses := make(map[string]VerdictStatus)
+	for _, v := range result.Verdicts {
+		statuses[v.Rule] = v.Status
+	}
+	if statuses["max_position_size"] != VerdictAdjust || statuses["min_confidence"] != VerdictPass {
+		t.Errorf("unexpected verdicts: %+v", result.Verdicts)
+	}
+
+	result = checkOpenLong(rc, "ETH", openLong(0.5, 0.05))
+	last := result.Verdicts[len(result.Verdicts)-1]
+	if last.Rule != "min_confidence" || last.Status != VerdictReject || last.Message != result.Reason {
+		t.Errorf("pipeline should stop at the rejecting rule, got %+v", last)
+	}
+}
+
+func TestCheckDecision_SpotMarket(t *testing.T) {
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2}
+	rc := NewController(riskCfg, &config.TradingConfig{}, quietLogger())
+
+	result := checkOpenLong(rc, "HYPE/USDC", openLong(0.8, 0.05))
+	if !result.Approved || result.AdjustedLeverage != 1 {
+		t.Errorf("a spot long should be approved unlevered, got %+v", result)
+	}
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); result.AdjustedLeverage != 3 {
+		t.Errorf("perp leverage should be unchanged, got %dx", result.AdjustedLeverage)
+	}
+
+	short := openLong(0.8, 0.05)
+	short.Action = "OPEN_SHORT"
+	short.StopLoss, short.TakeProfit = 2100, 1800
+	if result := checkOpenLong(rc, "HYPE/USDC", short); result.Approved {
+		t.Error("a spot short should be rejected")
+	}
+}
@@ -0,0 +1,297 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+// flatSize is the position size treated as flat, absorbing float rounding
+const flatSize = 1e-9
+
+// tradeActivity is the recent trading history of one symbol
+type tradeActivity struct {
+	lastClose  time.Time
+	stoppedOut bool // The last close was a stop-out
+	lastEntry  time.Time
+	lastSide   string // Side of the last open, LONG or SHORT
+	day        time.Time
+	trades     int // Opens and adds on day
+}
+
+// CooldownStatus is what the anti-churn limits allow for a symbol right now
+type CooldownStatus struct {
+	Reentry         time.Duration // Remaining before the symbol may be reopened
+	StoppedOut      bool          // Reentry follows a stop-out
+	Flip            time.Duration // Remaining before an entry opposite LastSide
+	LastSide        string
+	TradesToday     int
+	MaxTradesPerDay int
+}
+
+// Active reports whether any limit currently blocks new entries
+func (s CooldownStatus) Active() bool {
+	return s.Reentry > 0 || s.Flip > 0 || s.TradesExhausted()
+}
+
+// TradesExhausted reports whether the daily trade limit is reached
+func (s CooldownStatus) TradesExhausted() bool {
+	return s.MaxTradesPerDay > 0 && s.TradesToday >= s.MaxTradesPerDay
+}
+
+// cooldownFor resolves symbol's cooldown settings, each override field
+// replacing the global one when set
+func (rc *Controller) cooldownFor(symbol string) config.CooldownConfig {
+	cooldown := rc.config.Cooldown
+	override := rc.tradingConfig.Override(symbol).Cooldown
+	if override.AfterClose > 0 {
+		cooldown.AfterClose = override.AfterClose
+	}
+	if override.AfterStopLoss > 0 {
+		cooldown.AfterStopLoss = override.AfterStopLoss
+	}
+	if override.MinFlipInterval > 0 {
+		cooldown.MinFlipInterval = override.MinFlipInterval
+	}
+	if override.MaxTradesPerDay > 0 {
+		cooldown.MaxTradesPerDay = override.MaxTradesPerDay
+	}
+	return cooldown
+}
+
+// activityFor returns symbol's trading history, creating it when missing
+func (rc *Controller) activityFor(symbol string) *tradeActivity {
+	if rc.activity == nil {
+		rc.activity = make(map[string]*tradeActivity)
+	}
+	activity, ok := rc.activity[symbol]
+	if !ok {
+		activity = &tradeActivity{}
+		rc.activity[symbol] = activity
+	}
+	return activity
+}
+
+// RecordTrade records an executed action for the anti-churn limits. stopOut
+// marks a close triggered by the stop loss.
+func (rc *Controller) RecordTrade(symbol, action string, stopOut bool) {
+	activity := rc.activityFor(symbol)
+	defer rc.saveActivity()
+
+	now := time.Now()
+	switch {
+	case action == "CLOSE_POSITION":
+		activity.lastClose = now
+		activity.stoppedOut = stopOut
+	case increasesExposure(action):
+		if !sameDay(activity.day, now) {
+			activity.day = now
+			activity.trades = 0
+		}
+		activity.trades++
+		if isOpen(action) {
+			activity.lastEntry = now
+			activity.lastSide = openSide(action)
+		}
+	}
+}
+
+// RecordFills starts the re-entry cooldown of symbol from fills of coin that
+// closed its position, so closes the bot did not execute itself, such as
+// exchange-side stop losses, liquidations and manual trades, count too. A
+// close at a loss counts as a stop-out. Closes no later than the last one
+// recorded are skipped, so fills can be passed again every cycle.
+func (rc *Controller) RecordFills(symbol, coin string, fills []hyperliquid.Fill) {
+	var lastClose *hyperliquid.Fill
+	for i, fill := range fills {
+		start, end := fill.StartPosition, fill.EndPosition()
+		closed := math.Abs(start) >= flatSize && (math.Abs(end) < flatSize || (start > 0) != (end > 0))
+		if fill.Coin == coin && closed && (lastClose == nil || fill.Time.After(lastClose.Time)) {
+			lastClose = &fills[i]
+		}
+	}
+	if lastClose == nil {
+		return
+	}
+
+	activity := rc.activityFor(symbol)
+	if !lastClose.Time.After(activity.lastClose) {
+		return
+	}
+	activity.lastClose = lastClose.Time
+	activity.stoppedOut = lastClose.ClosedPnL < 0
+	rc.saveActivity()
+
+	rc.logger.WithFields(logrus.Fields{
+		"symbol":      symbol,
+		"closed_at":   lastClose.Time,
+		"stopped_out": activity.stoppedOut,
+	}).Info("Position close recorded from fills")
+}
+
+// Cooldown returns the anti-churn status of symbol
+func (rc *Controller) Cooldown(symbol string) CooldownStatus {
+	cooldown := rc.cooldownFor(symbol)
+	status := CooldownStatus{MaxTradesPerDay: cooldown.MaxTradesPerDay}
+
+	activity, ok := rc.activity[symbol]
+	if !ok {
+		return status
+	}
+
+	now := time.Now()
+	if !activity.lastClose.IsZero() {
+		wait := cooldown.AfterClose
+		if activity.stoppedOut && cooldown.AfterStopLoss > 0 {
+			wait = cooldown.AfterStopLoss
+		}
+		status.Reentry = remaining(activity.lastClose, wait, now)
+		status.StoppedOut = activity.stoppedOut && status.Reentry > 0
+	}
+	if activity.lastSide != "" {
+		status.LastSide = activity.lastSide
+		status.Flip = remaining(activity.lastEntry, cooldown.MinFlipInterval, now)
+	}
+	if sameDay(activity.day, now) {
+		status.TradesToday = activity.trades
+	}
+	return status
+}
+
+// savedActivity is a tradeActivity as persisted in the cooldown state file
+type savedActivity struct {
+	LastClose  time.Time `json:"last_close"`
+	StoppedOut bool      `json:"stopped_out,omitempty"`
+	LastEntry  time.Time `json:"last_entry"`
+	LastSide   string    `json:"last_side,omitempty"`
+	Day        time.Time `json:"day"`
+	Trades     int       `json:"trades,omitempty"`
+}
+
+// loadActivity restores the trading history saved in the cooldown state file
+func (rc *Controller) loadActivity() {
+	stateFile := rc.config.Cooldown.StateFile
+	if stateFile == "" {
+		return
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			rc.logger.WithError(err).Warn("Failed to load cooldowns")
+		}
+		return
+	}
+
+	var saved map[string]savedActivity
+	if err := json.Unmarshal(data, &saved); err != nil {
+		rc.logger.WithError(err).Warn("Failed to parse cooldowns")
+		return
+	}
+	rc.activity = make(map[string]*tradeActivity, len(saved))
+	for symbol, a := range saved {
+		rc.activity[symbol] = &tradeActivity{
+			lastClose:  a.LastClose,
+			stoppedOut: a.StoppedOut,
+			lastEntry:  a.LastEntry,
+			lastSide:   a.LastSide,
+			day:        a.Day,
+			trades:     a.Trades,
+		}
+	}
+}
+
+// saveActivity writes the trading history to the cooldown state file
+func (rc *Controller) saveActivity() {
+	stateFile := rc.config.Cooldown.StateFile
+	if stateFile == "" {
+		return
+	}
+	if err := writeActivity(stateFile, rc.activity); err != nil {
+		rc.logger.WithError(err).Warn("Failed to persist cooldowns")
+	}
+}
+
+func writeActivity(stateFile string, activity map[string]*tradeActivity) error {
+	saved := make(map[string]savedActivity, len(activity))
+	for symbol, a := range activity {
+		saved[symbol] = savedActivity{
+			LastClose:  a.lastClose,
+			StoppedOut: a.stoppedOut,
+			LastEntry:  a.lastEntry,
+			LastSide:   a.lastSide,
+			Day:        a.day,
+			Trades:     a.trades,
+		}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cooldowns: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create cooldown state directory: %w", err)
+	}
+	if err := os.WriteFile(stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cooldowns: %w", err)
+	}
+	return nil
+}
+
+// remaining returns how much of a wait of seconds started at since is left
+func remaining(since time.Time, seconds int, now time.Time) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	left := since.Add(time.Duration(seconds) * time.Second).Sub(now)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// openSide returns the position side an open action creates
+func openSide(action string) string {
+	if action == "OPEN_SHORT" {
+		return "SHORT"
+	}
+	return "LONG"
+}
+
+// checkCooldown rejects entries during a re-entry cooldown, direction flips
+// within min_flip_interval and trades beyond max_trades_per_day
+func checkCooldown(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	action := ctx.Decision.Action
+	if !increasesExposure(action) {
+		return skip()
+	}
+
+	status := rc.Cooldown(ctx.Symbol)
+	if status.TradesExhausted() {
+		return reject("Max trades per day reached: %d/%d", status.TradesToday, status.MaxTradesPerDay)
+	}
+	if !isOpen(action) {
+		return pass()
+	}
+
+	if status.Reentry > 0 {
+		cause := "close"
+		if status.StoppedOut {
+			cause = "stop-out"
+		}
+		return reject("Cooldown after %s: %s remaining", cause, status.Reentry.Round(time.Second))
+	}
+	if status.Flip > 0 && openSide(action) != status.LastSide {
+		return reject("Direction flip from %s too soon: %s remaining", status.LastSide, status.Flip.Round(time.Second))
+	}
+	return pass()
+}
@@ -0,0 +1,120 @@
+package risk
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aitrading/config"
+	"aitrading/hyperliquid"
+)
+
+func cooldownController(cooldown config.CooldownConfig) *Controller {
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2, Cooldown: cooldown}
+	return NewController(riskCfg, &config.TradingConfig{}, quietLogger())
+}
+
+func TestCheckDecision_CooldownAfterClose(t *testing.T) {
+	rc := cooldownController(config.CooldownConfig{AfterClose: 600, AfterStopLoss: 3600})
+
+	rc.RecordTrade("ETH", "CLOSE_POSITION", false)
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); result.Approved {
+		t.Error("reopening right after a close should be rejected")
+	}
+	if result := checkOpenLong(rc, "BTC", openLong(0.8, 0.05)); !result.Approved {
+		t.Errorf("cooldown should only apply to the closed symbol: %s", result.Reason)
+	}
+
+	rc.activity["ETH"].lastClose = time.Now().Add(-11 * time.Minute)
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); !result.Approved {
+		t.Errorf("cooldown should expire after after_close: %s", result.Reason)
+	}
+
+	rc.RecordTrade("ETH", "CLOSE_POSITION", true)
+	rc.activity["ETH"].lastClose = time.Now().Add(-11 * time.Minute)
+	if status := rc.Cooldown("ETH"); !status.StoppedOut || status.Reentry < 48*time.Minute {
+		t.Errorf("a stop-out should use after_stop_loss, got %+v", status)
+	}
+}
+
+func TestCheckDecision_MinFlipInterval(t *testing.T) {
+	rc := cooldownController(config.CooldownConfig{MinFlipInterval: 3600})
+
+	rc.RecordTrade("ETH", "OPEN_LONG", false)
+	rc.RecordTrade("ETH", "CLOSE_POSITION", false)
+	if result := checkOpenLong(rc, "ETH", openShort(0.05)); result.Approved {
+		t.Error("flipping to short within min_flip_interval should be rejected")
+	}
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); !result.Approved {
+		t.Errorf("reopening the same direction should pass: %s", result.Reason)
+	}
+}
+
+func TestCheckDecision_MaxTradesPerDay(t *testing.T) {
+	rc := cooldownController(config.CooldownConfig{MaxTradesPerDay: 2})
+	rc.tradingConfig.SymbolOverrides = map[string]config.SymbolOverride{
+		"DOGE": {Cooldown: config.CooldownConfig{MaxTradesPerDay: 1}},
+	}
+
+	rc.RecordTrade("ETH", "OPEN_LONG", false)
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); !result.Approved {
+		t.Errorf("second trade should pass: %s", result.Reason)
+	}
+	rc.RecordTrade("ETH", "ADD_POSITION", false)
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); result.Approved {
+		t.Error("third trade should be rejected by max_trades_per_day")
+	}
+
+	rc.RecordTrade("DOGE", "OPEN_LONG", false)
+	if result := checkOpenLong(rc, "DOGE", openLong(0.9, 0.05)); result.Approved {
+		t.Error("DOGE should use its override of one trade per day")
+	}
+
+	rc.activity["ETH"].day = time.Now().AddDate(0, 0, -1)
+	if status := rc.Cooldown("ETH"); status.TradesToday != 0 || status.Active() {
+		t.Errorf("trade count should reset on a new day, got %+v", status)
+	}
+}
+
+func TestRecordFillsStartsCooldown(t *testing.T) {
+	rc := cooldownController(config.CooldownConfig{AfterClose: 600, AfterStopLoss: 3600})
+	now := time.Now()
+	fills := []hyperliquid.Fill{
+		{Coin: "ETH", Side: "B", Size: 1, StartPosition: 0, Time: now.Add(-time.Hour)},
+		{Coin: "ETH", Side: "A", Size: 0.5, StartPosition: 1, Time: now.Add(-50 * time.Minute), ClosedPnL: 10},
+		// The exchange-side stop loss closing the rest
+		{Coin: "ETH", Side: "A", Size: 0.5, StartPosition: 0.5, Time: now.Add(-5 * time.Minute), ClosedPnL: -40},
+		{Coin: "BTC", Side: "B", Size: 0.1, StartPosition: 0, Time: now.Add(-time.Minute)},
+	}
+
+	rc.RecordFills("ETH", "ETH", fills)
+	rc.RecordFills("BTC", "BTC", fills)
+	if status := rc.Cooldown("ETH"); !status.StoppedOut || status.Reentry < 54*time.Minute {
+		t.Errorf("a losing close from fills should start the stop-out cooldown, got %+v", status)
+	}
+	if status := rc.Cooldown("BTC"); status.Active() {
+		t.Errorf("an open is not a close, got %+v", status)
+	}
+
+	// A close the bot recorded itself is not overwritten by its older fill
+	rc.RecordTrade("ETH", "CLOSE_POSITION", false)
+	rc.RecordFills("ETH", "ETH", fills)
+	if status := rc.Cooldown("ETH"); status.StoppedOut {
+		t.Errorf("the bot's own close should stand, got %+v", status)
+	}
+}
+
+func TestCooldownsPersist(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "cooldowns.json")
+	cooldown := config.CooldownConfig{AfterStopLoss: 3600, MaxTradesPerDay: 2, StateFile: stateFile}
+
+	rc := cooldownController(cooldown)
+	rc.RecordTrade("ETH", "OPEN_LONG", false)
+	rc.RecordTrade("ETH", "CLOSE_POSITION", true)
+
+	restarted := cooldownController(cooldown)
+	status := restarted.Cooldown("ETH")
+	if !status.StoppedOut || status.Reentry < 59*time.Minute || status.TradesToday != 1 || status.LastSide != "LONG" {
+		t.Errorf("cooldowns should survive a restart, got %+v", status)
+	}
+}
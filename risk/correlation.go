@@ -0,0 +1,107 @@
+package risk
+
+import (
+	"math"
+
+	"aitrading/indicators"
+)
+
+const (
+	defaultCorrelationWindow = 100
+	minCorrelationSamples    = 20
+)
+
+// RecordCandles stores the latest candle history of a symbol for correlation checks
+func (rc *Controller) RecordCandles(symbol string, candles []indicators.MarketData) {
+	rc.candlesMu.Lock()
+	defer rc.candlesMu.Unlock()
+
+	if rc.candles == nil {
+		rc.candles = make(map[string][]indicators.MarketData)
+	}
+	rc.candles[symbol] = candles
+}
+
+// HasCandles reports whether candle history is known for symbol
+func (rc *Controller) HasCandles(symbol string) bool {
+	return len(rc.candleHistory(symbol)) > 0
+}
+
+// candleHistory returns the recorded candles of symbol. Recorded slices are
+// replaced, never modified, so they are safe to read after unlocking.
+func (rc *Controller) candleHistory(symbol string) []indicators.MarketData {
+	rc.candlesMu.RLock()
+	defer rc.candlesMu.RUnlock()
+	return rc.candles[symbol]
+}
+
+// Correlation returns the Pearson correlation of candle returns between two
+// symbols over the configured window. ok is false without enough aligned history.
+func (rc *Controller) Correlation(a, b string) (corr float64, ok bool) {
+	window := rc.config.CorrelationWindow
+	if window <= 0 {
+		window = defaultCorrelationWindow
+	}
+
+	x, y := alignedReturns(rc.candleHistory(a), rc.candleHistory(b), window)
+	if len(x) < minCorrelationSamples {
+		return 0, false
+	}
+	return pearson(x, y), true
+}
+
+// alignedReturns matches candles by timestamp and returns the last window
+// simple returns of both series
+func alignedReturns(a, b []indicators.MarketData, window int) ([]float64, []float64) {
+	closes := make(map[int64]float64, len(b))
+	for _, c := range b {
+		closes[c.Timestamp] = c.Close
+	}
+
+	var xa, xb []float64
+	for _, c := range a {
+		if other, found := closes[c.Timestamp]; found {
+			xa = append(xa, c.Close)
+			xb = append(xb, other)
+		}
+	}
+
+	if len(xa) > window+1 {
+		xa = xa[len(xa)-window-1:]
+		xb = xb[len(xb)-window-1:]
+	}
+
+	var ra, rb []float64
+	for i := 1; i < len(xa); i++ {
+		if xa[i-1] <= 0 || xb[i-1] <= 0 {
+			continue
+		}
+		ra = append(ra, xa[i]/xa[i-1]-1)
+		rb = append(rb, xb[i]/xb[i-1]-1)
+	}
+	return ra, rb
+}
+
+// pearson returns the correlation coefficient of two equal-length series
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,133 @@
+package risk
+
+import (
+	"math"
+	"testing"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"aitrading/indicators"
+	"github.com/sirupsen/logrus"
+)
+
+// candleSeries builds hourly candles whose returns follow step(i)
+func candleSeries(n int, step func(i int) float64) []indicators.MarketData {
+	candles := make([]indicators.MarketData, n)
+	price := 100.0
+	for i := range candles {
+		price *= 1 + step(i)
+		candles[i] = indicators.MarketData{Timestamp: int64(i) * 3600000, Close: price}
+	}
+	return candles
+}
+
+func wave(i int) float64     { return 0.01 * math.Sin(float64(i)) }
+func offWave(i int) float64  { return 0.01 * math.Cos(float64(i)*7) }
+func antiWave(i int) float64 { return -wave(i) }
+
+func newCorrelationController(action string) *Controller {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	rc := NewController(&config.RiskConfig{
+		MaxTotalExposure:   1,
+		MinRiskRewardRatio: 2,
+		CorrelationLimit:   0.8,
+		CorrelationAction:  action,
+	}, &config.TradingConfig{}, logger)
+
+	rc.RecordCandles("ETH", candleSeries(150, wave))
+	rc.RecordCandles("BTC", candleSeries(150, wave))
+	rc.RecordCandles("SOL", candleSeries(150, offWave))
+	rc.RecordCandles("XRP", candleSeries(150, antiWave))
+	return rc
+}
+
+func checkWithPositions(rc *Controller, decision *ai.Decision, positions ...*hyperliquid.Position) *RiskCheckResult {
+	result, _ := rc.CheckDecision(&DecisionContext{
+		Symbol:            "ETH",
+		Decision:          decision,
+		CurrentPrice:      2000,
+		AccountBalance:    10000,
+		OpenPositionCount: len(positions),
+		Portfolio:         &PortfolioSnapshot{Positions: positions},
+	})
+	return result
+}
+
+func TestCorrelation(t *testing.T) {
+	rc := newCorrelationController("")
+
+	if corr, ok := rc.Correlation("ETH", "BTC"); !ok || corr < 0.99 {
+		t.Errorf("identical series should be fully correlated, got %.2f", corr)
+	}
+	if corr, ok := rc.Correlation("ETH", "XRP"); !ok || corr > -0.99 {
+		t.Errorf("mirrored series should be anti-correlated, got %.2f", corr)
+	}
+	if _, ok := rc.Correlation("ETH", "DOGE"); ok {
+		t.Error("missing history should not yield a correlation")
+	}
+}
+
+func TestCheckDecision_CorrelationReject(t *testing.T) {
+	rc := newCorrelationController("reject")
+
+	btcLong := &hyperliquid.Position{Symbol: "BTC", Side: "LONG", Size: 1}
+	if result := checkWithPositions(rc, openLong(0.8, 0.1), btcLong); result.Approved {
+		t.Error("long ETH next to a correlated BTC long should be rejected")
+	}
+
+	btcShort := &hyperliquid.Position{Symbol: "BTC", Side: "SHORT", Size: 1}
+	if result := checkWithPositions(rc, openLong(0.8, 0.1), btcShort); !result.Approved {
+		t.Errorf("opposite direction hedge should pass: %s", result.Reason)
+	}
+
+	xrpShort := &hyperliquid.Position{Symbol: "XRP", Side: "SHORT", Size: 1}
+	if result := checkWithPositions(rc, openLong(0.8, 0.1), xrpShort); result.Approved {
+		t.Error("long ETH next to a short in an anti-correlated symbol should be rejected")
+	}
+
+	solLong := &hyperliquid.Position{Symbol: "SOL", Side: "LONG", Size: 1}
+	if result := checkWithPositions(rc, openLong(0.8, 0.1), solLong); !result.Approved {
+		t.Errorf("uncorrelated position should pass: %s", result.Reason)
+	}
+}
+
+func TestCheckDecision_CorrelationReduce(t *testing.T) {
+	rc := newCorrelationController("reduce")
+	rc.RecordCandles("BTC", candleSeries(150, func(i int) float64 { return 0.9*wave(i) + 0.1*offWave(i) }))
+
+	corr, _ := rc.Correlation("ETH", "BTC")
+	if corr <= 0.8 || corr >= 1 {
+		t.Fatalf("test series should correlate between the limit and 1, got %.3f", corr)
+	}
+
+	btcLong := &hyperliquid.Position{Symbol: "BTC", Side: "LONG", Size: 1}
+	result := checkWithPositions(rc, openLong(0.8, 0.1), btcLong)
+	want := 0.1 * (1 - corr) / 0.2
+	if !result.Approved || math.Abs(result.AdjustedSize-want) > 1e-9 {
+		t.Errorf("expected size reduced to %.4f, got %.4f (%v)", want, result.AdjustedSize, result.Approved)
+	}
+}
+
+func TestCandlesConcurrentAccess(t *testing.T) {
+	rc := newCorrelationController("reject")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			rc.RecordCandles("SOL", candleSeries(150, offWave))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		rc.Correlation("ETH", "SOL")
+		rc.ATR("SOL")
+		rc.HasCandles("SOL")
+	}
+	<-done
+
+	if !rc.HasCandles("SOL") {
+		t.Error("SOL candles should be recorded")
+	}
+}
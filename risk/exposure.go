@@ -0,0 +1,132 @@
+package risk
+
+import (
+	"math"
+	"sort"
+
+	"aitrading/hyperliquid"
+)
+
+// PortfolioSnapshot is the account-wide state the exposure rules judge against
+type PortfolioSnapshot struct {
+	Positions  []*hyperliquid.Position // Open or pending positions across all symbols
+	MarginUsed float64
+}
+
+// NewPortfolioSnapshot builds a snapshot from one account state plus opens
+// approved earlier in the cycle that are not on the exchange yet. Pending
+// opens for symbols the account already holds are ignored.
+func NewPortfolioSnapshot(account *hyperliquid.AccountState, pending map[string]*hyperliquid.Position) *PortfolioSnapshot {
+	snapshot := &PortfolioSnapshot{
+		Positions:  account.OpenPositions(),
+		MarginUsed: account.Margin.MarginUsed,
+	}
+
+	symbols := make([]string, 0, len(pending))
+	for symbol := range pending {
+		if _, held := account.Positions[symbol]; !held && pending[symbol] != nil {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		snapshot.Positions = append(snapshot.Positions, pending[symbol])
+		snapshot.MarginUsed += pending[symbol].MarginUsed
+	}
+
+	return snapshot
+}
+
+// Exposure is notional exposure by direction in USD
+type Exposure struct {
+	Long  float64
+	Short float64
+}
+
+// Gross returns long plus short notional
+func (e Exposure) Gross() float64 {
+	return e.Long + e.Short
+}
+
+// Net returns the absolute difference between long and short notional
+func (e Exposure) Net() float64 {
+	return math.Abs(e.Long - e.Short)
+}
+
+// Add returns the exposure after adding notional on side
+func (e Exposure) Add(side string, notional float64) Exposure {
+	if side == "SHORT" {
+		e.Short += notional
+	} else {
+		e.Long += notional
+	}
+	return e
+}
+
+// positions returns every open position known to the decision, falling back
+// to the decided symbol's own position without a portfolio snapshot
+func (ctx *DecisionContext) positions() []*hyperliquid.Position {
+	if ctx.Portfolio != nil {
+		return ctx.Portfolio.Positions
+	}
+	if ctx.Position != nil && ctx.Position.Size > 0 {
+		return []*hyperliquid.Position{ctx.Position}
+	}
+	return nil
+}
+
+// exposure sums the notional of all open positions by direction
+func (ctx *DecisionContext) exposure() Exposure {
+	var exposure Exposure
+	for _, pos := range ctx.positions() {
+		if pos == nil || (pos.Side != "LONG" && pos.Side != "SHORT") {
+			continue
+		}
+		exposure = exposure.Add(pos.Side, ctx.notional(pos))
+	}
+	return exposure
+}
+
+// notional returns a position's USD value, preferring the exchange figure
+func (ctx *DecisionContext) notional(pos *hyperliquid.Position) float64 {
+	if pos.PositionValue > 0 {
+		return pos.PositionValue
+	}
+	if pos.Symbol == ctx.Symbol && ctx.CurrentPrice > 0 {
+		return pos.Size * ctx.CurrentPrice
+	}
+	return pos.Size * pos.EntryPrice
+}
+
+// marginUsed returns the margin already committed across the portfolio
+func (ctx *DecisionContext) marginUsed() float64 {
+	if ctx.Portfolio != nil {
+		return ctx.Portfolio.MarginUsed
+	}
+	if ctx.Position != nil {
+		return ctx.Position.MarginUsed
+	}
+	return 0
+}
+
+// accountValue returns the perp account value the account-wide limits are
+// measured against
+func (ctx *DecisionContext) accountValue() float64 {
+	if ctx.AccountValue > 0 {
+		return ctx.AccountValue
+	}
+	return ctx.AccountBalance
+}
+
+// orderSide returns the direction the decision adds exposure in
+func (ctx *DecisionContext) orderSide() string {
+	switch ctx.Decision.Action {
+	case "OPEN_SHORT":
+		return "SHORT"
+	case "ADD_POSITION":
+		if ctx.Position != nil && ctx.Position.Side == "SHORT" {
+			return "SHORT"
+		}
+	}
+	return "LONG"
+}
@@ -0,0 +1,138 @@
+package risk
+
+import (
+	"testing"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+func newExposureController(riskCfg *config.RiskConfig) *Controller {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	riskCfg.MinRiskRewardRatio = 2
+	return NewController(riskCfg, &config.TradingConfig{}, logger)
+}
+
+func checkWithPortfolio(rc *Controller, decision *ai.Decision, portfolio *PortfolioSnapshot) *RiskCheckResult {
+	result, _ := rc.CheckDecision(&DecisionContext{
+		Symbol:         "ETH",
+		Decision:       decision,
+		CurrentPrice:   2000,
+		AccountBalance: 10000,
+		Portfolio:      portfolio,
+	})
+	return result
+}
+
+func openShort(size float64) *ai.Decision {
+	return &ai.Decision{
+		Action:     "OPEN_SHORT",
+		Confidence: 0.8,
+		Size:       size,
+		Leverage:   2,
+		StopLoss:   2100,
+		TakeProfit: 1800,
+	}
+}
+
+func TestCheckDecision_GrossExposureAcrossSymbols(t *testing.T) {
+	rc := newExposureController(&config.RiskConfig{MaxTotalExposure: 0.5})
+	portfolio := &PortfolioSnapshot{Positions: []*hyperliquid.Position{
+		{Symbol: "BTC", Side: "LONG", Size: 0.05, PositionValue: 3000},
+		{Symbol: "SOL", Side: "SHORT", Size: 10, EntryPrice: 150},
+	}}
+
+	// 3000 + 1500 held, so a 1000 order reaches 55%
+	if result := checkWithPortfolio(rc, openLong(0.8, 0.1), portfolio); result.Approved {
+		t.Error("gross exposure across symbols should be capped")
+	}
+	if result := checkWithPortfolio(rc, openLong(0.8, 0.05), portfolio); !result.Approved {
+		t.Errorf("order within the gross cap should pass: %s", result.Reason)
+	}
+}
+
+func TestCheckDecision_NetAndDirectionExposure(t *testing.T) {
+	portfolio := &PortfolioSnapshot{Positions: []*hyperliquid.Position{
+		{Symbol: "BTC", Side: "LONG", PositionValue: 2000},
+	}}
+
+	rc := newExposureController(&config.RiskConfig{MaxTotalExposure: 1, MaxNetExposure: 0.25})
+	if result := checkWithPortfolio(rc, openLong(0.8, 0.1), portfolio); result.Approved {
+		t.Error("net long exposure of 30% should exceed the 25% cap")
+	}
+	if result := checkWithPortfolio(rc, openShort(0.1), portfolio); !result.Approved {
+		t.Errorf("a short that reduces net exposure should pass: %s", result.Reason)
+	}
+
+	rc = newExposureController(&config.RiskConfig{MaxTotalExposure: 1, MaxLongExposure: 0.5, MaxShortExposure: 0.05})
+	if result := checkWithPortfolio(rc, openLong(0.8, 0.1), portfolio); !result.Approved {
+		t.Errorf("long exposure of 30%% should pass the 50%% cap: %s", result.Reason)
+	}
+	if result := checkWithPortfolio(rc, openShort(0.1), portfolio); result.Approved {
+		t.Error("short exposure of 10% should exceed the 5% cap")
+	}
+}
+
+func TestCheckDecision_MarginUtilization(t *testing.T) {
+	rc := newExposureController(&config.RiskConfig{MaxTotalExposure: 1, MaxMarginUtilization: 0.3})
+	portfolio := &PortfolioSnapshot{MarginUsed: 2500}
+
+	// 1000 notional at 2x adds 500 margin: 30% passes
+	if result := checkWithPortfolio(rc, openShort(0.1), portfolio); !result.Approved {
+		t.Errorf("utilization at the cap should pass: %s", result.Reason)
+	}
+
+	portfolio.MarginUsed = 2600
+	if result := checkWithPortfolio(rc, openShort(0.1), portfolio); result.Approved {
+		t.Error("utilization above the cap should be rejected")
+	}
+}
+
+func TestNewPortfolioSnapshot(t *testing.T) {
+	account := &hyperliquid.AccountState{
+		Positions: map[string]*hyperliquid.Position{
+			"ETH": {Symbol: "ETH", Side: "LONG", Size: 1, MarginUsed: 400},
+			"BTC": {Symbol: "BTC", Side: "SHORT", Size: 0.1, MarginUsed: 600},
+		},
+		Margin: hyperliquid.MarginSummary{MarginUsed: 1000},
+	}
+	pending := map[string]*hyperliquid.Position{
+		"ETH": {Symbol: "ETH", Side: "LONG", Size: 2, MarginUsed: 800}, // Already held
+		"SOL": {Symbol: "SOL", Side: "LONG", Size: 10, MarginUsed: 300},
+	}
+
+	snapshot := NewPortfolioSnapshot(account, pending)
+	if len(snapshot.Positions) != 3 || snapshot.Positions[0].Symbol != "BTC" || snapshot.Positions[2].Symbol != "SOL" {
+		t.Errorf("expected BTC, ETH and pending SOL, got %+v", snapshot.Positions)
+	}
+	if snapshot.MarginUsed != 1300 {
+		t.Errorf("expected account margin plus pending SOL margin, got %v", snapshot.MarginUsed)
+	}
+}
+
+func TestCheckDecision_SpotSizedFromQuoteBalance(t *testing.T) {
+	rc := newExposureController(&config.RiskConfig{MaxTotalExposure: 0.5})
+	portfolio := &PortfolioSnapshot{Positions: []*hyperliquid.Position{
+		{Symbol: "BTC", Side: "LONG", PositionValue: 3000},
+	}}
+
+	// 500 USDC on spot pays for the order; the 10000 perp account bounds exposure
+	decision := &ai.Decision{Action: "OPEN_LONG", Confidence: 0.8, Size: 0.2, Leverage: 1, StopLoss: 24, TakeProfit: 30}
+	result, _ := rc.CheckDecision(&DecisionContext{
+		Symbol:         "HYPE/USDC",
+		Decision:       decision,
+		CurrentPrice:   25,
+		AccountBalance: 500,
+		AccountValue:   10000,
+		Portfolio:      portfolio,
+	})
+	if !result.Approved {
+		t.Fatalf("spot order within the exposure cap should pass: %s", result.Reason)
+	}
+	if expected := result.AdjustedSize * 500 / 25; result.Quantity != expected {
+		t.Errorf("expected %.4f HYPE sized from the USDC balance, got %.4f", expected, result.Quantity)
+	}
+}
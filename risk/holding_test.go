@@ -0,0 +1,106 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+)
+
+func TestMaxHoldingTime(t *testing.T) {
+	riskCfg := &config.RiskConfig{HoldingTime: config.HoldingTimeConfig{Medium: "12h"}}
+	tradingCfg := &config.TradingConfig{
+		SymbolOverrides: map[string]config.SymbolOverride{
+			"DOGE": {HoldingTime: config.HoldingTimeConfig{Short: "2h"}},
+		},
+	}
+	rc := NewController(riskCfg, tradingCfg, quietLogger())
+
+	cases := []struct {
+		symbol, period string
+		want           time.Duration
+	}{
+		{"ETH", "SHORT", 4 * time.Hour},
+		{"ETH", "MEDIUM", 12 * time.Hour},
+		{"ETH", "", 12 * time.Hour},
+		{"ETH", "LONG", 7 * 24 * time.Hour},
+		{"DOGE", "SHORT", 2 * time.Hour},
+		{"DOGE", "MEDIUM", 12 * time.Hour},
+	}
+	for _, c := range cases {
+		if got := rc.MaxHoldingTime(c.symbol, c.period); got != c.want {
+			t.Errorf("%s %s: expected %s, got %s", c.symbol, c.period, c.want, got)
+		}
+	}
+}
+
+func TestCheckHoldingTime(t *testing.T) {
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2, HoldingTime: config.HoldingTimeConfig{Enforce: true}}
+	rc := NewController(riskCfg, &config.TradingConfig{}, quietLogger())
+
+	decision := openLong(0.8, 0.05)
+	decision.ExpectedHoldingPeriod = "SHORT"
+	if result := checkOpenLong(rc, "ETH", decision); !result.Approved {
+		t.Fatalf("open should be approved: %s", result.Reason)
+	}
+	if _, ok := rc.holdingPeriods["ETH"]; ok {
+		t.Fatal("approval alone should not record an entry")
+	}
+	rc.RecordEntry("ETH", decision.ExpectedHoldingPeriod)
+
+	position := &hyperliquid.Position{
+		Symbol:      "ETH",
+		Side:        "LONG",
+		Size:        1,
+		EntryPrice:  2000,
+		OpenTime:    time.Now().Add(-5 * time.Hour),
+		HoldingTime: 5 * time.Hour,
+	}
+	if !rc.CheckHoldingTime("ETH", position) {
+		t.Error("a SHORT entry held 5h should exceed its 4h limit")
+	}
+
+	position.HoldingTime = 3 * time.Hour
+	if rc.CheckHoldingTime("ETH", position) {
+		t.Error("a SHORT entry held 3h should be within its limit")
+	}
+
+	rc.config.HoldingTime.Enforce = false
+	position.HoldingTime = 5 * time.Hour
+	if rc.CheckHoldingTime("ETH", position) {
+		t.Error("time stops should be off unless enforced")
+	}
+}
+
+func TestCheckDecision_AddPastHoldingTime(t *testing.T) {
+	riskCfg := &config.RiskConfig{MaxTotalExposure: 1, MinRiskRewardRatio: 2}
+	rc := NewController(riskCfg, &config.TradingConfig{}, quietLogger())
+
+	position := &hyperliquid.Position{
+		Symbol:      "ETH",
+		Side:        "LONG",
+		Size:        0.1,
+		EntryPrice:  2000,
+		HoldingTime: 30 * time.Hour,
+	}
+	add := &ai.Decision{
+		Action:     "ADD_POSITION",
+		Confidence: 0.8,
+		Size:       0.05,
+		Leverage:   3,
+		StopLoss:   1900,
+		TakeProfit: 2200,
+	}
+	result, _ := rc.CheckDecision(&DecisionContext{
+		Symbol:         "ETH",
+		Decision:       add,
+		CurrentPrice:   2000,
+		AccountBalance: 10000,
+		Position:       position,
+	})
+	if result.Approved {
+		t.Error("adding to a position held past its limit should be rejected")
+	}
+}
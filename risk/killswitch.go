@@ -0,0 +1,294 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"aitrading/config"
+	"github.com/sirupsen/logrus"
+)
+
+// TradingState gates which orders may be sent
+type TradingState string
+
+const (
+	StateActive     TradingState = "ACTIVE"      // Normal trading
+	StateReduceOnly TradingState = "REDUCE_ONLY" // Only closes are allowed
+	StateHalted     TradingState = "HALTED"      // No orders at all
+	StateFlatten    TradingState = "FLATTEN"     // Close every position, then halt
+)
+
+// severity orders states from least to most restrictive
+func (s TradingState) severity() int {
+	switch s {
+	case StateReduceOnly:
+		return 1
+	case StateHalted:
+		return 2
+	case StateFlatten:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ParseTradingState parses a state name case-insensitively
+func ParseTradingState(name string) (TradingState, error) {
+	state := TradingState(strings.ToUpper(strings.TrimSpace(name)))
+	switch state {
+	case StateActive, StateReduceOnly, StateHalted, StateFlatten:
+		return state, nil
+	}
+	return "", fmt.Errorf("unknown trading state: %s", name)
+}
+
+// Allows reports whether an action may be executed in state s
+func (s TradingState) Allows(action string) bool {
+	switch s {
+	case StateReduceOnly, StateFlatten:
+		return action == "CLOSE_POSITION" || action == "HOLD"
+	case StateHalted:
+		return action == "HOLD"
+	default:
+		return true
+	}
+}
+
+// Halt is the persisted trading state and why it was entered
+type Halt struct {
+	State  TradingState `json:"state"`
+	Reason string       `json:"reason,omitempty"`
+	Source string       `json:"source,omitempty"`
+	Since  time.Time    `json:"since"`
+}
+
+// KillSwitch holds the trading state machine. Escalations persist to a state
+// file and survive restarts until Clear is called.
+type KillSwitch struct {
+	mu         sync.Mutex
+	config     config.KillSwitchConfig
+	logger     *logrus.Logger
+	halt       Halt
+	errorTimes []time.Time
+	saveErr    error
+	now        func() time.Time
+}
+
+// NewKillSwitch creates a kill switch and restores any persisted state
+func NewKillSwitch(cfg config.KillSwitchConfig, logger *logrus.Logger) *KillSwitch {
+	ks := &KillSwitch{
+		config: cfg,
+		logger: logger,
+		now:    time.Now,
+	}
+	ks.halt = Halt{State: StateActive, Since: ks.now()}
+	ks.load()
+	return ks
+}
+
+// State returns the current trading state
+func (ks *KillSwitch) State() Halt {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.halt
+}
+
+// Allows reports whether an action may be executed in the current state
+func (ks *KillSwitch) Allows(action string) bool {
+	return ks.State().State.Allows(action)
+}
+
+// Escalate moves to state unless the current state is already at least as
+// restrictive. It returns whether the state changed.
+func (ks *KillSwitch) Escalate(state TradingState, source, reason string) bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if state.severity() <= ks.halt.State.severity() {
+		return false
+	}
+	ks.set(state, source, reason)
+	return true
+}
+
+// Set moves to state unconditionally, e.g. from a manual command or after a
+// flatten completes
+func (ks *KillSwitch) Set(state TradingState, source, reason string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.set(state, source, reason)
+}
+
+// Clear returns to ACTIVE and forgets recent errors
+func (ks *KillSwitch) Clear(source string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.errorTimes = nil
+	ks.set(StateActive, source, "cleared")
+}
+
+func (ks *KillSwitch) set(state TradingState, source, reason string) {
+	ks.halt = Halt{State: state, Reason: reason, Source: source, Since: ks.now()}
+	ks.saveErr = ks.save()
+
+	entry := ks.logger.WithFields(logrus.Fields{
+		"state":  state,
+		"source": source,
+		"reason": reason,
+	})
+	if state == StateActive {
+		entry.Info("Trading state changed")
+	} else {
+		entry.Warn("Trading state changed")
+	}
+}
+
+// RecordError counts a failure and escalates when error_burst failures fall
+// within error_window seconds
+func (ks *KillSwitch) RecordError(err error) {
+	if ks.config.ErrorBurst <= 0 {
+		return
+	}
+
+	window := time.Duration(ks.config.ErrorWindow) * time.Second
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+
+	ks.mu.Lock()
+	now := ks.now()
+	recent := ks.errorTimes[:0]
+	for _, t := range ks.errorTimes {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	ks.errorTimes = append(recent, now)
+	burst := len(ks.errorTimes) >= ks.config.ErrorBurst
+	ks.mu.Unlock()
+
+	if burst {
+		ks.Escalate(ks.target(ks.config.OnErrorBurst, StateHalted), "error_burst",
+			fmt.Sprintf("%d errors within %s, last: %v", ks.config.ErrorBurst, window, err))
+	}
+}
+
+// Sync reloads the state file to pick up changes made by CLI commands, then
+// consumes the trigger file if present. The trigger file may name a state;
+// an empty file means HALTED.
+func (ks *KillSwitch) Sync() {
+	// An unsaved escalation must not be overwritten by the stale file
+	if ks.SaveError() == nil {
+		ks.load()
+	}
+
+	if ks.config.TriggerFile == "" {
+		return
+	}
+	data, err := os.ReadFile(ks.config.TriggerFile)
+	if err != nil {
+		return
+	}
+
+	state := StateHalted
+	if content := strings.TrimSpace(string(data)); content != "" {
+		if parsed, err := ParseTradingState(content); err == nil {
+			state = parsed
+		}
+	}
+	if err := os.Remove(ks.config.TriggerFile); err != nil {
+		ks.logger.WithError(err).Warn("Failed to remove kill switch trigger file")
+	}
+
+	if state == StateActive {
+		ks.Clear("trigger_file")
+	} else {
+		ks.Set(state, "trigger_file", "trigger file "+ks.config.TriggerFile)
+	}
+}
+
+// OnDrawdown escalates to the state configured for max drawdown breaches
+func (ks *KillSwitch) OnDrawdown(reason string) {
+	ks.Escalate(ks.target(ks.config.OnDrawdown, StateHalted), "drawdown", reason)
+}
+
+// OnDailyLoss escalates to the state configured for daily loss breaches
+func (ks *KillSwitch) OnDailyLoss(reason string) {
+	ks.Escalate(ks.target(ks.config.OnDailyLoss, StateReduceOnly), "daily_loss", reason)
+}
+
+// SaveError returns the last error persisting the state, if any
+func (ks *KillSwitch) SaveError() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.saveErr
+}
+
+// target parses a configured state, falling back to def
+func (ks *KillSwitch) target(name string, def TradingState) TradingState {
+	if name == "" {
+		return def
+	}
+	state, err := ParseTradingState(name)
+	if err != nil {
+		ks.logger.WithError(err).Warnf("Invalid kill switch state, using %s", def)
+		return def
+	}
+	return state
+}
+
+func (ks *KillSwitch) load() {
+	if ks.config.StateFile == "" {
+		return
+	}
+	halt, err := LoadTradingState(ks.config.StateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ks.logger.WithError(err).Warn("Failed to load trading state")
+		}
+		return
+	}
+
+	ks.mu.Lock()
+	ks.halt = *halt
+	ks.mu.Unlock()
+}
+
+func (ks *KillSwitch) save() error {
+	if ks.config.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(ks.halt, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trading state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ks.config.StateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create trading state directory: %w", err)
+	}
+	if err := os.WriteFile(ks.config.StateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write trading state: %w", err)
+	}
+	return nil
+}
+
+// LoadTradingState reads a trading state persisted by a KillSwitch
+func LoadTradingState(filename string) (*Halt, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var halt Halt
+	if err := json.Unmarshal(data, &halt); err != nil {
+		return nil, fmt.Errorf("failed to parse trading state: %w", err)
+	}
+	if _, err := ParseTradingState(string(halt.State)); err != nil {
+		return nil, err
+	}
+	return &halt, nil
+}
@@ -0,0 +1,156 @@
+package risk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+func TestTradingStateAllows(t *testing.T) {
+	cases := []struct {
+		state   TradingState
+		open    bool
+		closing bool
+	}{
+		{StateActive, true, true},
+		{StateReduceOnly, false, true},
+		{StateHalted, false, false},
+		{StateFlatten, false, true},
+	}
+	for _, c := range cases {
+		if c.state.Allows("OPEN_LONG") != c.open || c.state.Allows("CLOSE_POSITION") != c.closing {
+			t.Errorf("%s: unexpected permissions", c.state)
+		}
+	}
+}
+
+func TestKillSwitchPersistsUntilCleared(t *testing.T) {
+	cfg := config.KillSwitchConfig{StateFile: filepath.Join(t.TempDir(), "state.json")}
+
+	ks := NewKillSwitch(cfg, quietLogger())
+	if !ks.Escalate(StateHalted, "test", "boom") {
+		t.Fatal("escalation from ACTIVE should change state")
+	}
+	if ks.Escalate(StateReduceOnly, "test", "milder") {
+		t.Error("a less restrictive state must not replace HALTED")
+	}
+
+	restarted := NewKillSwitch(cfg, quietLogger())
+	if halt := restarted.State(); halt.State != StateHalted || halt.Reason != "boom" {
+		t.Fatalf("state should survive restart, got %+v", halt)
+	}
+
+	restarted.Clear("test")
+	if NewKillSwitch(cfg, quietLogger()).State().State != StateActive {
+		t.Error("cleared state should persist as ACTIVE")
+	}
+}
+
+func TestKillSwitchErrorBurst(t *testing.T) {
+	ks := NewKillSwitch(config.KillSwitchConfig{ErrorBurst: 3, ErrorWindow: 60}, quietLogger())
+	now := time.Now()
+	ks.now = func() time.Time { return now }
+
+	ks.RecordError(errors.New("one"))
+	now = now.Add(2 * time.Minute) // the first error ages out
+	ks.RecordError(errors.New("two"))
+	ks.RecordError(errors.New("three"))
+	if ks.State().State != StateActive {
+		t.Fatal("errors outside the window should not count")
+	}
+
+	ks.RecordError(errors.New("four"))
+	if ks.State().State != StateHalted {
+		t.Error("three errors within the window should halt")
+	}
+}
+
+func TestKillSwitchTriggerFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.KillSwitchConfig{
+		StateFile:   filepath.Join(dir, "state.json"),
+		TriggerFile: filepath.Join(dir, "HALT"),
+	}
+	ks := NewKillSwitch(cfg, quietLogger())
+
+	os.WriteFile(cfg.TriggerFile, []byte("flatten\n"), 0644)
+	ks.Sync()
+	if ks.State().State != StateFlatten {
+		t.Errorf("trigger file should set FLATTEN, got %s", ks.State().State)
+	}
+	if _, err := os.Stat(cfg.TriggerFile); !os.IsNotExist(err) {
+		t.Error("trigger file should be consumed")
+	}
+
+	// A CLI clear written by another process is picked up on sync
+	NewKillSwitch(cfg, quietLogger()).Clear("cli")
+	ks.Sync()
+	if ks.State().State != StateActive {
+		t.Error("sync should load the state written by the CLI")
+	}
+}
+
+func TestControllerTripsKillSwitchOnDrawdown(t *testing.T) {
+	rc := NewController(&config.RiskConfig{MaxDrawdown: 0.05, MinRiskRewardRatio: 2}, &config.TradingConfig{}, quietLogger())
+	ks := NewKillSwitch(config.KillSwitchConfig{OnDrawdown: "REDUCE_ONLY"}, quietLogger())
+	rc.SetKillSwitch(ks)
+
+	rc.UpdateAccount(10000)
+	rc.UpdateAccount(9000)
+	if ks.State().State != StateReduceOnly {
+		t.Fatalf("10%% drawdown should trip the kill switch, got %s", ks.State().State)
+	}
+
+	// Back above the drawdown limit, entries stay blocked until cleared
+	result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05))
+	if result.Approved || result.Verdicts[0].Rule != "trading_state" {
+		t.Errorf("entries should be rejected by the trading state, got %+v", result.Verdicts)
+	}
+
+	ks.Clear("test")
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); !result.Approved {
+		t.Errorf("entries should pass once cleared: %s", result.Reason)
+	}
+}
+
+func TestClosePassesPastLossLimits(t *testing.T) {
+	rc := NewController(&config.RiskConfig{MaxDrawdown: 0.05, DailyLossLimit: 0.02, MinRiskRewardRatio: 2}, &config.TradingConfig{}, quietLogger())
+	ks := NewKillSwitch(config.KillSwitchConfig{OnDrawdown: "REDUCE_ONLY", OnDailyLoss: "REDUCE_ONLY"}, quietLogger())
+	rc.SetKillSwitch(ks)
+
+	rc.UpdateAccount(10000)
+	rc.UpdatePnL(-900)
+	rc.UpdateAccount(9100)
+	if ks.State().State != StateReduceOnly {
+		t.Fatalf("the losses should trip the kill switch, got %s", ks.State().State)
+	}
+
+	// Cutting the losing position must not be blocked by the limits it breached
+	result, _ := rc.CheckDecision(&DecisionContext{
+		Symbol:         "ETH",
+		Decision:       &ai.Decision{Action: "CLOSE_POSITION", Confidence: 0.9},
+		Position:       &hyperliquid.Position{Symbol: "ETH", Side: "LONG", Size: 1, EntryPrice: 2100},
+		CurrentPrice:   2000,
+		AccountBalance: 9100,
+	})
+	if !result.Approved {
+		t.Errorf("a close should pass in REDUCE_ONLY, got %+v", result.Verdicts)
+	}
+
+	if result := checkOpenLong(rc, "ETH", openLong(0.8, 0.05)); result.Approved {
+		t.Error("entries should still be rejected")
+	}
+}
@@ -0,0 +1,102 @@
+package risk
+
+import (
+	"aitrading/hyperliquid"
+)
+
+// defaultMaxLeverage is assumed when an asset's margin table is unknown. It
+// is conservative: lower max leverage means higher maintenance margin.
+const defaultMaxLeverage = 20
+
+// SetMarginTiers installs the exchange margin tables used for liquidation estimates
+func (rc *Controller) SetMarginTiers(tiers map[string][]hyperliquid.MarginTier) {
+	rc.marginTiers = tiers
+}
+
+// maintenanceMargin returns the maintenance margin rate at notional and the
+// deduction that keeps maintenance margin continuous across tiers. Each
+// tier's rate is half the initial margin at its max leverage.
+func maintenanceMargin(tiers []hyperliquid.MarginTier, notional float64) (rate, deduction float64) {
+	if len(tiers) == 0 {
+		return 1 / (2 * float64(defaultMaxLeverage)), 0
+	}
+
+	prevRate := 0.0
+	for i, tier := range tiers {
+		if i > 0 && notional < tier.LowerBound {
+			break
+		}
+		rate = 1 / (2 * float64(tier.MaxLeverage))
+		deduction += tier.LowerBound * (rate - prevRate)
+		prevRate = rate
+	}
+	return rate, deduction
+}
+
+// LiquidationPrice estimates where a position of quantity entered at entry
+// is liquidated when margin backs it: the isolated margin, or for cross
+// margin the collateral not committed to other positions
+func LiquidationPrice(side string, entry, quantity, margin float64, tiers []hyperliquid.MarginTier) float64 {
+	if quantity <= 0 || entry <= 0 {
+		return 0
+	}
+
+	rate, deduction := maintenanceMargin(tiers, quantity*entry)
+
+	// Solve margin + pnl(p) = maintenance margin(p)
+	if side == "SHORT" {
+		return (quantity*entry + margin + deduction) / (quantity * (1 + rate))
+	}
+
+	liquidation := (quantity*entry - margin - deduction) / (quantity * (1 - rate))
+	if liquidation < 0 {
+		return 0
+	}
+	return liquidation
+}
+
+// EstimateLiquidation returns LiquidationPrice using the symbol's margin tiers
+func (rc *Controller) EstimateLiquidation(symbol, side string, entry, quantity, margin float64) float64 {
+	return LiquidationPrice(side, entry, quantity, margin, rc.marginTiers[symbol])
+}
+
+// stopPastLiquidation reports whether the stop would only trigger after liquidation
+func stopPastLiquidation(side string, stopLoss, liquidation float64) bool {
+	if liquidation <= 0 {
+		return false
+	}
+	if side == "SHORT" {
+		return stopLoss >= liquidation
+	}
+	return stopLoss <= liquidation
+}
+
+// liquidationFor estimates the liquidation price of the position the decision
+// would leave at leverage
+func (rc *Controller) liquidationFor(ctx *DecisionContext, size float64, leverage int) float64 {
+	side := ctx.orderSide()
+	entry := ctx.CurrentPrice
+	quantity := size * ctx.AccountBalance / ctx.CurrentPrice
+	margin := quantity * entry / float64(leverage)
+
+	// Adding merges into the existing position
+	existing := ctx.Position
+	if ctx.Decision.Action == "ADD_POSITION" && existing != nil && existing.Size > 0 {
+		entry = (existing.Size*existing.EntryPrice + quantity*ctx.CurrentPrice) / (existing.Size + quantity)
+		quantity += existing.Size
+		margin += existing.MarginUsed
+	}
+
+	mode := rc.config.MarginMode
+	if existing != nil && existing.MarginMode != "" {
+		mode = existing.MarginMode
+	}
+	if mode != "isolated" {
+		margin = ctx.AccountBalance - ctx.marginUsed()
+		if existing != nil {
+			margin += existing.MarginUsed
+		}
+	}
+
+	return rc.EstimateLiquidation(ctx.Symbol, side, entry, quantity, margin)
+}
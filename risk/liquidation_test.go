@@ -0,0 +1,79 @@
+package risk
+
+import (
+	"math"
+	"testing"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLiquidationPrice(t *testing.T) {
+	tiers := []hyperliquid.MarginTier{{LowerBound: 0, MaxLeverage: 50}}
+
+	// 10x isolated: 1000 notional backed by 100 margin at 1% maintenance
+	if liq := LiquidationPrice("LONG", 100, 10, 100, tiers); math.Abs(liq-900.0/9.9) > 1e-9 {
+		t.Errorf("unexpected long liquidation: %f", liq)
+	}
+	if liq := LiquidationPrice("SHORT", 100, 10, 100, tiers); math.Abs(liq-1100.0/10.1) > 1e-9 {
+		t.Errorf("unexpected short liquidation: %f", liq)
+	}
+	if liq := LiquidationPrice("LONG", 100, 10, 1000, tiers); liq != 0 {
+		t.Errorf("a fully collateralised long cannot be liquidated, got %f", liq)
+	}
+}
+
+func TestMaintenanceMarginTiers(t *testing.T) {
+	tiers := []hyperliquid.MarginTier{{LowerBound: 0, MaxLeverage: 40}, {LowerBound: 10000, MaxLeverage: 20}}
+
+	rate, deduction := maintenanceMargin(tiers, 5000)
+	if rate != 0.0125 || deduction != 0 {
+		t.Errorf("first tier: rate %f deduction %f", rate, deduction)
+	}
+
+	// Maintenance margin is continuous at the tier boundary
+	rate, deduction = maintenanceMargin(tiers, 20000)
+	if rate != 0.025 || math.Abs(10000*rate-deduction-125) > 1e-9 {
+		t.Errorf("second tier: rate %f deduction %f", rate, deduction)
+	}
+}
+
+func checkLeveragedLong(mode, action string) *RiskCheckResult {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	rc := NewController(&config.RiskConfig{
+		MinRiskRewardRatio: 2,
+		MarginMode:         mode,
+		LiquidationAction:  action,
+	}, &config.TradingConfig{}, logger)
+
+	// A 6% stop at 20x sits past the ~2.5% liquidation distance
+	return checkOpenLong(rc, "ETH", &ai.Decision{
+		Action:     "OPEN_LONG",
+		Confidence: 0.8,
+		Size:       0.1,
+		Leverage:   20,
+		StopLoss:   1880,
+		TakeProfit: 2240,
+	})
+}
+
+func TestCheckDecision_LiquidationIsolated(t *testing.T) {
+	result := checkLeveragedLong("isolated", "")
+	if !result.Approved || result.AdjustedLeverage != 11 {
+		t.Errorf("expected leverage lowered to 11x, got %dx (%s)", result.AdjustedLeverage, result.Reason)
+	}
+
+	if result := checkLeveragedLong("isolated", "reject"); result.Approved {
+		t.Error("stop past liquidation should be rejected when liquidation_action is reject")
+	}
+}
+
+func TestCheckDecision_LiquidationCross(t *testing.T) {
+	// The whole 10000 balance backs a 1000 notional position
+	if result := checkLeveragedLong("cross", ""); !result.Approved || result.AdjustedLeverage != 20 {
+		t.Errorf("cross margin position should pass unchanged: %s", result.Reason)
+	}
+}
@@ -0,0 +1,510 @@
+package risk
+
+import (
+	"fmt"
+	"time"
+
+	"aitrading/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// VerdictStatus is the outcome of a single risk rule
+type VerdictStatus string
+
+const (
+	VerdictPass   VerdictStatus = "PASS"   // Rule satisfied
+	VerdictAdjust VerdictStatus = "ADJUST" // Rule satisfied after changing size or leverage
+	VerdictReject VerdictStatus = "REJECT" // Decision must not be executed
+	VerdictSkip   VerdictStatus = "SKIP"   // Rule does not apply to this decision
+)
+
+// Verdict is the structured result a rule reports for a decision
+type Verdict struct {
+	Rule    string
+	Status  VerdictStatus
+	Message string
+}
+
+// rule is one step of the risk pipeline
+type rule struct {
+	name  string
+	check func(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict
+}
+
+// Limits are the thresholds in force for one symbol after applying overrides
+type Limits struct {
+	MinConfidence      float64
+	MaxPositionSize    float64
+	MaxLeverage        int
+	MaxOpenPositions   int
+	MinRiskRewardRatio float64
+	MaxTotalExposure   float64
+	DailyLossLimit     float64
+	MaxDrawdown        float64
+	CorrelationLimit   float64
+
+	MaxNetExposure       float64
+	MaxLongExposure      float64
+	MaxShortExposure     float64
+	MaxMarginUtilization float64
+}
+
+// LimitsFor resolves the thresholds for symbol from RiskConfig, TradingConfig
+// and the symbol's override
+func (rc *Controller) LimitsFor(symbol string) Limits {
+	limits := Limits{
+		MinConfidence:      rc.tradingConfig.MinConfidence,
+		MaxPositionSize:    rc.tradingConfig.MaxPositionSize,
+		MaxLeverage:        rc.tradingConfig.MaxLeverage,
+		MaxOpenPositions:   rc.tradingConfig.MaxOpenPositions,
+		MinRiskRewardRatio: rc.config.MinRiskRewardRatio,
+		MaxTotalExposure:   rc.config.MaxTotalExposure,
+		DailyLossLimit:     rc.config.DailyLossLimit,
+		MaxDrawdown:        rc.config.MaxDrawdown,
+		CorrelationLimit:   rc.config.CorrelationLimit,
+
+		MaxNetExposure:       rc.config.MaxNetExposure,
+		MaxLongExposure:      rc.config.MaxLongExposure,
+		MaxShortExposure:     rc.config.MaxShortExposure,
+		MaxMarginUtilization: rc.config.MaxMarginUtilization,
+	}
+
+	override := rc.tradingConfig.Override(symbol)
+	if override.MinConfidence > 0 {
+		limits.MinConfidence = override.MinConfidence
+	}
+	if override.MaxPositionSize > 0 {
+		limits.MaxPositionSize = override.MaxPositionSize
+	}
+	if override.MaxLeverage > 0 {
+		limits.MaxLeverage = override.MaxLeverage
+	}
+	if override.MinRiskRewardRatio > 0 {
+		limits.MinRiskRewardRatio = override.MinRiskRewardRatio
+	}
+
+	return limits
+}
+
+// defaultRules returns the risk pipeline in evaluation order
+func defaultRules() []rule {
+	return []rule{
+		{"trading_state", checkTradingState},
+		{"market", checkMarket},
+		{"cooldown", checkCooldown},
+		{"max_open_positions", checkMaxOpenPositions},
+		{"max_leverage", checkLeverage},
+		{"min_confidence", checkConfidence},
+		{"daily_loss_limit", checkDailyLoss},
+		{"max_drawdown", checkDrawdown},
+		{"position_sizing", checkSizing},
+		{"max_position_size", checkPositionSize},
+		{"correlation_limit", checkCorrelation},
+		{"liquidation", checkLiquidation},
+		{"max_total_exposure", checkTotalExposure},
+		{"max_net_exposure", checkNetExposure},
+		{"max_direction_exposure", checkDirectionExposure},
+		{"max_margin_utilization", checkMarginUtilization},
+		{"min_risk_reward_ratio", checkRiskReward},
+		{"stop_loss", checkStopLossPlacement},
+		{"holding_time", checkHoldingTime},
+	}
+}
+
+func pass() Verdict {
+	return Verdict{Status: VerdictPass}
+}
+
+func skip() Verdict {
+	return Verdict{Status: VerdictSkip}
+}
+
+func reject(format string, args ...interface{}) Verdict {
+	return Verdict{Status: VerdictReject, Message: fmt.Sprintf(format, args...)}
+}
+
+func adjust(format string, args ...interface{}) Verdict {
+	return Verdict{Status: VerdictAdjust, Message: fmt.Sprintf(format, args...)}
+}
+
+// isOpen reports whether the action opens a new position
+func isOpen(action string) bool {
+	return action == "OPEN_LONG" || action == "OPEN_SHORT"
+}
+
+// increasesExposure reports whether the action adds to exposure
+func increasesExposure(action string) bool {
+	return isOpen(action) || action == "ADD_POSITION"
+}
+
+// checkTradingState rejects actions the kill switch does not allow
+func checkTradingState(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if rc.killSwitch == nil {
+		return skip()
+	}
+	halt := rc.killSwitch.State()
+	if !halt.State.Allows(ctx.Decision.Action) {
+		return reject("Trading state %s does not allow %s: %s", halt.State, ctx.Decision.Action, halt.Reason)
+	}
+	return pass()
+}
+
+// checkMarket keeps spot decisions to what spot can do: no shorts and no
+// leverage
+func checkMarket(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if rc.tradingConfig.Market(ctx.Symbol) != config.MarketSpot {
+		return skip()
+	}
+	if ctx.Decision.Action == "OPEN_SHORT" {
+		return reject("%s is a spot market and cannot be shorted", ctx.Symbol)
+	}
+	if increasesExposure(ctx.Decision.Action) && result.AdjustedLeverage > 1 {
+		original := result.AdjustedLeverage
+		result.AdjustedLeverage = 1
+		return adjust("Leverage adjusted for spot: %dx -> 1x", original)
+	}
+	return pass()
+}
+
+// checkMaxOpenPositions rejects new positions beyond max_open_positions
+func checkMaxOpenPositions(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !isOpen(ctx.Decision.Action) || limits.MaxOpenPositions <= 0 {
+		return skip()
+	}
+	if ctx.OpenPositionCount >= limits.MaxOpenPositions {
+		return reject("Maximum open positions reached: %d >= %d", ctx.OpenPositionCount, limits.MaxOpenPositions)
+	}
+	return pass()
+}
+
+// checkLeverage caps leverage at max_leverage
+func checkLeverage(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || limits.MaxLeverage <= 0 {
+		return skip()
+	}
+	if result.AdjustedLeverage > limits.MaxLeverage {
+		original := result.AdjustedLeverage
+		result.AdjustedLeverage = limits.MaxLeverage
+		rc.logger.WithFields(logrus.Fields{
+			"original_leverage": original,
+			"adjusted_leverage": result.AdjustedLeverage,
+		}).Warn("Leverage adjusted down to max limit")
+		return adjust("Leverage adjusted: %dx -> %dx", original, result.AdjustedLeverage)
+	}
+	return pass()
+}
+
+// checkConfidence rejects decisions below min_confidence
+func checkConfidence(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if ctx.Decision.Action == "HOLD" {
+		return skip()
+	}
+	if ctx.Decision.Confidence < limits.MinConfidence {
+		return reject("Confidence too low: %.2f < %.2f", ctx.Decision.Confidence, limits.MinConfidence)
+	}
+	return pass()
+}
+
+// checkDailyLoss rejects new exposure once the daily loss limit is exceeded.
+// Closes still pass so losing positions can be cut.
+func checkDailyLoss(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if ctx.accountValue() <= 0 || limits.DailyLossLimit <= 0 {
+		return skip()
+	}
+	if loss := rc.dailyLoss(ctx.accountValue()); loss > limits.DailyLossLimit {
+		rc.tripDailyLoss(loss)
+		if increasesExposure(ctx.Decision.Action) {
+			return reject("Daily loss limit exceeded: %.2f%% > %.2f%%", loss*100, limits.DailyLossLimit*100)
+		}
+	}
+	return pass()
+}
+
+// checkDrawdown rejects new exposure beyond max_drawdown from the peak
+// balance. Closes still pass.
+func checkDrawdown(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if drawdown := rc.drawdown(ctx.accountValue()); limits.MaxDrawdown > 0 && drawdown > limits.MaxDrawdown {
+		rc.tripDrawdown(drawdown)
+		if increasesExposure(ctx.Decision.Action) {
+			return reject("Max drawdown exceeded: %.2f%% > %.2f%%", drawdown*100, limits.MaxDrawdown*100)
+		}
+	}
+
+	// Update peak balance
+	if ctx.accountValue() > rc.peakBalance {
+		rc.peakBalance = ctx.accountValue()
+	}
+	return pass()
+}
+
+// checkPositionSize caps the balance fraction of a new order at max_position_size
+func checkPositionSize(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || limits.MaxPositionSize <= 0 {
+		return skip()
+	}
+	if result.AdjustedSize > limits.MaxPositionSize {
+		original := result.AdjustedSize
+		result.AdjustedSize = limits.MaxPositionSize
+		rc.logger.WithFields(logrus.Fields{
+			"original_size": original,
+			"adjusted_size": result.AdjustedSize,
+		}).Warn("Position size adjusted down")
+		return adjust("Position size adjusted: %.4f -> %.4f", original, result.AdjustedSize)
+	}
+	return pass()
+}
+
+// checkCorrelation blocks or scales down entries that duplicate an open
+// position: a same-direction position whose returns correlate above
+// correlation_limit, or an opposite-direction one that is anti-correlated
+func checkCorrelation(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || limits.CorrelationLimit <= 0 || limits.CorrelationLimit >= 1 {
+		return skip()
+	}
+
+	side := ctx.orderSide()
+	worst, worstSymbol := 0.0, ""
+	for _, pos := range ctx.positions() {
+		if pos == nil || pos.Symbol == ctx.Symbol || (pos.Side != "LONG" && pos.Side != "SHORT") {
+			continue
+		}
+		corr, ok := rc.Correlation(ctx.Symbol, pos.Symbol)
+		if !ok {
+			continue
+		}
+		if pos.Side != side {
+			corr = -corr
+		}
+		if corr > worst {
+			worst, worstSymbol = corr, pos.Symbol
+		}
+	}
+
+	if worst <= limits.CorrelationLimit {
+		return pass()
+	}
+
+	if rc.config.CorrelationAction == "reject" {
+		return reject("Correlated with open %s position: %.2f > %.2f", worstSymbol, worst, limits.CorrelationLimit)
+	}
+
+	// Shrink linearly from full size at the limit to nothing at perfect correlation
+	original := result.AdjustedSize
+	result.AdjustedSize = original * (1 - worst) / (1 - limits.CorrelationLimit)
+	if result.AdjustedSize <= 0 {
+		return reject("Correlated with open %s position: %.2f", worstSymbol, worst)
+	}
+	rc.logger.WithFields(logrus.Fields{
+		"correlated_with": worstSymbol,
+		"correlation":     worst,
+		"original_size":   original,
+		"adjusted_size":   result.AdjustedSize,
+	}).Warn("Position size reduced for correlation")
+	return adjust("Correlated with open %s position (%.2f > %.2f), size %.4f -> %.4f",
+		worstSymbol, worst, limits.CorrelationLimit, original, result.AdjustedSize)
+}
+
+// checkLiquidation rejects entries whose stop loss sits at or past the
+// estimated liquidation price. With isolated margin it lowers leverage until
+// the stop triggers first, unless liquidation_action is "reject".
+func checkLiquidation(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || ctx.Decision.StopLoss <= 0 ||
+		ctx.CurrentPrice <= 0 || ctx.AccountBalance <= 0 || result.AdjustedSize <= 0 {
+		return skip()
+	}
+
+	side := ctx.orderSide()
+	stopLoss := ctx.Decision.StopLoss
+	leverage := result.AdjustedLeverage
+	if leverage < 1 {
+		leverage = 1
+	}
+
+	liquidation := rc.liquidationFor(ctx, result.AdjustedSize, leverage)
+	if !stopPastLiquidation(side, stopLoss, liquidation) {
+		return pass()
+	}
+
+	isolated := rc.config.MarginMode == "isolated"
+	if ctx.Position != nil && ctx.Position.MarginMode != "" {
+		isolated = ctx.Position.MarginMode == "isolated"
+	}
+	if !isolated || rc.config.LiquidationAction == "reject" {
+		return reject("Stop loss %.4f is past estimated liquidation %.4f", stopLoss, liquidation)
+	}
+
+	for lower := leverage - 1; lower >= 1; lower-- {
+		size := result.AdjustedSize
+		if size > float64(lower) {
+			size = float64(lower)
+		}
+		liquidation = rc.liquidationFor(ctx, size, lower)
+		if stopPastLiquidation(side, stopLoss, liquidation) {
+			continue
+		}
+
+		rc.logger.WithFields(logrus.Fields{
+			"original_leverage": leverage,
+			"adjusted_leverage": lower,
+			"liquidation":       liquidation,
+			"stop_loss":         stopLoss,
+		}).Warn("Leverage lowered to keep stop loss ahead of liquidation")
+		result.AdjustedLeverage = lower
+		result.AdjustedSize = size
+		return adjust("Leverage lowered %dx -> %dx, estimated liquidation %.4f beyond stop %.4f",
+			leverage, lower, liquidation, stopLoss)
+	}
+
+	return reject("Stop loss %.4f is past estimated liquidation %.4f even at 1x", stopLoss, liquidation)
+}
+
+// checkTotalExposure rejects orders that would push gross notional across
+// all positions over max_total_exposure
+func checkTotalExposure(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || limits.MaxTotalExposure <= 0 || ctx.AccountBalance <= 0 {
+		return skip()
+	}
+
+	after := ctx.exposure().Add(ctx.orderSide(), result.AdjustedSize*ctx.AccountBalance)
+	newExposure := after.Gross() / ctx.accountValue()
+	if newExposure > limits.MaxTotalExposure {
+		return reject("Total exposure would exceed limit: %.2f%% > %.2f%%",
+			newExposure*100, limits.MaxTotalExposure*100)
+	}
+	return pass()
+}
+
+// checkNetExposure rejects orders that would push |long - short| notional over
+// max_net_exposure; orders that reduce net exposure always pass
+func checkNetExposure(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || limits.MaxNetExposure <= 0 || ctx.AccountBalance <= 0 {
+		return skip()
+	}
+
+	before := ctx.exposure()
+	after := before.Add(ctx.orderSide(), result.AdjustedSize*ctx.AccountBalance)
+	newExposure := after.Net() / ctx.accountValue()
+	if newExposure > limits.MaxNetExposure && after.Net() > before.Net() {
+		return reject("Net exposure would exceed limit: %.2f%% > %.2f%%",
+			newExposure*100, limits.MaxNetExposure*100)
+	}
+	return pass()
+}
+
+// checkDirectionExposure caps long and short notional separately
+func checkDirectionExposure(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || ctx.AccountBalance <= 0 {
+		return skip()
+	}
+
+	side := ctx.orderSide()
+	after := ctx.exposure().Add(side, result.AdjustedSize*ctx.AccountBalance)
+
+	limit, notional := limits.MaxLongExposure, after.Long
+	if side == "SHORT" {
+		limit, notional = limits.MaxShortExposure, after.Short
+	}
+	if limit <= 0 {
+		return skip()
+	}
+
+	if notional/ctx.accountValue() > limit {
+		return reject("%s exposure would exceed limit: %.2f%% > %.2f%%",
+			side, notional/ctx.accountValue()*100, limit*100)
+	}
+	return pass()
+}
+
+// checkMarginUtilization rejects orders whose margin would push total margin
+// used over max_margin_utilization
+func checkMarginUtilization(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	if !increasesExposure(ctx.Decision.Action) || limits.MaxMarginUtilization <= 0 || ctx.AccountBalance <= 0 {
+		return skip()
+	}
+
+	leverage := result.AdjustedLeverage
+	if leverage < 1 {
+		leverage = 1
+	}
+
+	margin := ctx.marginUsed() + result.AdjustedSize*ctx.AccountBalance/float64(leverage)
+	utilization := margin / ctx.accountValue()
+	if utilization > limits.MaxMarginUtilization {
+		return reject("Margin utilization would exceed limit: %.2f%% > %.2f%%",
+			utilization*100, limits.MaxMarginUtilization*100)
+	}
+	return pass()
+}
+
+// checkRiskReward rejects entries whose reward/risk is below min_risk_reward_ratio
+func checkRiskReward(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	decision := ctx.Decision
+	if !isOpen(decision.Action) || decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
+		return skip()
+	}
+
+	var riskReward float64
+	if decision.Action == "OPEN_LONG" {
+		risk := ctx.CurrentPrice - decision.StopLoss
+		reward := decision.TakeProfit - ctx.CurrentPrice
+		if risk > 0 {
+			riskReward = reward / risk
+		}
+	} else {
+		risk := decision.StopLoss - ctx.CurrentPrice
+		reward := ctx.CurrentPrice - decision.TakeProfit
+		if risk > 0 {
+			riskReward = reward / risk
+		}
+	}
+
+	if riskReward < limits.MinRiskRewardRatio {
+		return reject("Risk-reward ratio too low: %.2f < %.2f", riskReward, limits.MinRiskRewardRatio)
+	}
+	return pass()
+}
+
+// checkStopLossPlacement requires stops on entries and on the correct side of price
+func checkStopLossPlacement(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	decision := ctx.Decision
+	if !isOpen(decision.Action) {
+		return skip()
+	}
+
+	if decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
+		return reject("Stop loss and take profit must be set")
+	}
+	if decision.Action == "OPEN_LONG" && decision.StopLoss >= ctx.CurrentPrice {
+		return reject("Stop loss for long position must be below current price")
+	}
+	if decision.Action == "OPEN_SHORT" && decision.StopLoss <= ctx.CurrentPrice {
+		return reject("Stop loss for short position must be above current price")
+	}
+	return pass()
+}
+
+// checkHoldingTime rejects adding to a position held past its holding time
+// limit and warns about any other decision on it
+func checkHoldingTime(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	position := ctx.Position
+	if position.Size <= 0 || ctx.Decision.Action == "CLOSE_POSITION" {
+		return skip()
+	}
+
+	maxHoldingTime := rc.HoldingLimit(ctx.Symbol, ctx.Decision.ExpectedHoldingPeriod)
+	if position.HoldingTime <= maxHoldingTime {
+		return pass()
+	}
+
+	if ctx.Decision.Action == "ADD_POSITION" {
+		return reject("Position held past max holding time: %s > %s",
+			position.HoldingTime.Round(time.Minute), maxHoldingTime)
+	}
+
+	rc.logger.WithFields(logrus.Fields{
+		"holding_time": position.HoldingTime,
+		"max_time":     maxHoldingTime,
+	}).Warn("Position held too long, should consider closing")
+	return pass()
+}
@@ -0,0 +1,91 @@
+package risk
+
+import (
+	"math"
+
+	"aitrading/indicators"
+	"github.com/sirupsen/logrus"
+)
+
+// Sizing modes
+const (
+	SizingFixed      = "fixed"
+	SizingRisk       = "risk"
+	SizingVolatility = "volatility"
+)
+
+const defaultATRMultiplier = 2.0
+
+// ATR returns the 14-period average true range of a symbol's recorded candles
+func (rc *Controller) ATR(symbol string) float64 {
+	return indicators.NewCalculator().ATR(rc.candleHistory(symbol), 14)
+}
+
+// kelly returns the Kelly bet fraction for a win probability and reward/risk ratio
+func kelly(winProbability, rewardRisk float64) float64 {
+	if rewardRisk <= 0 {
+		return 0
+	}
+	return winProbability - (1-winProbability)/rewardRisk
+}
+
+// checkSizing replaces the decision's balance fraction with one that loses
+// position_risk_per_trade of the balance at the stop distance. The stop is
+// the decision's stop loss in risk mode and atr_multiplier ATRs in volatility
+// mode. Notional never exceeds what the leverage allows.
+func checkSizing(rc *Controller, ctx *DecisionContext, limits Limits, result *RiskCheckResult) Verdict {
+	mode := rc.config.SizingMode
+	if mode == "" || mode == SizingFixed || !increasesExposure(ctx.Decision.Action) ||
+		ctx.CurrentPrice <= 0 || rc.config.PositionRiskPerTrade <= 0 {
+		return skip()
+	}
+
+	decision := ctx.Decision
+	stopDistance := math.Abs(ctx.CurrentPrice - decision.StopLoss)
+	if decision.StopLoss <= 0 {
+		stopDistance = 0
+	}
+
+	if mode == SizingVolatility {
+		multiplier := rc.config.ATRMultiplier
+		if multiplier <= 0 {
+			multiplier = defaultATRMultiplier
+		}
+		if atr := rc.ATR(ctx.Symbol); atr > 0 {
+			stopDistance = atr * multiplier
+		}
+	}
+
+	if stopDistance <= 0 {
+		return skip()
+	}
+
+	riskFraction := rc.config.PositionRiskPerTrade
+	if rc.config.KellyFraction > 0 && decision.StopLoss > 0 && decision.TakeProfit > 0 {
+		rewardRisk := math.Abs(decision.TakeProfit-ctx.CurrentPrice) / math.Abs(ctx.CurrentPrice-decision.StopLoss)
+		bet := rc.config.KellyFraction * kelly(decision.Confidence, rewardRisk)
+		if bet <= 0 {
+			return reject("No edge for Kelly sizing: confidence %.2f at %.2f reward/risk", decision.Confidence, rewardRisk)
+		}
+		riskFraction = math.Min(riskFraction, bet)
+	}
+
+	size := riskFraction * ctx.CurrentPrice / stopDistance
+	leverage := result.AdjustedLeverage
+	if leverage < 1 {
+		leverage = 1
+	}
+	size = math.Min(size, float64(leverage))
+
+	original := result.AdjustedSize
+	result.AdjustedSize = size
+	rc.logger.WithFields(logrus.Fields{
+		"mode":          mode,
+		"risk_fraction": riskFraction,
+		"stop_distance": stopDistance,
+		"original_size": original,
+		"adjusted_size": size,
+	}).Info("Position sized from risk per trade")
+	return adjust("Sized for %.2f%% risk over %.4f stop distance: %.4f -> %.4f",
+		riskFraction*100, stopDistance, original, size)
+}
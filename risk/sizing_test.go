@@ -0,0 +1,93 @@
+package risk
+
+import (
+	"math"
+	"testing"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/indicators"
+	"github.com/sirupsen/logrus"
+)
+
+func newSizingController(riskCfg *config.RiskConfig) *Controller {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	riskCfg.PositionRiskPerTrade = 0.01
+	riskCfg.MinRiskRewardRatio = 2
+	return NewController(riskCfg, &config.TradingConfig{}, logger)
+}
+
+func sizedLong(stopLoss float64) *ai.Decision {
+	return &ai.Decision{
+		Action:     "OPEN_LONG",
+		Confidence: 0.8,
+		Size:       0.1,
+		Leverage:   3,
+		StopLoss:   stopLoss,
+		TakeProfit: 2000 + 2*(2000-stopLoss),
+	}
+}
+
+func TestSizingRisksFixedAmountToStop(t *testing.T) {
+	rc := newSizingController(&config.RiskConfig{SizingMode: SizingRisk})
+
+	// Whatever the stop distance, the loss at the stop is 1% of 10000
+	for _, stopLoss := range []float64{1900, 1980} {
+		result := checkOpenLong(rc, "ETH", sizedLong(stopLoss))
+		if !result.Approved {
+			t.Fatalf("stop %.0f: unexpected rejection: %s", stopLoss, result.Reason)
+		}
+		loss := result.Quantity * (2000 - stopLoss)
+		if math.Abs(loss-100) > 1e-6 {
+			t.Errorf("stop %.0f: expected 100 at risk, got %.2f (quantity %.4f)", stopLoss, loss, result.Quantity)
+		}
+	}
+
+	// A 5 dollar stop would need 4x notional, above the 3x leverage
+	result := checkOpenLong(rc, "ETH", sizedLong(1995))
+	if result.AdjustedSize != 3 {
+		t.Errorf("size should be capped at the leverage, got %.2f", result.AdjustedSize)
+	}
+}
+
+func TestSizingVolatilityUsesATR(t *testing.T) {
+	rc := newSizingController(&config.RiskConfig{SizingMode: SizingVolatility, ATRMultiplier: 2})
+	candles := make([]indicators.MarketData, 30)
+	for i := range candles {
+		candles[i] = indicators.MarketData{High: 2002, Low: 1998, Close: 2000}
+	}
+	rc.RecordCandles("ETH", candles)
+
+	// ATR 4, stop distance 8: 0.01 * 2000 / 8
+	result := checkOpenLong(rc, "ETH", sizedLong(1900))
+	if math.Abs(result.AdjustedSize-2.5) > 1e-9 {
+		t.Errorf("expected ATR size 2.5, got %.4f", result.AdjustedSize)
+	}
+}
+
+func TestSizingKellyFraction(t *testing.T) {
+	rc := newSizingController(&config.RiskConfig{SizingMode: SizingRisk, KellyFraction: 0.01})
+
+	// Kelly at 0.6 confidence and 2:1 is 0.4, a hundredth of that is 0.4% risk
+	decision := sizedLong(1900)
+	decision.Confidence = 0.6
+	result := checkOpenLong(rc, "ETH", decision)
+	if math.Abs(result.AdjustedSize-0.08) > 1e-9 {
+		t.Errorf("expected Kelly-capped size 0.08, got %.4f", result.AdjustedSize)
+	}
+
+	decision.Confidence = 0.3
+	if result := checkOpenLong(rc, "ETH", decision); result.Approved {
+		t.Error("a negative Kelly bet should be rejected")
+	}
+}
+
+func TestSizingFixedKeepsDecisionSize(t *testing.T) {
+	rc := newSizingController(&config.RiskConfig{})
+
+	result := checkOpenLong(rc, "ETH", sizedLong(1900))
+	if result.AdjustedSize != 0.1 || math.Abs(result.Quantity-0.5) > 1e-9 {
+		t.Errorf("fixed sizing should keep 10%% of balance, got size %.2f quantity %.4f", result.AdjustedSize, result.Quantity)
+	}
+}
@@ -0,0 +1,244 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/indicators"
+)
+
+// Strategy modes
+const (
+	ModeOff        = "off"
+	ModeFallback   = "fallback"
+	ModeVeto       = "veto"
+	ModeStandalone = "standalone"
+)
+
+// Engine produces deterministic decisions from technical indicators using the
+// same entry rules the AI prompt describes
+type Engine struct {
+	config     *config.StrategyConfig
+	calculator *indicators.Calculator
+}
+
+// NewEngine creates a new rule-based strategy engine, filling unset
+// thresholds with the defaults of the prompt rules
+func NewEngine(cfg *config.StrategyConfig) *Engine {
+	c := *cfg
+	if c.MinSignals <= 0 {
+		c.MinSignals = 3
+	}
+	if c.CloseSignals <= 0 {
+		c.CloseSignals = 2
+	}
+	if c.VetoMinSignals <= 0 {
+		c.VetoMinSignals = 1
+	}
+	if c.VolumeFactor <= 0 {
+		c.VolumeFactor = 1.5
+	}
+	if c.Size <= 0 {
+		c.Size = 0.05
+	}
+	if c.Leverage <= 0 {
+		c.Leverage = 1
+	}
+	if c.StopLossPct <= 0 {
+		c.StopLossPct = 0.02
+	}
+	if c.RiskReward <= 0 {
+		c.RiskReward = 2
+	}
+
+	return &Engine{
+		config:     &c,
+		calculator: indicators.NewCalculator(),
+	}
+}
+
+// Signals lists the entry rules that fired in each direction
+type Signals struct {
+	Long  []string
+	Short []string
+}
+
+// Evaluate checks every entry rule against the latest and previous candle
+func (e *Engine) Evaluate(analysis *ai.MarketAnalysis) *Signals {
+	signals := &Signals{}
+
+	cur := analysis.Indicators
+	candles := analysis.Candles
+	if cur == nil || len(candles) < 2 {
+		return signals
+	}
+
+	prev := e.calculator.Calculate(candles[:len(candles)-1])
+	if prev == nil {
+		return signals
+	}
+
+	price := candles[len(candles)-1].Close
+	prevPrice := candles[len(candles)-2].Close
+
+	// MA breakout
+	if prevPrice <= prev.EMA10 && price > cur.EMA10 {
+		signals.Long = append(signals.Long, "价格突破EMA10")
+	}
+	if prevPrice >= prev.EMA10 && price < cur.EMA10 {
+		signals.Short = append(signals.Short, "价格跌破EMA10")
+	}
+
+	// MACD cross with histogram flip
+	if prev.MACDHIST <= 0 && cur.MACDHIST > 0 {
+		signals.Long = append(signals.Long, "MACD金叉且柱状图转正")
+	}
+	if prev.MACDHIST >= 0 && cur.MACDHIST < 0 {
+		signals.Short = append(signals.Short, "MACD死叉且柱状图转负")
+	}
+
+	// RSI reversal out of extreme zones
+	if prev.RSI14 < 30 && cur.RSI14 > prev.RSI14 {
+		signals.Long = append(signals.Long, "RSI从超卖区回升")
+	}
+	if prev.RSI14 > 70 && cur.RSI14 < prev.RSI14 {
+		signals.Short = append(signals.Short, "RSI从超买区回落")
+	}
+
+	// Bollinger band support and resistance
+	if prevPrice <= prev.BBLower && price > cur.BBLower {
+		signals.Long = append(signals.Long, "布林带下轨支撑有效")
+	}
+	if prevPrice >= prev.BBUpper && price < cur.BBUpper {
+		signals.Short = append(signals.Short, "布林带上轨阻力有效")
+	}
+
+	// Volume confirmation
+	if cur.VMA20 > 0 && cur.CurrentVolume > cur.VMA20*e.config.VolumeFactor {
+		if price > prevPrice {
+			signals.Long = append(signals.Long, "成交量放大确认上涨")
+		} else if price < prevPrice {
+			signals.Short = append(signals.Short, "成交量配合下跌")
+		}
+	}
+
+	return signals
+}
+
+// Decide returns the rule-based decision for a symbol
+func (e *Engine) Decide(analysis *ai.MarketAnalysis) *ai.Decision {
+	signals := e.Evaluate(analysis)
+	price := analysis.Market.CurrentPrice
+	position := analysis.Position
+
+	// Exit on enough reversal signals against the open position
+	if position != nil && position.Size > 0 {
+		against := signals.Short
+		if position.Side == "SHORT" {
+			against = signals.Long
+		}
+		if len(against) >= e.config.CloseSignals {
+			return e.decision("CLOSE_POSITION", len(against), price, "反转信号: "+strings.Join(against, ", "))
+		}
+		return e.hold(signals)
+	}
+
+	long, short := len(signals.Long), len(signals.Short)
+	switch {
+	case long >= e.config.MinSignals && long > short:
+		return e.decision("OPEN_LONG", long, price, "多头信号: "+strings.Join(signals.Long, ", "))
+	case short >= e.config.MinSignals && short > long:
+		return e.decision("OPEN_SHORT", short, price, "空头信号: "+strings.Join(signals.Short, ", "))
+	default:
+		return e.hold(signals)
+	}
+}
+
+// Veto replaces an AI entry with HOLD unless the rules confirm its direction.
+// It returns the decision to use and whether a veto happened.
+func (e *Engine) Veto(decision *ai.Decision, analysis *ai.MarketAnalysis) (*ai.Decision, bool) {
+	var confirming, opposing []string
+	signals := e.Evaluate(analysis)
+
+	switch decision.Action {
+	case "OPEN_LONG":
+		confirming, opposing = signals.Long, signals.Short
+	case "OPEN_SHORT":
+		confirming, opposing = signals.Short, signals.Long
+	case "ADD_POSITION":
+		if analysis.Position == nil || analysis.Position.Side == "SHORT" {
+			confirming, opposing = signals.Short, signals.Long
+		} else {
+			confirming, opposing = signals.Long, signals.Short
+		}
+	default:
+		return decision, false
+	}
+
+	reason := ""
+	if len(confirming) < e.config.VetoMinSignals {
+		reason = fmt.Sprintf("策略否决 %s: 确认信号 %d < %d", decision.Action, len(confirming), e.config.VetoMinSignals)
+	} else if len(opposing) >= e.config.MinSignals {
+		reason = fmt.Sprintf("策略否决 %s: 反向信号 %s", decision.Action, strings.Join(opposing, ", "))
+	} else {
+		return decision, false
+	}
+
+	return &ai.Decision{
+		Action:                "HOLD",
+		Confidence:            decision.Confidence,
+		Leverage:              1,
+		RiskLevel:             decision.RiskLevel,
+		Reason:                reason + " | AI理由: " + decision.Reason,
+		ExpectedHoldingPeriod: decision.ExpectedHoldingPeriod,
+	}, true
+}
+
+// decision builds an actionable decision with stops derived from the config
+func (e *Engine) decision(action string, signalCount int, price float64, reason string) *ai.Decision {
+	decision := &ai.Decision{
+		Action:                action,
+		Confidence:            e.confidence(signalCount),
+		Size:                  e.config.Size,
+		Leverage:              e.config.Leverage,
+		Reason:                "[规则策略] " + reason,
+		RiskLevel:             "MEDIUM",
+		ExpectedHoldingPeriod: "SHORT",
+	}
+
+	stopDistance := price * e.config.StopLossPct
+	switch action {
+	case "OPEN_LONG":
+		decision.StopLoss = price - stopDistance
+		decision.TakeProfit = price + stopDistance*e.config.RiskReward
+	case "OPEN_SHORT":
+		decision.StopLoss = price + stopDistance
+		decision.TakeProfit = price - stopDistance*e.config.RiskReward
+	}
+
+	return decision
+}
+
+// hold builds a HOLD decision listing the signals seen
+func (e *Engine) hold(signals *Signals) *ai.Decision {
+	return &ai.Decision{
+		Action:     "HOLD",
+		Confidence: e.confidence(0),
+		Leverage:   1,
+		Reason: fmt.Sprintf("[规则策略] 信号不足 (多头%d/空头%d, 需要%d)",
+			len(signals.Long), len(signals.Short), e.config.MinSignals),
+		RiskLevel:             "LOW",
+		ExpectedHoldingPeriod: "SHORT",
+	}
+}
+
+// confidence maps the number of agreeing rules onto 0.5-1.0
+func (e *Engine) confidence(signalCount int) float64 {
+	confidence := 0.5 + 0.1*float64(signalCount)
+	if confidence > 1 {
+		confidence = 1
+	}
+	return confidence
+}
@@ -0,0 +1,98 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+
+	"aitrading/ai"
+	"aitrading/config"
+	"aitrading/hyperliquid"
+	"aitrading/indicators"
+)
+
+// reversalAnalysis builds a steady decline whose last candle bounces by jump on heavy volume
+func reversalAnalysis(jump float64, position *hyperliquid.Position) *ai.MarketAnalysis {
+	candles := make([]indicators.MarketData, 150)
+	price := 2000.0
+	for i := range candles {
+		volume := 100.0
+		if i < len(candles)-1 {
+			price -= 2
+		} else {
+			price += jump
+			volume = 500
+		}
+		candles[i] = indicators.MarketData{Open: price, High: price, Low: price, Close: price, Volume: volume}
+	}
+
+	if position == nil {
+		position = &hyperliquid.Position{Side: "NONE"}
+	}
+
+	return &ai.MarketAnalysis{
+		Symbol:     "ETH",
+		Market:     &hyperliquid.MarketInfo{Symbol: "ETH", CurrentPrice: price},
+		Indicators: indicators.NewCalculator().Calculate(candles),
+		Position:   position,
+		Candles:    candles,
+	}
+}
+
+func TestDecideOpensLongOnEnoughSignals(t *testing.T) {
+	engine := NewEngine(&config.StrategyConfig{StopLossPct: 0.01, RiskReward: 2})
+	analysis := reversalAnalysis(20, nil)
+
+	decision := engine.Decide(analysis)
+	if decision.Action != "OPEN_LONG" {
+		t.Fatalf("expected OPEN_LONG, got %s (%s)", decision.Action, decision.Reason)
+	}
+
+	price := analysis.Market.CurrentPrice
+	if math.Abs(decision.StopLoss-price*0.99) > 1e-6 || math.Abs(decision.TakeProfit-price*1.02) > 1e-6 {
+		t.Errorf("unexpected stops: SL=%f TP=%f for price %f", decision.StopLoss, decision.TakeProfit, price)
+	}
+}
+
+func TestDecideHoldsBelowMinSignals(t *testing.T) {
+	engine := NewEngine(&config.StrategyConfig{})
+
+	decision := engine.Decide(reversalAnalysis(5, nil))
+	if decision.Action != "HOLD" {
+		t.Errorf("expected HOLD with two signals, got %s", decision.Action)
+	}
+
+	engine = NewEngine(&config.StrategyConfig{MinSignals: 2})
+	if decision := engine.Decide(reversalAnalysis(5, nil)); decision.Action != "OPEN_LONG" {
+		t.Errorf("expected OPEN_LONG when min_signals is 2, got %s", decision.Action)
+	}
+}
+
+func TestDecideClosesOnReversalAgainstPosition(t *testing.T) {
+	engine := NewEngine(&config.StrategyConfig{})
+	position := &hyperliquid.Position{Side: "SHORT", Size: 1}
+
+	decision := engine.Decide(reversalAnalysis(20, position))
+	if decision.Action != "CLOSE_POSITION" {
+		t.Errorf("expected CLOSE_POSITION for a short into a rally, got %s", decision.Action)
+	}
+}
+
+func TestVetoRejectsUnconfirmedEntry(t *testing.T) {
+	engine := NewEngine(&config.StrategyConfig{})
+	analysis := reversalAnalysis(20, nil)
+
+	short := &ai.Decision{Action: "OPEN_SHORT", Confidence: 0.9, RiskLevel: "HIGH"}
+	if filtered, vetoed := engine.Veto(short, analysis); !vetoed || filtered.Action != "HOLD" {
+		t.Errorf("short into a rally should be vetoed, got %s", filtered.Action)
+	}
+
+	long := &ai.Decision{Action: "OPEN_LONG", Confidence: 0.9, RiskLevel: "LOW"}
+	if filtered, vetoed := engine.Veto(long, analysis); vetoed || filtered != long {
+		t.Errorf("confirmed long should pass through unchanged")
+	}
+
+	hold := &ai.Decision{Action: "HOLD"}
+	if _, vetoed := engine.Veto(hold, analysis); vetoed {
+		t.Error("non-entry decisions are never vetoed")
+	}
+}
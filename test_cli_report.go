@@ -1,3 +1,4 @@
+//go:build ignore
 // +build ignore
 
 package main
@@ -36,11 +37,11 @@ func main() {
 	var aiAPIKey, aiBaseURL, aiModel string
 	switch cfg.AI.Provider {
 	case "qwen":
-		aiAPIKey = cfg.AI.Qwen.APIKey
+		aiAPIKey, _ = cfg.AI.Qwen.APIKey.Resolve()
 		aiBaseURL = cfg.AI.Qwen.BaseURL
 		aiModel = cfg.AI.Qwen.Model
 	default:
-		aiAPIKey = cfg.AI.APIKey
+		aiAPIKey, _ = cfg.AI.APIKey.Resolve()
 		aiBaseURL = cfg.AI.BaseURL
 		aiModel = cfg.AI.Model
 	}
@@ -79,11 +80,12 @@ func main() {
 	inds := calc.Calculate(candles)
 
 	// Get position
-	position, err := hlClient.GetPosition(symbol, cfg.Hyperliquid.AccountAddress)
+	account, err := hlClient.GetAccountState(cfg.Hyperliquid.AccountAddress)
 	if err != nil {
 		fmt.Printf("Failed to fetch position: %v\n", err)
 		os.Exit(1)
 	}
+	position := account.Position(symbol)
 
 	// Get AI decision
 	analysis := &ai.MarketAnalysis{
@@ -1,3 +1,4 @@
+//go:build ignore
 // +build ignore
 
 package main
@@ -41,7 +42,7 @@ func main() {
 	fmt.Println("\n--- Test 2: AI Provider Configuration ---")
 	fmt.Printf("AI Provider: %s\n", cfg.AI.Provider)
 	if cfg.AI.Provider == "qwen" {
-		fmt.Printf("Qwen API Key: %s...\n", cfg.AI.Qwen.APIKey[:10])
+		fmt.Printf("Qwen API Key: %s\n", cfg.AI.Qwen.APIKey)
 		fmt.Printf("Qwen Model: %s\n", cfg.AI.Qwen.Model)
 		fmt.Println("✅ Qwen configuration available")
 	} else {
@@ -59,11 +60,11 @@ func main() {
 	var aiAPIKey, aiBaseURL, aiModel string
 	switch cfg.AI.Provider {
 	case "qwen":
-		aiAPIKey = cfg.AI.Qwen.APIKey
+		aiAPIKey, _ = cfg.AI.Qwen.APIKey.Resolve()
 		aiBaseURL = cfg.AI.Qwen.BaseURL
 		aiModel = cfg.AI.Qwen.Model
 	default:
-		aiAPIKey = cfg.AI.APIKey
+		aiAPIKey, _ = cfg.AI.APIKey.Resolve()
 		aiBaseURL = cfg.AI.BaseURL
 		aiModel = cfg.AI.Model
 	}
@@ -111,7 +112,10 @@ func main() {
 	inds := calc.Calculate(candles)
 
 	marketInfo, _ := hlClient.GetMarketData(cfg.Trading.Symbols[0])
-	position, _ := hlClient.GetPosition(cfg.Trading.Symbols[0], cfg.Hyperliquid.AccountAddress)
+	var position *hyperliquid.Position
+	if account, err := hlClient.GetAccountState(cfg.Hyperliquid.AccountAddress); err == nil {
+		position = account.Position(cfg.Trading.Symbols[0])
+	}
 
 	analysis := &ai.MarketAnalysis{
 		Symbol:     cfg.Trading.Symbols[0],
@@ -149,7 +153,13 @@ func main() {
 	}
 
 	// Simulate 0 open positions
-	riskCheck, err := riskControl.CheckDecision(testDecision, 3930, 1000, position, 0)
+	riskCheck, err := riskControl.CheckDecision(&risk.DecisionContext{
+		Symbol:         "ETH",
+		Decision:       testDecision,
+		CurrentPrice:   3930,
+		AccountBalance: 1000,
+		Position:       position,
+	})
 	if err != nil {
 		fmt.Printf("❌ Risk check failed: %v\n", err)
 	} else {
@@ -163,7 +173,14 @@ func main() {
 
 	// Test with max positions reached
 	fmt.Println("\n--- Test 7: Position Count Limit ---")
-	riskCheck2, err := riskControl.CheckDecision(testDecision, 3930, 1000, position, cfg.Trading.MaxOpenPositions)
+	riskCheck2, err := riskControl.CheckDecision(&risk.DecisionContext{
+		Symbol:            "ETH",
+		Decision:          testDecision,
+		CurrentPrice:      3930,
+		AccountBalance:    1000,
+		Position:          position,
+		OpenPositionCount: cfg.Trading.MaxOpenPositions,
+	})
 	if err != nil {
 		fmt.Printf("❌ Risk check failed: %v\n", err)
 	} else {